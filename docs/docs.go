@@ -24,35 +24,74 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/chats/create": {
+        "/attachments/get/{id}": {
+            "get": {
+                "description": "Get the metadata and URL of an attachment",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "attachments"
+                ],
+                "summary": "Get an attachment",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Attachment ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.Attachment"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/attachments/upload": {
             "post": {
-                "description": "Create a new chat with the input payload",
+                "description": "Upload a file that can then be referenced by messages or used as a chat avatar",
                 "consumes": [
-                    "application/json"
+                    "multipart/form-data"
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "chats"
+                    "attachments"
                 ],
-                "summary": "Create a new chat",
+                "summary": "Upload an attachment",
                 "parameters": [
                     {
-                        "description": "Chat",
-                        "name": "chat",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/model.CreateConversationInput"
-                        }
+                        "type": "file",
+                        "description": "File",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/model.Conversation"
+                            "$ref": "#/definitions/model.Attachment"
                         }
                     },
                     "400": {
@@ -61,6 +100,12 @@ const docTemplate = `{
                             "type": "string"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -70,9 +115,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/chats/get/{id}": {
-            "get": {
-                "description": "Get a chat by ID",
+        "/chats/call": {
+            "post": {
+                "description": "Start, accept, decline or hang up a call, or relay an SDP offer, answer or ICE candidate to a participant. Channels have no calls",
                 "consumes": [
                     "application/json"
                 ],
@@ -82,21 +127,29 @@ const docTemplate = `{
                 "tags": [
                     "chats"
                 ],
-                "summary": "Get a chat by ID",
+                "summary": "Control a call",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Chat ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Call event",
+                        "name": "event",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.ClientEvent"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.Conversation"
+                            "$ref": "#/definitions/model.CallSession"
+                        }
+                    },
+                    "204": {
+                        "description": "No Content",
+                        "schema": {
+                            "type": "string"
                         }
                     },
                     "400": {
@@ -105,12 +158,30 @@ const docTemplate = `{
                             "type": "string"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
                             "type": "string"
                         }
                     },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -120,37 +191,40 @@ const docTemplate = `{
                 }
             }
         },
-        "/chats/getForUser": {
+        "/chats/call/{id}": {
             "get": {
-                "description": "Get all chats for a user",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Get the state of a ringing or active call the current user takes part in",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "chats"
                 ],
-                "summary": "Get all chats for a user",
+                "summary": "Get a call",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Call ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/model.Conversation"
-                            }
+                            "$ref": "#/definitions/model.CallSession"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "type": "string"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "type": "string"
                         }
@@ -158,9 +232,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/chats/message": {
+        "/chats/create": {
             "post": {
-                "description": "Send a message with the input payload",
+                "description": "Create a new group chat or broadcast channel with the input payload, the creator becomes its admin. A group with a single other member is the direct chat with them, which is returned instead. Members who only let their contacts start a conversation with them must have the creator as a contact",
                 "consumes": [
                     "application/json"
                 ],
@@ -170,15 +244,15 @@ const docTemplate = `{
                 "tags": [
                     "chats"
                 ],
-                "summary": "Send a message",
+                "summary": "Create a new chat",
                 "parameters": [
                     {
-                        "description": "Message",
-                        "name": "message",
+                        "description": "Chat",
+                        "name": "chat",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/model.SendMessageInput"
+                            "$ref": "#/definitions/model.CreateConversationInput"
                         }
                     }
                 ],
@@ -186,7 +260,7 @@ const docTemplate = `{
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/model.Message"
+                            "$ref": "#/definitions/model.Conversation"
                         }
                     },
                     "400": {
@@ -195,6 +269,12 @@ const docTemplate = `{
                             "type": "string"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -204,9 +284,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/chats/ws/{id}": {
-            "get": {
-                "description": "Handle a websocket connection",
+        "/chats/direct/{userId}": {
+            "post": {
+                "description": "Return the direct chat between the current user and the given user, creating it if it does not exist yet. A user who only lets their contacts start a conversation with them must have the current user as a contact",
                 "consumes": [
                     "application/json"
                 ],
@@ -216,12 +296,12 @@ const docTemplate = `{
                 "tags": [
                     "chats"
                 ],
-                "summary": "Handle a websocket connection",
+                "summary": "Get or create a direct chat",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Chat ID",
-                        "name": "id",
+                        "description": "User ID",
+                        "name": "userId",
                         "in": "path",
                         "required": true
                     }
@@ -230,7 +310,13 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/model.Conversation"
+                        }
+                    },
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/model.Conversation"
                         }
                     },
                     "400": {
@@ -239,6 +325,18 @@ const docTemplate = `{
                             "type": "string"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
@@ -254,50 +352,28 @@ const docTemplate = `{
                 }
             }
         },
-        "/users/auth/login": {
+        "/chats/drafts/list": {
             "get": {
-                "description": "Login a user with the input payload",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "List the current user's drafts that are not empty, the latest first",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "users"
-                ],
-                "summary": "Login a user",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Email",
-                        "name": "email",
-                        "in": "query",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Password",
-                        "name": "password",
-                        "in": "query",
-                        "required": true
-                    }
+                    "drafts"
                 ],
+                "summary": "List drafts",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.User"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "type": "string"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.MessageDraft"
+                            }
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "type": "string"
                         }
@@ -311,9 +387,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/users/create": {
-            "post": {
-                "description": "Create a new user with the input payload",
+        "/chats/drafts/save/{id}": {
+            "put": {
+                "description": "Replace the current user's draft of a chat, an empty draft clears it. The draft with the latest updatedAt wins, when a newer one is stored it is returned instead",
                 "consumes": [
                     "application/json"
                 ],
@@ -321,25 +397,32 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "drafts"
                 ],
-                "summary": "Create a new user",
+                "summary": "Save a draft",
                 "parameters": [
                     {
-                        "description": "User",
-                        "name": "user",
+                        "type": "string",
+                        "description": "Conversation ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Draft",
+                        "name": "draft",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/model.CreateUserInput"
+                            "$ref": "#/definitions/model.SaveDraftInput"
                         }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.User"
+                            "$ref": "#/definitions/model.MessageDraft"
                         }
                     },
                     "400": {
@@ -348,6 +431,18 @@ const docTemplate = `{
                             "type": "string"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -357,29 +452,22 @@ const docTemplate = `{
                 }
             }
         },
-        "/users/get": {
+        "/chats/folders/chats/{id}": {
             "get": {
-                "description": "Get a list of users by IDs",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "List the chats in a folder like the inbox, pinned chats first and then by latest message",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "folders"
                 ],
-                "summary": "Get a list of users by IDs",
+                "summary": "List the chats of a folder",
                 "parameters": [
                     {
-                        "type": "array",
-                        "items": {
-                            "type": "string"
-                        },
-                        "collectionFormat": "csv",
-                        "description": "User IDs",
-                        "name": "ids",
-                        "in": "query",
+                        "type": "string",
+                        "description": "Folder ID",
+                        "name": "id",
+                        "in": "path",
                         "required": true
                     }
                 ],
@@ -389,12 +477,12 @@ const docTemplate = `{
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/model.User"
+                                "$ref": "#/definitions/model.Conversation"
                             }
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "type": "string"
                         }
@@ -414,9 +502,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/users/get/{id}": {
-            "get": {
-                "description": "Get a user by ID",
+        "/chats/folders/create": {
+            "post": {
+                "description": "Create a folder of the current user's inbox, with either an explicit list of chats or rules matching them",
                 "consumes": [
                     "application/json"
                 ],
@@ -424,23 +512,25 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "folders"
                 ],
-                "summary": "Get a user by ID",
+                "summary": "Create a chat folder",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "User ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Folder",
+                        "name": "folder",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.ChatFolderInput"
+                        }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/model.User"
+                            "$ref": "#/definitions/model.ChatFolder"
                         }
                     },
                     "400": {
@@ -449,24 +539,112 @@ const docTemplate = `{
                             "type": "string"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "type": "string"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "type": "string"
                         }
-                    }
-                }
-            }
-        },
-        "/users/update": {
-            "patch": {
-                "description": "Update a user with the input payload",
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/chats/folders/delete/{id}": {
+            "post": {
+                "description": "Delete a folder, its chats are left untouched",
+                "tags": [
+                    "folders"
+                ],
+                "summary": "Delete a chat folder",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Folder ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/chats/folders/list": {
+            "get": {
+                "description": "List the current user's folders in order, each with the total of unread messages in its chats",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "folders"
+                ],
+                "summary": "List chat folders",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.ChatFolder"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/chats/folders/update/{id}": {
+            "put": {
+                "description": "Replace the name, position, chat list and rules of a folder",
                 "consumes": [
                     "application/json"
                 ],
@@ -474,17 +652,24 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "folders"
                 ],
-                "summary": "Update a user",
+                "summary": "Update a chat folder",
                 "parameters": [
                     {
-                        "description": "User",
-                        "name": "user",
+                        "type": "string",
+                        "description": "Folder ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Folder",
+                        "name": "folder",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/model.User"
+                            "$ref": "#/definitions/model.ChatFolderInput"
                         }
                     }
                 ],
@@ -492,7 +677,136 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.User"
+                            "$ref": "#/definitions/model.ChatFolder"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/chats/forward": {
+            "post": {
+                "description": "Forward messages to other chats in the order they were sent, keeping their content type and attachments and a reference to where they came from",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "chats"
+                ],
+                "summary": "Forward messages",
+                "parameters": [
+                    {
+                        "description": "Messages and target chats",
+                        "name": "forward",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.ForwardMessagesInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.Message"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/chats/get/{id}": {
+            "get": {
+                "description": "Get a chat by ID with its messages, members only",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "chats"
+                ],
+                "summary": "Get a chat by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Chat ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.Conversation"
                         }
                     },
                     "400": {
@@ -501,6 +815,18 @@ const docTemplate = `{
                             "type": "string"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
@@ -515,61 +841,4524 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "model.Conversation": {
+        },
+        "/chats/getForUser": {
+            "get": {
+                "description": "Get the chats of the current user, pinned chats first and then by latest message. Archived chats are left out unless archived=true",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "chats"
+                ],
+                "summary": "Get all chats for a user",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "Only archived (true) or only unarchived (false, default) chats",
+                        "name": "archived",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only muted or only unmuted chats",
+                        "name": "muted",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only pinned or only unpinned chats",
+                        "name": "pinned",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.Conversation"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/chats/invites/create": {
+            "post": {
+                "description": "Create an invite link for a group chat, only admins can create links",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "invites"
+                ],
+                "summary": "Create an invite link",
+                "parameters": [
+                    {
+                        "description": "Invite",
+                        "name": "invite",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.CreateInviteInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/model.ConversationInvite"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/chats/invites/join/{token}": {
+            "post": {
+                "description": "Join the chat of an invite link, or request to join it when the link needs an admin's approval",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "invites"
+                ],
+                "summary": "Join a chat with an invite link",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Invite token",
+                        "name": "token",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.JoinResult"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/chats/invites/list/{conversationId}": {
+            "get": {
+                "description": "List every invite link of a chat, including revoked and expired ones",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "invites"
+                ],
+                "summary": "List the invite links of a chat",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Chat ID",
+                        "name": "conversationId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.ConversationInvite"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/chats/invites/preview/{token}": {
+            "get": {
+                "description": "Show the title and member count of the chat an invite link leads to",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "invites"
+                ],
+                "summary": "Preview a chat from an invite link",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Invite token",
+                        "name": "token",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.InvitePreview"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/chats/invites/requests/approve/{id}": {
+            "post": {
+                "description": "Approve a pending join request and add the user to the chat",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "invites"
+                ],
+                "summary": "Approve a join request",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Join request ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.JoinRequest"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/chats/invites/requests/reject/{id}": {
+            "post": {
+                "description": "Reject a pending join request",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "invites"
+                ],
+                "summary": "Reject a join request",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Join request ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.JoinRequest"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/chats/invites/requests/{conversationId}": {
+            "get": {
+                "description": "List the join requests of a chat waiting for an admin's review",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "invites"
+                ],
+                "summary": "List pending join requests",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Chat ID",
+                        "name": "conversationId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.JoinRequest"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/chats/invites/revoke/{id}": {
+            "post": {
+                "description": "Revoke an invite link so it cannot be used anymore",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "invites"
+                ],
+                "summary": "Revoke an invite link",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Invite ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.ConversationInvite"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/chats/message": {
+            "post": {
+                "description": "Send a message with the input payload, only admins can post in a channel",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "chats"
+                ],
+                "summary": "Send a message",
+                "parameters": [
+                    {
+                        "description": "Message",
+                        "name": "message",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.SendMessageInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/model.Message"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/chats/pin/{messageId}": {
+            "post": {
+                "description": "Pin a message to the top of its chat, only admins can pin messages",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "chats"
+                ],
+                "summary": "Pin a message",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Message ID",
+                        "name": "messageId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/model.PinnedMessage"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/chats/pinned/{id}": {
+            "get": {
+                "description": "List the pinned messages of a chat, the latest pin first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "chats"
+                ],
+                "summary": "List pinned messages",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Chat ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.PinnedMessage"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/chats/poll/{id}": {
+            "get": {
+                "description": "Wait for the events of a chat, or of the current user when no chat ID is given, after the given event ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "chats"
+                ],
+                "summary": "Long-poll for events",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Chat ID",
+                        "name": "id",
+                        "in": "path"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Return events after this event ID",
+                        "name": "lastEventId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Seconds to wait for an event",
+                        "name": "timeout",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.EventPollResult"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/chats/polls/close/{messageId}": {
+            "post": {
+                "description": "Stop the voting early, only the author of the poll or a chat admin can close it",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "polls"
+                ],
+                "summary": "Close a poll",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Poll message ID",
+                        "name": "messageId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.PollResults"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/chats/polls/results/{messageId}": {
+            "get": {
+                "description": "Get the vote counts of a poll, with the voters unless the poll is anonymous",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "polls"
+                ],
+                "summary": "Get poll results",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Poll message ID",
+                        "name": "messageId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.PollResults"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/chats/polls/vote/{messageId}": {
+            "post": {
+                "description": "Replace the current user's vote, an empty list retracts it",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "polls"
+                ],
+                "summary": "Vote in a poll",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Poll message ID",
+                        "name": "messageId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Chosen options",
+                        "name": "vote",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.VotePollInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.PollResults"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/chats/react/{messageId}": {
+            "post": {
+                "description": "Add the current user's emoji reaction to a message, every member can react, including channel readers",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "chats"
+                ],
+                "summary": "React to a message",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Message ID",
+                        "name": "messageId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Reaction",
+                        "name": "reaction",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.ReactInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.ReactionCount"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/chats/reactions/{messageId}": {
+            "get": {
+                "description": "Get the reaction totals of a message per emoji, most used first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "chats"
+                ],
+                "summary": "Get the reactions of a message",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Message ID",
+                        "name": "messageId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.ReactionCount"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/chats/read/{id}": {
+            "post": {
+                "description": "Move the current user's read marker of a chat to now",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "chats"
+                ],
+                "summary": "Mark a chat as read",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Chat ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.ConversationMember"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/chats/rendered/{messageId}": {
+            "get": {
+                "description": "Get the plain text and sanitized HTML of a message, for notifications and exports",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "chats"
+                ],
+                "summary": "Render a message as text",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Message ID",
+                        "name": "messageId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.RenderedMessage"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/chats/scheduled/cancel/{id}": {
+            "post": {
+                "description": "Cancel a message that was not sent yet",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "scheduled"
+                ],
+                "summary": "Cancel a scheduled message",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Scheduled message ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.ScheduledMessage"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/chats/scheduled/create": {
+            "post": {
+                "description": "Compose a message now and have it sent to a chat at sendAt",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "scheduled"
+                ],
+                "summary": "Schedule a message",
+                "parameters": [
+                    {
+                        "description": "Scheduled message",
+                        "name": "message",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.ScheduleMessageInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/model.ScheduledMessage"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/chats/scheduled/list": {
+            "get": {
+                "description": "List the current user's messages waiting to be sent, the next one first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "scheduled"
+                ],
+                "summary": "List scheduled messages",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Only the messages of this chat",
+                        "name": "conversationId",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.ScheduledMessage"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/chats/scheduled/update/{id}": {
+            "patch": {
+                "description": "Change the content or send time of a message that was not sent yet, only the fields present are changed",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "scheduled"
+                ],
+                "summary": "Edit a scheduled message",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Scheduled message ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Changes",
+                        "name": "message",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.UpdateScheduledMessageInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.ScheduledMessage"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/chats/settings/{id}": {
+            "patch": {
+                "description": "Mute, pin, archive or nickname a chat for the current user only, only the fields present are changed. Muting without mutedUntil mutes forever",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "chats"
+                ],
+                "summary": "Update my settings of a chat",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Chat ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Chat settings",
+                        "name": "settings",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.UpdateConversationSettingsInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.ConversationMember"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/chats/signal": {
+            "post": {
+                "description": "Send a typing or call signal for clients that receive events over Server-Sent Events or long-polling",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "chats"
+                ],
+                "summary": "Send an ephemeral signal",
+                "parameters": [
+                    {
+                        "description": "Signal",
+                        "name": "signal",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.ClientEvent"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/chats/sse/{id}": {
+            "get": {
+                "description": "Stream the events of a chat, or of the current user when no chat ID is given, as Server-Sent Events",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "chats"
+                ],
+                "summary": "Stream events with Server-Sent Events",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Chat ID",
+                        "name": "id",
+                        "in": "path"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Resume after this event ID",
+                        "name": "Last-Event-ID",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.Event"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/chats/timer/{id}": {
+            "patch": {
+                "description": "Turn disappearing messages on with a timer of 24 hours, 7 days or 90 days in seconds, or off with 0. Admins set it in groups and channels, either side in a direct chat",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "chats"
+                ],
+                "summary": "Set the disappearing messages timer",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Chat ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Timer",
+                        "name": "timer",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.SetMessageTimerInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.Conversation"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/chats/unpin/{messageId}": {
+            "post": {
+                "description": "Unpin a pinned message, only admins can unpin messages",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "chats"
+                ],
+                "summary": "Unpin a message",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Message ID",
+                        "name": "messageId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/chats/unreact/{messageId}": {
+            "post": {
+                "description": "Remove the current user's emoji reaction from a message",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "chats"
+                ],
+                "summary": "Remove a reaction",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Message ID",
+                        "name": "messageId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Reaction",
+                        "name": "reaction",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.ReactInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.ReactionCount"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/chats/update/{id}": {
+            "patch": {
+                "description": "Update the title, description, topic or avatar of a group chat, only the fields present are changed",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "chats"
+                ],
+                "summary": "Update a chat",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Chat ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Chat metadata",
+                        "name": "chat",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.UpdateConversationInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.Conversation"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/chats/view/{id}": {
+            "post": {
+                "description": "Count the current user once towards the view count of each message, the user's own messages are not counted",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "chats"
+                ],
+                "summary": "Mark messages as viewed",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Chat ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Viewed messages",
+                        "name": "views",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.ViewMessagesInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/chats/ws/{id}": {
+            "get": {
+                "description": "Stream the events of a chat, or of the current user when no chat ID is given, over a websocket. Clients send model.ClientEvent typing signals on the same socket.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "chats"
+                ],
+                "summary": "Handle a websocket connection",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Chat ID",
+                        "name": "id",
+                        "in": "path"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Resume after this event ID",
+                        "name": "lastEventId",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.Event"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/uploads/{key}": {
+            "get": {
+                "description": "Serve an uploaded file with the content type it was stored with. Only images are shown inline, anything else is downloaded",
+                "produces": [
+                    "application/octet-stream"
+                ],
+                "tags": [
+                    "attachments"
+                ],
+                "summary": "Download an uploaded file",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Storage key",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/auth/login": {
+            "get": {
+                "description": "Login a user with the input payload",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Login a user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Email",
+                        "name": "email",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Password",
+                        "name": "password",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.User"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/avatar": {
+            "post": {
+                "description": "Replace the current user's avatar with a PNG, JPEG or GIF picture. It is cropped to a square around its center and stored in several sizes",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Upload the current user's avatar",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "Picture",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.User"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/avatar/{id}": {
+            "get": {
+                "description": "Redirect to the user's avatar in the size closest to the one asked for, or draw their initials as SVG when they have no picture",
+                "produces": [
+                    "image/svg+xml"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Get a user's avatar",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Size in pixels, 128 by default",
+                        "name": "size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "302": {
+                        "description": "Found"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/contacts/list": {
+            "get": {
+                "description": "List the current user's contacts with their presence, the latest added first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "contacts"
+                ],
+                "summary": "Get the current user's contacts",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.ContactEntry"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/contacts/remove/{userId}": {
+            "post": {
+                "description": "Remove the user from the current user's contacts, and the current user from theirs",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "contacts"
+                ],
+                "summary": "Remove a contact",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.ContactRelation"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/contacts/requests/accept/{userId}": {
+            "post": {
+                "description": "Accept the contact request the user sent to the current user",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "contacts"
+                ],
+                "summary": "Accept a contact request",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.ContactRelation"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/contacts/requests/cancel/{userId}": {
+            "post": {
+                "description": "Take back the contact request the current user sent to the user",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "contacts"
+                ],
+                "summary": "Cancel a contact request",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.ContactRelation"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/contacts/requests/decline/{userId}": {
+            "post": {
+                "description": "Turn down the contact request the user sent to the current user",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "contacts"
+                ],
+                "summary": "Decline a contact request",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.ContactRelation"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/contacts/requests/incoming": {
+            "get": {
+                "description": "List the pending contact requests sent to the current user with their senders, latest first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "contacts"
+                ],
+                "summary": "Get the contact requests sent to the current user",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.ContactRequest"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/contacts/requests/outgoing": {
+            "get": {
+                "description": "List the pending contact requests the current user sent with their recipients, latest first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "contacts"
+                ],
+                "summary": "Get the contact requests the current user sent",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.ContactRequest"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/contacts/requests/send/{userId}": {
+            "post": {
+                "description": "Ask the user to become a contact of the current user. When they already asked the current user, their request is accepted instead",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "contacts"
+                ],
+                "summary": "Send a contact request",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.ContactRelation"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/contacts/status/{userId}": {
+            "get": {
+                "description": "Tell whether the user is a contact of the current user, or whether a request between them is pending",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "contacts"
+                ],
+                "summary": "Get the contact status with a user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.ContactRelation"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/create": {
+            "post": {
+                "description": "Create a new user with the input payload",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Create a new user",
+                "parameters": [
+                    {
+                        "description": "User",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.CreateUserInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/model.User"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/email": {
+            "patch": {
+                "description": "Change the email the current user logs in with, the current password confirms it",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Change the current user's email",
+                "parameters": [
+                    {
+                        "description": "New email",
+                        "name": "email",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.ChangeEmailInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.User"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/get": {
+            "get": {
+                "description": "Get a list of users by IDs",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Get a list of users by IDs",
+                "parameters": [
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "csv",
+                        "description": "User IDs",
+                        "name": "ids",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.User"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/get/{id}": {
+            "get": {
+                "description": "Get a user by ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Get a user by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.User"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/handle/{handle}": {
+            "get": {
+                "description": "Find the user with the username, with or without a leading @. A username changed recently still leads to its previous owner",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Find a user by handle",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Handle",
+                        "name": "handle",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.HandleLookup"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/password": {
+            "patch": {
+                "description": "Replace the current user's password, the current password confirms it",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Change the current user's password",
+                "parameters": [
+                    {
+                        "description": "Passwords",
+                        "name": "password",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.ChangePasswordInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/presence": {
+            "get": {
+                "description": "Get the online status, custom status and last-seen time of a list of users",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Get the presence of users",
+                "parameters": [
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "csv",
+                        "description": "User IDs",
+                        "name": "ids",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.Presence"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/privacy": {
+            "patch": {
+                "description": "Update the privacy settings that are present in the payload",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Update the current user's privacy settings",
+                "parameters": [
+                    {
+                        "description": "Privacy settings",
+                        "name": "privacy",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.UpdatePrivacyInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.User"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/search": {
+            "get": {
+                "description": "Find users by a prefix or a fuzzy match of their username or display name, or by their exact email. Contacts of the current user and users sharing conversations with them rank first, users who are not discoverable by them are left out",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Search the user directory",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username, display name or email",
+                        "name": "q",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Results per page, 20 by default and 50 at most",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "The nextOffset of the previous page",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.UserSearchPage"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/status": {
+            "patch": {
+                "description": "Set an away or do-not-disturb status with an optional text and expiry, ONLINE clears it",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Update the current user's status",
+                "parameters": [
+                    {
+                        "description": "Status",
+                        "name": "status",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.UpdateStatusInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.Presence"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/update": {
+            "patch": {
+                "description": "Update the display name, username, bio or profile picture of the current user, only the fields present are changed. The profile picture is made from an uploaded image attachment, an empty profilePictureId removes it",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Update the current user's profile",
+                "parameters": [
+                    {
+                        "description": "Profile changes",
+                        "name": "profile",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.UpdateProfileInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.User"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/username/available": {
+            "get": {
+                "description": "Tell whether the current user could take the username, and why not when they could not",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Check whether a username is available",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username",
+                        "name": "username",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.UsernameAvailability"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/username/history": {
+            "get": {
+                "description": "List the current user's username changes, latest first",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Get the current user's username history",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.UsernameChange"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "model.Attachment": {
+            "type": "object",
+            "properties": {
+                "contentType": {
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "fileName": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "size": {
+                    "type": "integer"
+                },
+                "uploaderId": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.Avatar": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                },
+                "variants": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.AvatarVariant"
+                    }
+                }
+            }
+        },
+        "model.AvatarVariant": {
+            "type": "object",
+            "properties": {
+                "size": {
+                    "type": "integer"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.CallMedia": {
+            "type": "string",
+            "enum": [
+                "AUDIO",
+                "VIDEO"
+            ],
+            "x-enum-varnames": [
+                "CallMediaAudio",
+                "CallMediaVideo"
+            ]
+        },
+        "model.CallParticipantState": {
+            "type": "string",
+            "enum": [
+                "RINGING",
+                "JOINED",
+                "DECLINED",
+                "BUSY",
+                "MISSED",
+                "LEFT"
+            ],
+            "x-enum-varnames": [
+                "CallParticipantStateRinging",
+                "CallParticipantStateJoined",
+                "CallParticipantStateDeclined",
+                "CallParticipantStateBusy",
+                "CallParticipantStateMissed",
+                "CallParticipantStateLeft"
+            ]
+        },
+        "model.CallResult": {
+            "type": "string",
+            "enum": [
+                "COMPLETED",
+                "MISSED",
+                "DECLINED",
+                "BUSY",
+                "CANCELLED"
+            ],
+            "x-enum-varnames": [
+                "CallResultCompleted",
+                "CallResultMissed",
+                "CallResultDeclined",
+                "CallResultBusy",
+                "CallResultCancelled"
+            ]
+        },
+        "model.CallSession": {
+            "type": "object",
+            "properties": {
+                "answeredAt": {
+                    "type": "string"
+                },
+                "conversationId": {
+                    "type": "string"
+                },
+                "endedAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "initiatorId": {
+                    "type": "string"
+                },
+                "media": {
+                    "$ref": "#/definitions/model.CallMedia"
+                },
+                "participants": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/model.CallParticipantState"
+                    }
+                },
+                "result": {
+                    "$ref": "#/definitions/model.CallResult"
+                },
+                "startedAt": {
+                    "type": "string"
+                },
+                "state": {
+                    "$ref": "#/definitions/model.CallState"
+                }
+            }
+        },
+        "model.CallState": {
+            "type": "string",
+            "enum": [
+                "RINGING",
+                "ACTIVE",
+                "ENDED"
+            ],
+            "x-enum-varnames": [
+                "CallStateRinging",
+                "CallStateActive",
+                "CallStateEnded"
+            ]
+        },
+        "model.ChangeEmailInput": {
+            "type": "object",
+            "properties": {
+                "currentPassword": {
+                    "type": "string"
+                },
+                "newEmail": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.ChangePasswordInput": {
+            "type": "object",
+            "properties": {
+                "currentPassword": {
+                    "type": "string"
+                },
+                "newPassword": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.ChatFolder": {
+            "type": "object",
+            "properties": {
+                "conversationIds": {
+                    "description": "explicit list, when it is not empty the rules are ignored",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "directOnly": {
+                    "type": "boolean"
+                },
+                "groupsOnly": {
+                    "type": "boolean"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "mentions": {
+                    "type": "boolean"
+                },
+                "muted": {
+                    "type": "boolean"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "position": {
+                    "type": "integer"
+                },
+                "unread": {
+                    "description": "rules",
+                    "type": "boolean"
+                },
+                "unreadCount": {
+                    "description": "UnreadCount totals the unread messages of the folder's conversations,\nit is only set when listing folders",
+                    "type": "integer"
+                },
+                "updatedAt": {
+                    "type": "string"
+                },
+                "userId": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.ChatFolderInput": {
+            "type": "object",
+            "properties": {
+                "conversationIds": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "directOnly": {
+                    "type": "boolean"
+                },
+                "groupsOnly": {
+                    "type": "boolean"
+                },
+                "mentions": {
+                    "type": "boolean"
+                },
+                "muted": {
+                    "type": "boolean"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "position": {
+                    "type": "integer"
+                },
+                "unread": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "model.ClientEvent": {
+            "type": "object",
+            "properties": {
+                "callId": {
+                    "type": "string"
+                },
+                "conversationId": {
+                    "type": "string"
+                },
+                "data": {
+                    "type": "object"
+                },
+                "media": {
+                    "$ref": "#/definitions/model.CallMedia"
+                },
+                "targetUserId": {
+                    "type": "string"
+                },
+                "type": {
+                    "$ref": "#/definitions/model.EventType"
+                }
+            }
+        },
+        "model.ContactEntry": {
+            "type": "object",
+            "properties": {
+                "presence": {
+                    "$ref": "#/definitions/model.Presence"
+                },
+                "since": {
+                    "type": "string"
+                },
+                "user": {
+                    "$ref": "#/definitions/model.User"
+                }
+            }
+        },
+        "model.ContactRelation": {
+            "type": "object",
+            "properties": {
+                "state": {
+                    "$ref": "#/definitions/model.ContactState"
+                },
+                "userId": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.ContactRequest": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "recipient": {
+                    "$ref": "#/definitions/model.User"
+                },
+                "recipientId": {
+                    "type": "string"
+                },
+                "sender": {
+                    "$ref": "#/definitions/model.User"
+                },
+                "senderId": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.ContactState": {
+            "type": "string",
+            "enum": [
+                "NONE",
+                "OUTGOING",
+                "INCOMING",
+                "CONTACT"
+            ],
+            "x-enum-varnames": [
+                "ContactStateNone",
+                "ContactStateOutgoing",
+                "ContactStateIncoming",
+                "ContactStateContact"
+            ]
+        },
+        "model.Conversation": {
+            "type": "object",
+            "properties": {
+                "avatar": {
+                    "$ref": "#/definitions/model.Attachment"
+                },
+                "avatarId": {
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "draft": {
+                    "description": "Draft is the current user's unsent message, set when listing\nconversations",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.MessageDraft"
+                        }
+                    ]
+                },
+                "id": {
+                    "type": "string"
+                },
+                "kind": {
+                    "$ref": "#/definitions/model.ConversationKind"
+                },
+                "lastMessageAt": {
+                    "description": "LastMessageAt orders the conversation lists",
+                    "type": "string"
+                },
+                "members": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.User"
+                    }
+                },
+                "messageTtl": {
+                    "description": "MessageTTL is the disappearing messages timer in seconds, messages sent\nwhile it is set expire that long after they were sent. 0 turns it off.",
+                    "type": "integer"
+                },
+                "messages": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.Message"
+                    }
+                },
+                "settings": {
+                    "description": "Settings holds the current user's membership, it is only set when\nlisting the conversations of that user",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.ConversationMember"
+                        }
+                    ]
+                },
+                "title": {
+                    "type": "string"
+                },
+                "topic": {
+                    "type": "string"
+                },
+                "unreadCount": {
+                    "description": "UnreadCount is the number of unread messages of the current user, set\ntogether with Settings",
+                    "type": "integer"
+                },
+                "updatedAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.ConversationInvite": {
+            "type": "object",
+            "properties": {
+                "conversationId": {
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "createdById": {
+                    "type": "string"
+                },
+                "expiresAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "maxUses": {
+                    "type": "integer"
+                },
+                "requiresApproval": {
+                    "type": "boolean"
+                },
+                "revoked": {
+                    "type": "boolean"
+                },
+                "token": {
+                    "type": "string"
+                },
+                "useCount": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.ConversationKind": {
+            "type": "string",
+            "enum": [
+                "DIRECT",
+                "GROUP",
+                "CHANNEL"
+            ],
+            "x-enum-varnames": [
+                "ConversationKindDirect",
+                "ConversationKindGroup",
+                "ConversationKindChannel"
+            ]
+        },
+        "model.ConversationMember": {
+            "type": "object",
+            "properties": {
+                "archivedAt": {
+                    "type": "string"
+                },
+                "conversationId": {
+                    "type": "string"
+                },
+                "joinedAt": {
+                    "type": "string"
+                },
+                "lastReadAt": {
+                    "description": "LastReadAt is the read marker, later messages of others are unread",
+                    "type": "string"
+                },
+                "mutedForever": {
+                    "description": "private settings of the member, nobody else sees them",
+                    "type": "boolean"
+                },
+                "mutedUntil": {
+                    "type": "string"
+                },
+                "nickname": {
+                    "type": "string"
+                },
+                "pinnedAt": {
+                    "type": "string"
+                },
+                "role": {
+                    "$ref": "#/definitions/model.MemberRole"
+                },
+                "userId": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.CreateConversationInput": {
+            "type": "object",
+            "properties": {
+                "kind": {
+                    "description": "Kind is GROUP when empty, DIRECT conversations have their own endpoint\nand a GROUP of two is turned into one",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.ConversationKind"
+                        }
+                    ]
+                },
+                "memberIds": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.CreateInviteInput": {
+            "type": "object",
+            "properties": {
+                "conversationId": {
+                    "type": "string"
+                },
+                "expiresAt": {
+                    "type": "string"
+                },
+                "maxUses": {
+                    "type": "integer"
+                },
+                "requiresApproval": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "model.CreatePollInput": {
+            "type": "object",
+            "properties": {
+                "anonymous": {
+                    "type": "boolean"
+                },
+                "closesAt": {
+                    "type": "string"
+                },
+                "multipleChoice": {
+                    "type": "boolean"
+                },
+                "options": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "question": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.CreateUserInput": {
+            "type": "object",
+            "properties": {
+                "displayName": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.DirectMessagePolicy": {
+            "type": "string",
+            "enum": [
+                "EVERYONE",
+                "CONTACTS"
+            ],
+            "x-enum-varnames": [
+                "DirectMessagePolicyEveryone",
+                "DirectMessagePolicyContacts"
+            ]
+        },
+        "model.Event": {
+            "type": "object",
+            "properties": {
+                "conversationId": {
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "payload": {},
+                "type": {
+                    "$ref": "#/definitions/model.EventType"
+                }
+            }
+        },
+        "model.EventPollResult": {
+            "type": "object",
+            "properties": {
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.Event"
+                    }
+                },
+                "lastEventId": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.EventType": {
+            "type": "string",
+            "enum": [
+                "MESSAGE_CREATED",
+                "MESSAGE_UPDATED",
+                "CONVERSATION_CREATED",
+                "METADATA_CHANGED",
+                "SETTINGS_CHANGED",
+                "MESSAGE_PINNED",
+                "MESSAGE_UNPINNED",
+                "REACTION_CHANGED",
+                "SCHEDULED_MESSAGE_FAILED",
+                "MESSAGES_DELETED",
+                "POLL_UPDATED",
+                "DRAFT_UPDATED",
+                "MEMBER_ADDED",
+                "MEMBER_REMOVED",
+                "TYPING_STARTED",
+                "TYPING_STOPPED",
+                "PRESENCE_CHANGED",
+                "JOIN_REQUESTED",
+                "JOIN_REQUEST_REVIEWED",
+                "CONTACT_REQUESTED",
+                "CONTACT_REQUEST_CLOSED",
+                "CONTACT_ADDED",
+                "CONTACT_REMOVED",
+                "CALL_START",
+                "CALL_ACCEPT",
+                "CALL_DECLINE",
+                "CALL_HANG_UP",
+                "CALL_OFFER",
+                "CALL_ANSWER",
+                "CALL_ICE_CANDIDATE",
+                "CALL_RINGING",
+                "CALL_UPDATED",
+                "CALL_ENDED",
+                "RESYNC"
+            ],
+            "x-enum-varnames": [
+                "EventTypeMessageCreated",
+                "EventTypeMessageUpdated",
+                "EventTypeConversationCreated",
+                "EventTypeMetadataChanged",
+                "EventTypeSettingsChanged",
+                "EventTypeMessagePinned",
+                "EventTypeMessageUnpinned",
+                "EventTypeReactionChanged",
+                "EventTypeScheduledFailed",
+                "EventTypeMessagesDeleted",
+                "EventTypePollUpdated",
+                "EventTypeDraftUpdated",
+                "EventTypeMemberAdded",
+                "EventTypeMemberRemoved",
+                "EventTypeTypingStarted",
+                "EventTypeTypingStopped",
+                "EventTypePresenceChanged",
+                "EventTypeJoinRequested",
+                "EventTypeJoinRequestReviewed",
+                "EventTypeContactRequested",
+                "EventTypeContactRequestClosed",
+                "EventTypeContactAdded",
+                "EventTypeContactRemoved",
+                "EventTypeCallStart",
+                "EventTypeCallAccept",
+                "EventTypeCallDecline",
+                "EventTypeCallHangUp",
+                "EventTypeCallOffer",
+                "EventTypeCallAnswer",
+                "EventTypeCallCandidate",
+                "EventTypeCallRinging",
+                "EventTypeCallUpdated",
+                "EventTypeCallEnded",
+                "EventTypeResync"
+            ]
+        },
+        "model.FormattedNode": {
+            "type": "object",
+            "properties": {
+                "children": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.FormattedNode"
+                    }
+                },
+                "language": {
+                    "description": "Language of CODE_BLOCK nodes, when the sender named one",
+                    "type": "string"
+                },
+                "start": {
+                    "description": "Start is the first number of ORDERED_LIST nodes",
+                    "type": "integer"
+                },
+                "text": {
+                    "description": "Text of TEXT, CODE and CODE_BLOCK nodes",
+                    "type": "string"
+                },
+                "type": {
+                    "$ref": "#/definitions/model.FormattedNodeType"
+                },
+                "url": {
+                    "description": "URL of LINK nodes, always http, https or mailto",
+                    "type": "string"
+                }
+            }
+        },
+        "model.FormattedNodeType": {
+            "type": "string",
+            "enum": [
+                "DOCUMENT",
+                "PARAGRAPH",
+                "TEXT",
+                "LINE_BREAK",
+                "BOLD",
+                "ITALIC",
+                "CODE",
+                "CODE_BLOCK",
+                "LINK",
+                "QUOTE",
+                "BULLET_LIST",
+                "ORDERED_LIST",
+                "LIST_ITEM"
+            ],
+            "x-enum-varnames": [
+                "FormattedNodeTypeDocument",
+                "FormattedNodeTypeParagraph",
+                "FormattedNodeTypeText",
+                "FormattedNodeTypeLineBreak",
+                "FormattedNodeTypeBold",
+                "FormattedNodeTypeItalic",
+                "FormattedNodeTypeCode",
+                "FormattedNodeTypeCodeBlock",
+                "FormattedNodeTypeLink",
+                "FormattedNodeTypeQuote",
+                "FormattedNodeTypeBulletList",
+                "FormattedNodeTypeOrderedList",
+                "FormattedNodeTypeListItem"
+            ]
+        },
+        "model.ForwardMessagesInput": {
+            "type": "object",
+            "properties": {
+                "messageIds": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "targetConversationIds": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "model.HandleLookup": {
+            "type": "object",
+            "properties": {
+                "redirectedFrom": {
+                    "type": "string"
+                },
+                "user": {
+                    "$ref": "#/definitions/model.User"
+                }
+            }
+        },
+        "model.InvitePreview": {
+            "type": "object",
+            "properties": {
+                "conversationId": {
+                    "type": "string"
+                },
+                "expiresAt": {
+                    "type": "string"
+                },
+                "memberCount": {
+                    "type": "integer"
+                },
+                "requiresApproval": {
+                    "type": "boolean"
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.JoinRequest": {
+            "type": "object",
+            "properties": {
+                "conversationId": {
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "inviteId": {
+                    "type": "string"
+                },
+                "reviewedAt": {
+                    "type": "string"
+                },
+                "reviewedById": {
+                    "type": "string"
+                },
+                "status": {
+                    "$ref": "#/definitions/model.JoinRequestStatus"
+                },
+                "user": {
+                    "$ref": "#/definitions/model.User"
+                },
+                "userId": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.JoinRequestStatus": {
+            "type": "string",
+            "enum": [
+                "PENDING",
+                "APPROVED",
+                "REJECTED"
+            ],
+            "x-enum-varnames": [
+                "JoinRequestStatusPending",
+                "JoinRequestStatusApproved",
+                "JoinRequestStatusRejected"
+            ]
+        },
+        "model.JoinResult": {
+            "type": "object",
+            "properties": {
+                "conversation": {
+                    "$ref": "#/definitions/model.Conversation"
+                },
+                "request": {
+                    "$ref": "#/definitions/model.JoinRequest"
+                },
+                "status": {
+                    "$ref": "#/definitions/model.JoinRequestStatus"
+                }
+            }
+        },
+        "model.LinkPreview": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "imageUrl": {
+                    "type": "string"
+                },
+                "messageId": {
+                    "type": "string"
+                },
+                "position": {
+                    "description": "Position orders the previews like the links in the message",
+                    "type": "integer"
+                },
+                "siteName": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.MemberRole": {
+            "type": "string",
+            "enum": [
+                "ADMIN",
+                "MEMBER"
+            ],
+            "x-enum-varnames": [
+                "MemberRoleAdmin",
+                "MemberRoleMember"
+            ]
+        },
+        "model.Message": {
+            "type": "object",
+            "properties": {
+                "attachments": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.Attachment"
+                    }
+                },
+                "clientMessageId": {
+                    "description": "ClientMessageID is the idempotency key the sender's client picked",
+                    "type": "string"
+                },
+                "content": {
+                    "type": "string"
+                },
+                "contentType": {
+                    "$ref": "#/definitions/model.MessageContentType"
+                },
+                "conversation": {
+                    "$ref": "#/definitions/model.Conversation"
+                },
+                "conversation_id": {
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "expiresAt": {
+                    "description": "ExpiresAt is set on messages sent while the disappearing messages timer\nof the conversation is on, the sweeper deletes them once it is past",
+                    "type": "string"
+                },
+                "format": {
+                    "description": "Format tells how Content is written, the tree parsed from MARKDOWN\ncontent is kept in Formatted",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.MessageFormat"
+                        }
+                    ]
+                },
+                "formatted": {
+                    "$ref": "#/definitions/model.FormattedNode"
+                },
+                "forwardedFromMessageId": {
+                    "description": "forwarding, the origin is the first message of a forwarding chain.\nForwardedFromSenderID is left out when the original sender hides it.",
+                    "type": "string"
+                },
+                "forwardedFromName": {
+                    "type": "string"
+                },
+                "forwardedFromSenderId": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "linkPreviews": {
+                    "description": "LinkPreviews are added once the links of the message were fetched",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.LinkPreview"
+                    }
+                },
+                "poll": {
+                    "$ref": "#/definitions/model.Poll"
+                },
+                "sender": {
+                    "$ref": "#/definitions/model.User"
+                },
+                "sender_id": {
+                    "type": "string"
+                },
+                "viewCount": {
+                    "description": "ViewCount counts the distinct members who saw the message, it is kept\nfor channels where listing every reader is not practical",
+                    "type": "integer"
+                }
+            }
+        },
+        "model.MessageContentType": {
+            "type": "string",
+            "enum": [
+                "TEXT",
+                "IMAGE",
+                "POLL",
+                "SYSTEM"
+            ],
+            "x-enum-varnames": [
+                "MessageContentTypeText",
+                "MessageContentTypeImage",
+                "MessageContentTypePoll",
+                "MessageContentTypeSystem"
+            ]
+        },
+        "model.MessageDraft": {
+            "type": "object",
+            "properties": {
+                "attachments": {
+                    "description": "Attachments are loaded from MessageDraftAttachment rows",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.Attachment"
+                    }
+                },
+                "content": {
+                    "type": "string"
+                },
+                "conversationId": {
+                    "type": "string"
+                },
+                "replyToMessageId": {
+                    "type": "string"
+                },
+                "updatedAt": {
+                    "description": "UpdatedAt is the time the client edited the draft, it is not\nrefreshed by the database",
+                    "type": "string"
+                },
+                "userId": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.MessageFormat": {
+            "type": "string",
+            "enum": [
+                "PLAIN",
+                "MARKDOWN"
+            ],
+            "x-enum-varnames": [
+                "MessageFormatPlain",
+                "MessageFormatMarkdown"
+            ]
+        },
+        "model.PinnedMessage": {
+            "type": "object",
+            "properties": {
+                "conversationId": {
+                    "type": "string"
+                },
+                "message": {
+                    "$ref": "#/definitions/model.Message"
+                },
+                "messageId": {
+                    "type": "string"
+                },
+                "pinnedAt": {
+                    "type": "string"
+                },
+                "pinnedById": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.Poll": {
+            "type": "object",
+            "properties": {
+                "anonymous": {
+                    "type": "boolean"
+                },
+                "closedAt": {
+                    "type": "string"
+                },
+                "closesAt": {
+                    "type": "string"
+                },
+                "messageId": {
+                    "type": "string"
+                },
+                "multipleChoice": {
+                    "type": "boolean"
+                },
+                "options": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.PollOption"
+                    }
+                },
+                "question": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.PollOption": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                },
+                "messageId": {
+                    "type": "string"
+                },
+                "position": {
+                    "type": "integer"
+                },
+                "text": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.PollOptionResults": {
+            "type": "object",
+            "properties": {
+                "optionId": {
+                    "type": "string"
+                },
+                "voterIds": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "votes": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.PollResults": {
+            "type": "object",
+            "properties": {
+                "closed": {
+                    "type": "boolean"
+                },
+                "messageId": {
+                    "type": "string"
+                },
+                "myOptionIds": {
+                    "description": "MyOptionIDs are the options the current user voted for",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "options": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.PollOptionResults"
+                    }
+                },
+                "totalVoters": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.Presence": {
+            "type": "object",
+            "properties": {
+                "lastSeenAt": {
+                    "type": "string"
+                },
+                "status": {
+                    "$ref": "#/definitions/model.PresenceStatus"
+                },
+                "statusText": {
+                    "type": "string"
+                },
+                "userId": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.PresenceStatus": {
+            "type": "string",
+            "enum": [
+                "ONLINE",
+                "OFFLINE",
+                "AWAY",
+                "DO_NOT_DISTURB"
+            ],
+            "x-enum-varnames": [
+                "PresenceStatusOnline",
+                "PresenceStatusOffline",
+                "PresenceStatusAway",
+                "PresenceStatusDoNotDisturb"
+            ]
+        },
+        "model.ReactInput": {
             "type": "object",
             "properties": {
-                "id": {
+                "emoji": {
                     "type": "string"
+                }
+            }
+        },
+        "model.ReactionCount": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
                 },
-                "members": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/model.User"
-                    }
+                "emoji": {
+                    "type": "string"
                 },
-                "messages": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/model.Message"
-                    }
+                "reacted": {
+                    "description": "Reacted tells whether the current user is one of the Count, it is\nnot set in events",
+                    "type": "boolean"
+                }
+            }
+        },
+        "model.RenderedMessage": {
+            "type": "object",
+            "properties": {
+                "html": {
+                    "description": "HTML only contains the tags the formatting maps to, with every text\nescaped",
+                    "type": "string"
                 },
-                "title": {
+                "messageId": {
+                    "type": "string"
+                },
+                "plainText": {
                     "type": "string"
                 }
             }
         },
-        "model.CreateConversationInput": {
+        "model.SaveDraftInput": {
             "type": "object",
             "properties": {
-                "memberIds": {
+                "attachmentIds": {
                     "type": "array",
                     "items": {
                         "type": "string"
                     }
                 },
-                "title": {
+                "content": {
+                    "type": "string"
+                },
+                "replyToMessageId": {
+                    "type": "string"
+                },
+                "updatedAt": {
                     "type": "string"
                 }
             }
         },
-        "model.CreateUserInput": {
+        "model.ScheduleMessageInput": {
             "type": "object",
             "properties": {
-                "displayName": {
+                "content": {
                     "type": "string"
                 },
-                "email": {
+                "contentType": {
+                    "$ref": "#/definitions/model.MessageContentType"
+                },
+                "conversationId": {
                     "type": "string"
                 },
-                "username": {
+                "format": {
+                    "description": "Format is PLAIN when empty",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.MessageFormat"
+                        }
+                    ]
+                },
+                "sendAt": {
                     "type": "string"
                 }
             }
         },
-        "model.Message": {
+        "model.ScheduledMessage": {
             "type": "object",
             "properties": {
                 "content": {
@@ -578,37 +5367,69 @@ const docTemplate = `{
                 "contentType": {
                     "$ref": "#/definitions/model.MessageContentType"
                 },
-                "conversation": {
-                    "$ref": "#/definitions/model.Conversation"
+                "conversationId": {
+                    "type": "string"
                 },
-                "conversation_id": {
+                "createdAt": {
                     "type": "string"
                 },
+                "error": {
+                    "description": "Error tells why delivery failed once Status is FAILED",
+                    "type": "string"
+                },
+                "format": {
+                    "$ref": "#/definitions/model.MessageFormat"
+                },
                 "id": {
                     "type": "string"
                 },
-                "sender": {
-                    "$ref": "#/definitions/model.User"
+                "messageId": {
+                    "description": "MessageID is the delivered message once Status is SENT",
+                    "type": "string"
                 },
-                "sender_id": {
+                "sendAt": {
+                    "type": "string"
+                },
+                "senderId": {
+                    "type": "string"
+                },
+                "status": {
+                    "$ref": "#/definitions/model.ScheduledMessageStatus"
+                },
+                "updatedAt": {
                     "type": "string"
                 }
             }
         },
-        "model.MessageContentType": {
+        "model.ScheduledMessageStatus": {
             "type": "string",
             "enum": [
-                "TEXT",
-                "IMAGE"
+                "PENDING",
+                "SENT",
+                "CANCELLED",
+                "FAILED"
             ],
             "x-enum-varnames": [
-                "MessageContentTypeText",
-                "MessageContentTypeImage"
+                "ScheduledMessageStatusPending",
+                "ScheduledMessageStatusSent",
+                "ScheduledMessageStatusCancelled",
+                "ScheduledMessageStatusFailed"
             ]
         },
         "model.SendMessageInput": {
             "type": "object",
             "properties": {
+                "attachmentIds": {
+                    "description": "AttachmentIDs are attachments the sender uploaded beforehand",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "clientMessageId": {
+                    "description": "ClientMessageID makes retries safe, sending again with the same ID\nreturns the message created the first time",
+                    "type": "string"
+                },
                 "content": {
                     "type": "string"
                 },
@@ -618,7 +5439,135 @@ const docTemplate = `{
                 "conversationId": {
                     "type": "string"
                 },
+                "format": {
+                    "description": "Format is PLAIN when empty, MARKDOWN is only allowed for TEXT messages",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.MessageFormat"
+                        }
+                    ]
+                },
+                "poll": {
+                    "description": "Poll is required for the POLL content type and ignored otherwise",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.CreatePollInput"
+                        }
+                    ]
+                },
                 "senderId": {
+                    "description": "SenderID is always the current user, it may be left out",
+                    "type": "string"
+                }
+            }
+        },
+        "model.SetMessageTimerInput": {
+            "type": "object",
+            "properties": {
+                "messageTtl": {
+                    "description": "MessageTTL in seconds, 0 turns disappearing messages off",
+                    "type": "integer"
+                }
+            }
+        },
+        "model.UpdateConversationInput": {
+            "type": "object",
+            "properties": {
+                "avatarId": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "topic": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.UpdateConversationSettingsInput": {
+            "type": "object",
+            "properties": {
+                "archived": {
+                    "type": "boolean"
+                },
+                "muted": {
+                    "type": "boolean"
+                },
+                "mutedUntil": {
+                    "type": "string"
+                },
+                "nickname": {
+                    "type": "string"
+                },
+                "pinned": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "model.UpdatePrivacyInput": {
+            "type": "object",
+            "properties": {
+                "directMessages": {
+                    "$ref": "#/definitions/model.DirectMessagePolicy"
+                },
+                "discoverability": {
+                    "$ref": "#/definitions/model.UserDiscoverability"
+                },
+                "hideForwardSender": {
+                    "type": "boolean"
+                },
+                "hideLastSeen": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "model.UpdateProfileInput": {
+            "type": "object",
+            "properties": {
+                "bio": {
+                    "type": "string"
+                },
+                "displayName": {
+                    "type": "string"
+                },
+                "profilePictureId": {
+                    "type": "string"
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.UpdateScheduledMessageInput": {
+            "type": "object",
+            "properties": {
+                "content": {
+                    "type": "string"
+                },
+                "contentType": {
+                    "$ref": "#/definitions/model.MessageContentType"
+                },
+                "format": {
+                    "$ref": "#/definitions/model.MessageFormat"
+                },
+                "sendAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.UpdateStatusInput": {
+            "type": "object",
+            "properties": {
+                "expiresAt": {
+                    "type": "string"
+                },
+                "status": {
+                    "$ref": "#/definitions/model.PresenceStatus"
+                },
+                "statusText": {
                     "type": "string"
                 }
             }
@@ -626,6 +5575,12 @@ const docTemplate = `{
         "model.User": {
             "type": "object",
             "properties": {
+                "avatar": {
+                    "$ref": "#/definitions/model.Avatar"
+                },
+                "bio": {
+                    "type": "string"
+                },
                 "conversations": {
                     "description": "associations",
                     "type": "array",
@@ -633,25 +5588,182 @@ const docTemplate = `{
                         "$ref": "#/definitions/model.Conversation"
                     }
                 },
+                "directMessages": {
+                    "description": "DirectMessages decides who may start a conversation with the user",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.DirectMessagePolicy"
+                        }
+                    ]
+                },
+                "discoverability": {
+                    "description": "Discoverability decides who finds the user through the directory search",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.UserDiscoverability"
+                        }
+                    ]
+                },
                 "displayName": {
                     "type": "string"
                 },
                 "email": {
                     "type": "string"
                 },
+                "hideForwardSender": {
+                    "description": "HideForwardSender keeps the user's ID and name off what others forward",
+                    "type": "boolean"
+                },
+                "hideLastSeen": {
+                    "description": "privacy",
+                    "type": "boolean"
+                },
                 "id": {
                     "type": "string"
                 },
-                "password": {
+                "profilePicture": {
                     "type": "string"
                 },
-                "profilePicture": {
+                "profilePictureId": {
+                    "description": "ProfilePicture is the URL of the largest size of Avatar, and\nProfilePictureID the attachment it was made from when it was not\nuploaded directly",
                     "type": "string"
                 },
+                "status": {
+                    "description": "presence, Status is the one picked by the user and only applies while\nthey are connected, see Presence for what other users see. LastSeenAt\nis only sent as part of a Presence, which honours HideLastSeen.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.PresenceStatus"
+                        }
+                    ]
+                },
+                "statusExpiresAt": {
+                    "type": "string"
+                },
+                "statusText": {
+                    "type": "string"
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.UserDiscoverability": {
+            "type": "string",
+            "enum": [
+                "EVERYONE",
+                "CONVERSATION_PEERS",
+                "NOBODY"
+            ],
+            "x-enum-varnames": [
+                "UserDiscoverabilityEveryone",
+                "UserDiscoverabilityConversationPeers",
+                "UserDiscoverabilityNobody"
+            ]
+        },
+        "model.UserSearchPage": {
+            "type": "object",
+            "properties": {
+                "nextOffset": {
+                    "type": "integer"
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.UserSearchResult"
+                    }
+                }
+            }
+        },
+        "model.UserSearchResult": {
+            "type": "object",
+            "properties": {
+                "isContact": {
+                    "type": "boolean"
+                },
+                "sharedConversations": {
+                    "type": "integer"
+                },
+                "user": {
+                    "$ref": "#/definitions/model.User"
+                }
+            }
+        },
+        "model.UsernameAvailability": {
+            "type": "object",
+            "properties": {
+                "available": {
+                    "type": "boolean"
+                },
+                "reason": {
+                    "description": "Reason tells why the username cannot be taken, it is empty when it is\navailable",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.UsernameUnavailableReason"
+                        }
+                    ]
+                },
                 "username": {
                     "type": "string"
                 }
             }
+        },
+        "model.UsernameChange": {
+            "type": "object",
+            "properties": {
+                "changedAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "newUsername": {
+                    "type": "string"
+                },
+                "oldUsername": {
+                    "type": "string"
+                },
+                "redirectUntil": {
+                    "type": "string"
+                },
+                "userId": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.UsernameUnavailableReason": {
+            "type": "string",
+            "enum": [
+                "INVALID",
+                "RESERVED",
+                "TAKEN"
+            ],
+            "x-enum-varnames": [
+                "UsernameUnavailableReasonInvalid",
+                "UsernameUnavailableReasonReserved",
+                "UsernameUnavailableReasonTaken"
+            ]
+        },
+        "model.ViewMessagesInput": {
+            "type": "object",
+            "properties": {
+                "messageIds": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "model.VotePollInput": {
+            "type": "object",
+            "properties": {
+                "optionIds": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
         }
     }
 }`
@@ -2,10 +2,13 @@ package main
 
 import (
 	"os"
+	"time"
 
+	"github.com/badaccuracyid/softeng_backend/src/controllers"
 	"github.com/badaccuracyid/softeng_backend/src/database"
 	"github.com/badaccuracyid/softeng_backend/src/middleware"
 	"github.com/badaccuracyid/softeng_backend/src/routes"
+	"github.com/badaccuracyid/softeng_backend/src/unfurl"
 	"github.com/badaccuracyid/softeng_backend/src/utils"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -38,6 +41,41 @@ func main() {
 		panic(err)
 	}
 
+	postgresDatabase, err := database.GetPostgresDatabase()
+	if err != nil {
+		panic(err)
+	}
+
+	// Background jobs
+	controllers.RecordCallHistory(postgresDatabase)
+
+	messageScheduler := controllers.NewMessageScheduler(
+		postgresDatabase,
+		utils.GetEnvDuration("SCHEDULER_INTERVAL", 5*time.Second),
+		utils.GetEnvInt("SCHEDULER_BATCH_SIZE", 100),
+	)
+	go messageScheduler.Run()
+
+	messageSweeper := controllers.NewMessageSweeper(
+		postgresDatabase,
+		utils.GetEnvDuration("SWEEPER_INTERVAL", time.Minute),
+		utils.GetEnvInt("SWEEPER_BATCH_SIZE", 500),
+	)
+	go messageSweeper.Run()
+
+	unfurlOptions := unfurl.DefaultOptions
+	unfurlOptions.Timeout = utils.GetEnvDuration("LINK_PREVIEW_TIMEOUT", unfurlOptions.Timeout)
+	unfurlOptions.MaxBodySize = int64(utils.GetEnvInt("LINK_PREVIEW_MAX_BODY_SIZE", int(unfurlOptions.MaxBodySize)))
+	unfurlOptions.CacheSize = utils.GetEnvInt("LINK_PREVIEW_CACHE_SIZE", unfurlOptions.CacheSize)
+	unfurlOptions.CacheTTL = utils.GetEnvDuration("LINK_PREVIEW_CACHE_TTL", unfurlOptions.CacheTTL)
+
+	linkPreviewer := controllers.NewLinkPreviewer(
+		postgresDatabase,
+		unfurl.NewUnfurler(unfurlOptions),
+		utils.GetEnvInt("LINK_PREVIEW_QUEUE_SIZE", 1000),
+	)
+	go linkPreviewer.Run(utils.GetEnvInt("LINK_PREVIEW_WORKERS", 4))
+
 	router := gin.Default()
 
 	// Add CORS middleware
@@ -50,7 +88,15 @@ func main() {
 	router.Use(middleware.UserMiddleware())
 
 	router = routes.InitializeUserRoutes(router)
+	router = routes.InitializeContactRoutes(router)
 	router = routes.InitializeChatRoutes(router)
+	router = routes.InitializeInviteRoutes(router)
+	router = routes.InitializeFolderRoutes(router)
+	router = routes.InitializeScheduledMessageRoutes(router)
+	router = routes.InitializePollRoutes(router)
+	router = routes.InitializeDraftRoutes(router)
+	router = routes.InitializeAttachmentRoutes(router)
+	router = routes.InitializeUploadRoutes(router)
 
 	// Swagger endpoint
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
@@ -0,0 +1,171 @@
+// Package avatar turns uploaded pictures into profile avatars: the picture
+// is validated, center-cropped to a square and stored in a few sizes. Users
+// without a picture get an avatar drawn from their initials.
+package avatar
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"strconv"
+
+	"github.com/badaccuracyid/softeng_backend/src/utils"
+)
+
+const (
+	// MaxUploadSize is the largest picture accepted, in bytes
+	MaxUploadSize = 10 << 20
+	// the picture has to be at least as large as the smallest size once
+	// cropped, and is never scaled up
+	minDimension = 64
+	// larger pictures would take too much memory to decode
+	maxDimension = 4096
+	jpegQuality  = 88
+)
+
+// Sizes are the square sizes avatars are stored in, smallest first.
+var Sizes = []int{64, 128, 256, 512}
+
+var (
+	ErrUnsupported = fmt.Errorf("%w: avatar must be a PNG, JPEG or GIF picture", utils.ErrInvalidInput)
+	ErrTooLarge    = fmt.Errorf("%w: avatar picture is too large", utils.ErrInvalidInput)
+	ErrTooSmall    = fmt.Errorf("%w: avatar picture must be at least %dx%d", utils.ErrInvalidInput, minDimension, minDimension)
+)
+
+var supportedFormats = map[string]bool{
+	"png":  true,
+	"jpeg": true,
+	"gif":  true,
+}
+
+// Variant is one size of a processed avatar, encoded as JPEG.
+type Variant struct {
+	Size int
+	Data []byte
+}
+
+// StorageKey is where the given size of an avatar is kept.
+func StorageKey(avatarID string, size int) string {
+	return "avatars/" + avatarID + "/" + strconv.Itoa(size) + ".jpg"
+}
+
+// Process reads a picture, crops it to a square and scales it to every
+// size that is not larger than the square. The dimensions are checked before
+// the picture is decoded.
+func Process(reader io.Reader) ([]*Variant, error) {
+	data, err := io.ReadAll(io.LimitReader(reader, MaxUploadSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxUploadSize {
+		return nil, ErrTooLarge
+	}
+
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || !supportedFormats[format] {
+		return nil, ErrUnsupported
+	}
+	if config.Width > maxDimension || config.Height > maxDimension {
+		return nil, ErrTooLarge
+	}
+	if config.Width < minDimension || config.Height < minDimension {
+		return nil, ErrTooSmall
+	}
+
+	picture, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupported
+	}
+
+	square := cropSquare(picture.Bounds())
+	largest := Sizes[0]
+	for _, size := range Sizes {
+		if size <= square.Dx() {
+			largest = size
+		}
+	}
+
+	// the source is read once, the smaller sizes come from the largest
+	scaled := scale(picture, square, largest)
+	variants := []*Variant{}
+	for _, size := range Sizes {
+		if size > largest {
+			break
+		}
+
+		resized := scaled
+		if size != largest {
+			resized = scale(scaled, scaled.Bounds(), size)
+		}
+
+		buffer := &bytes.Buffer{}
+		if err := jpeg.Encode(buffer, flatten(resized), &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return nil, err
+		}
+		variants = append(variants, &Variant{Size: size, Data: buffer.Bytes()})
+	}
+
+	return variants, nil
+}
+
+// cropSquare is the largest square centered in the bounds.
+func cropSquare(bounds image.Rectangle) image.Rectangle {
+	side := min(bounds.Dx(), bounds.Dy())
+	x := bounds.Min.X + (bounds.Dx()-side)/2
+	y := bounds.Min.Y + (bounds.Dy()-side)/2
+	return image.Rect(x, y, x+side, y+side)
+}
+
+// scale shrinks the square area of the source to size by averaging the
+// source pixels each target pixel covers.
+func scale(source image.Image, area image.Rectangle, size int) *image.RGBA {
+	target := image.NewRGBA(image.Rect(0, 0, size, size))
+	side := area.Dx()
+
+	for y := 0; y < size; y++ {
+		top := area.Min.Y + y*side/size
+		bottom := max(area.Min.Y+(y+1)*side/size, top+1)
+		for x := 0; x < size; x++ {
+			left := area.Min.X + x*side/size
+			right := max(area.Min.X+(x+1)*side/size, left+1)
+
+			var r, g, b, a, count uint64
+			for sy := top; sy < bottom; sy++ {
+				for sx := left; sx < right; sx++ {
+					pr, pg, pb, pa := source.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(pr), g+uint64(pg), b+uint64(pb), a+uint64(pa)
+					count++
+				}
+			}
+
+			target.SetRGBA(x, y, color.RGBA{
+				R: uint8(r / count >> 8),
+				G: uint8(g / count >> 8),
+				B: uint8(b / count >> 8),
+				A: uint8(a / count >> 8),
+			})
+		}
+	}
+
+	return target
+}
+
+// flatten puts the picture over a white background, JPEG has no
+// transparency.
+func flatten(picture *image.RGBA) *image.RGBA {
+	flat := image.NewRGBA(picture.Bounds())
+	for i := 0; i < len(picture.Pix); i += 4 {
+		// the pixels are premultiplied, what is left of the alpha is white
+		white := 255 - picture.Pix[i+3]
+		flat.Pix[i] = picture.Pix[i] + white
+		flat.Pix[i+1] = picture.Pix[i+1] + white
+		flat.Pix[i+2] = picture.Pix[i+2] + white
+		flat.Pix[i+3] = 255
+	}
+	return flat
+}
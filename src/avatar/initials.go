@@ -0,0 +1,49 @@
+package avatar
+
+import (
+	"fmt"
+	"hash/fnv"
+	"html"
+	"strings"
+	"unicode"
+)
+
+// palette holds the backgrounds of initials avatars, dark enough for white
+// text.
+var palette = []string{
+	"#C0392B", "#D35400", "#B7950B", "#1E8449", "#117A65",
+	"#1F618D", "#6C3483", "#AD1457", "#4E342E", "#37474F",
+}
+
+// Initials are the first letters of the first and last words of the name,
+// in upper case, or "?" when the name has no letter or digit.
+func Initials(name string) string {
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) == 0 {
+		return "?"
+	}
+
+	initials := []rune{[]rune(words[0])[0]}
+	if len(words) > 1 {
+		initials = append(initials, []rune(words[len(words)-1])[0])
+	}
+	return strings.ToUpper(string(initials))
+}
+
+// InitialsSVG draws the initials of the name over a color picked from the
+// seed, so a user keeps the same color when their name changes.
+func InitialsSVG(name string, seed string, size int) []byte {
+	hash := fnv.New32a()
+	hash.Write([]byte(seed))
+	background := palette[hash.Sum32()%uint32(len(palette))]
+
+	return []byte(fmt.Sprintf(
+		`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 100 100">`+
+			`<rect width="100" height="100" fill="%s"/>`+
+			`<text x="50" y="50" dy=".35em" fill="#FFFFFF" font-family="sans-serif" font-size="42" text-anchor="middle">%s</text>`+
+			`</svg>`,
+		size, size, background, html.EscapeString(Initials(name)),
+	))
+}
@@ -0,0 +1,123 @@
+package controllers
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/badaccuracyid/softeng_backend/src/database/dao"
+	"github.com/badaccuracyid/softeng_backend/src/model"
+	"github.com/badaccuracyid/softeng_backend/src/storage"
+	"github.com/badaccuracyid/softeng_backend/src/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const maxAttachmentSize = 25 << 20
+
+type AttachmentController interface {
+	SetContext(ctx *gin.Context)
+	UploadAttachment(file *multipart.FileHeader) (*model.Attachment, error)
+	GetAttachment(id string) (*model.Attachment, error)
+	OpenUpload(key string) (*model.Attachment, io.ReadCloser, error)
+}
+
+type attachmentController struct {
+	ctx           *gin.Context
+	attachmentDAO *dao.AttachmentDAO
+	storage       storage.BlobStorage
+}
+
+func NewAttachmentController(db *gorm.DB) AttachmentController {
+	return &attachmentController{
+		attachmentDAO: dao.NewAttachmentDAO(db),
+		storage:       storage.GetBlobStorage(),
+	}
+}
+
+func (s *attachmentController) SetContext(ctx *gin.Context) {
+	s.ctx = ctx
+}
+
+// UploadAttachment stores the file and records it. The content type is
+// sniffed from the data rather than trusted from the client.
+func (s *attachmentController) UploadAttachment(file *multipart.FileHeader) (*model.Attachment, error) {
+	userId := utils.GetCurrentUserID(s.ctx)
+	if userId == "" {
+		return nil, utils.ErrUnauthenticated
+	}
+
+	if file.Size <= 0 || file.Size > maxAttachmentSize {
+		return nil, utils.ErrInvalidInput
+	}
+
+	source, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer source.Close()
+
+	reader := bufio.NewReader(io.LimitReader(source, maxAttachmentSize))
+	head, _ := reader.Peek(512)
+
+	id := uuid.New().String()
+	key := "attachments/" + id
+	if err := s.storage.Put(key, reader); err != nil {
+		return nil, err
+	}
+
+	attachment := &model.Attachment{
+		ID:          id,
+		UploaderID:  userId,
+		FileName:    file.Filename,
+		ContentType: http.DetectContentType(head),
+		Size:        file.Size,
+		StorageKey:  key,
+		URL:         s.storage.URL(key),
+	}
+
+	if err := s.attachmentDAO.CreateAttachment(attachment); err != nil {
+		_ = s.storage.Delete(key)
+		return nil, err
+	}
+
+	return attachment, nil
+}
+
+func (s *attachmentController) GetAttachment(id string) (*model.Attachment, error) {
+	return s.attachmentDAO.GetAttachmentByID(id)
+}
+
+// OpenUpload opens a stored file by its key, with the attachment telling
+// how to serve it. Avatars are not attachments, they are always the JPEG
+// files the server made itself.
+func (s *attachmentController) OpenUpload(key string) (*model.Attachment, io.ReadCloser, error) {
+	var attachment *model.Attachment
+	switch {
+	case strings.HasPrefix(key, "attachments/"):
+		var err error
+		attachment, err = s.attachmentDAO.GetAttachmentByID(strings.TrimPrefix(key, "attachments/"))
+		if err != nil {
+			return nil, nil, err
+		}
+	case strings.HasPrefix(key, "avatars/") && strings.HasSuffix(key, ".jpg"):
+		attachment = &model.Attachment{FileName: path.Base(key), ContentType: "image/jpeg", Size: -1, StorageKey: key}
+	default:
+		return nil, nil, utils.ErrNotFound
+	}
+
+	reader, err := s.storage.Open(attachment.StorageKey)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil, utils.ErrNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return attachment, reader, nil
+}
@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"bytes"
+	"mime/multipart"
+
+	"github.com/badaccuracyid/softeng_backend/src/avatar"
+	"github.com/badaccuracyid/softeng_backend/src/model"
+	"github.com/badaccuracyid/softeng_backend/src/utils"
+	"github.com/google/uuid"
+)
+
+// UploadAvatar makes the uploaded picture the current user's avatar.
+func (s *userController) UploadAvatar(file *multipart.FileHeader) (*model.User, error) {
+	userId := utils.GetCurrentUserID(s.ctx)
+	if userId == "" {
+		return nil, utils.ErrUnauthenticated
+	}
+
+	if file.Size <= 0 || file.Size > avatar.MaxUploadSize {
+		return nil, avatar.ErrTooLarge
+	}
+
+	source, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer source.Close()
+
+	variants, err := avatar.Process(source)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.replaceAvatar(userId, variants, nil); err != nil {
+		return nil, err
+	}
+
+	return s.userDAO.GetUserByID(userId)
+}
+
+// GetAvatar returns the URL of the user's avatar in the size closest to the
+// one asked for, or their initials avatar as SVG when they have no picture.
+func (s *userController) GetAvatar(userID string, size int) (string, []byte, error) {
+	user, err := s.userDAO.GetUserByID(userID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if user.Avatar != nil && len(user.Avatar.Variants) > 0 {
+		return user.Avatar.Variant(size).URL, nil, nil
+	}
+
+	name := user.DisplayName
+	if name == "" {
+		name = user.Username
+	}
+	return "", avatar.InitialsSVG(name, user.ID, size), nil
+}
+
+// attachmentAvatar makes the avatar variants from an attachment the user
+// uploaded earlier, a nil attachment has none.
+func (s *userController) attachmentAvatar(attachment *model.Attachment) ([]*avatar.Variant, error) {
+	if attachment == nil {
+		return nil, nil
+	}
+
+	source, err := s.storage.Open(attachment.StorageKey)
+	if err != nil {
+		return nil, err
+	}
+	defer source.Close()
+
+	return avatar.Process(source)
+}
+
+// replaceAvatar stores the variants under a new avatar and swaps it in. The
+// previous avatar's files are only deleted once nothing points to them, and
+// the new ones are deleted when the swap fails.
+func (s *userController) replaceAvatar(userID string, variants []*avatar.Variant, attachmentID *string) error {
+	next, err := s.storeAvatar(variants)
+	if err != nil {
+		return err
+	}
+
+	previous, err := s.userDAO.ReplaceAvatar(userID, next, attachmentID)
+	if err != nil {
+		s.deleteAvatarFiles(next)
+		return err
+	}
+
+	s.deleteAvatarFiles(previous)
+	return nil
+}
+
+// storeAvatar writes the files of the variants under a new avatar, which is
+// nil without variants. Nothing is left behind when a write fails.
+func (s *userController) storeAvatar(variants []*avatar.Variant) (*model.Avatar, error) {
+	if len(variants) == 0 {
+		return nil, nil
+	}
+
+	stored := &model.Avatar{ID: uuid.New().String()}
+	for _, variant := range variants {
+		key := avatar.StorageKey(stored.ID, variant.Size)
+		if err := s.storage.Put(key, bytes.NewReader(variant.Data)); err != nil {
+			s.deleteAvatarFiles(stored)
+			return nil, err
+		}
+		stored.Variants = append(stored.Variants, &model.AvatarVariant{Size: variant.Size, URL: s.storage.URL(key)})
+	}
+	return stored, nil
+}
+
+func (s *userController) deleteAvatarFiles(stored *model.Avatar) {
+	if stored == nil {
+		return
+	}
+	for _, variant := range stored.Variants {
+		_ = s.storage.Delete(avatar.StorageKey(stored.ID, variant.Size))
+	}
+}
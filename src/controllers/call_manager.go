@@ -0,0 +1,396 @@
+package controllers
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/badaccuracyid/softeng_backend/src/model"
+	"github.com/badaccuracyid/softeng_backend/src/utils"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const callRingTimeout = 30 * time.Second
+
+// CallManager keeps the signaling state of voice and video calls. Media never
+// goes through the server, it only relays SDP and ICE between the peers over
+// their user event streams. Every method takes the acting user explicitly so
+// it can be driven by websocket frames, HTTP requests or scripted test peers.
+type CallManager struct {
+	mutex       sync.Mutex
+	hub         *EventHub
+	ringTimeout time.Duration
+	onEnded     func(call *model.CallSession)
+
+	calls          map[string]*model.CallSession
+	byConversation map[string]string
+	byUser         map[string]string
+	ringTimers     map[string]*time.Timer
+}
+
+var callManager = NewCallManager(eventHub, callRingTimeout, nil)
+
+// NewCallManager creates a manager publishing on the hub. onEnded is called
+// once per call, outside of the manager lock, after it ended.
+func NewCallManager(hub *EventHub, ringTimeout time.Duration, onEnded func(call *model.CallSession)) *CallManager {
+	return &CallManager{
+		hub:            hub,
+		ringTimeout:    ringTimeout,
+		onEnded:        onEnded,
+		calls:          make(map[string]*model.CallSession),
+		byConversation: make(map[string]string),
+		byUser:         make(map[string]string),
+		ringTimers:     make(map[string]*time.Timer),
+	}
+}
+
+// RecordCallHistory leaves a SYSTEM entry in the conversation of every call
+// that ends. It is wired once at startup.
+func RecordCallHistory(db *gorm.DB) {
+	callManager.SetOnEnded(newChatController(db).recordCallHistory)
+}
+
+// SetOnEnded replaces the callback receiving every call that ended.
+func (m *CallManager) SetOnEnded(onEnded func(call *model.CallSession)) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.onEnded = onEnded
+}
+
+// Start rings every other member of the conversation. If a call is already
+// going on in the conversation the user joins it instead.
+func (m *CallManager) Start(userID string, conversationID string, memberIDs []string, media model.CallMedia) (*model.CallSession, error) {
+	if !media.IsValid() {
+		return nil, utils.ErrInvalidInput
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if callID, found := m.byConversation[conversationID]; found {
+		return m.acceptLocked(userID, callID)
+	}
+
+	if _, busy := m.byUser[userID]; busy {
+		return nil, utils.ErrConflict
+	}
+
+	call := &model.CallSession{
+		ID:             uuid.New().String(),
+		ConversationID: conversationID,
+		InitiatorID:    userID,
+		Media:          media,
+		State:          model.CallStateRinging,
+		Participants:   map[string]model.CallParticipantState{userID: model.CallParticipantStateJoined},
+		StartedAt:      time.Now(),
+	}
+
+	for _, memberID := range memberIDs {
+		if memberID == userID {
+			continue
+		}
+		if _, busy := m.byUser[memberID]; busy {
+			call.Participants[memberID] = model.CallParticipantStateBusy
+			continue
+		}
+		call.Participants[memberID] = model.CallParticipantStateRinging
+	}
+
+	m.calls[call.ID] = call
+	m.byConversation[conversationID] = call.ID
+	m.byUser[userID] = call.ID
+
+	for memberID, state := range call.Participants {
+		if state == model.CallParticipantStateRinging {
+			m.sendToUser(memberID, model.EventTypeCallRinging, call, call)
+		}
+	}
+	m.sendToUser(userID, model.EventTypeCallUpdated, call, call)
+
+	if m.ringingCount(call) == 0 {
+		m.endLocked(call)
+		return copyCallSession(call), nil
+	}
+
+	m.ringTimers[call.ID] = time.AfterFunc(m.ringTimeout, func() {
+		m.ringTimedOut(call.ID)
+	})
+	return copyCallSession(call), nil
+}
+
+func (m *CallManager) Accept(userID string, callID string) (*model.CallSession, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.acceptLocked(userID, callID)
+}
+
+func (m *CallManager) acceptLocked(userID string, callID string) (*model.CallSession, error) {
+	call, err := m.participantCall(userID, callID)
+	if err != nil {
+		return nil, err
+	}
+
+	if call.Participants[userID] == model.CallParticipantStateJoined {
+		return copyCallSession(call), nil
+	}
+	if activeCallID, busy := m.byUser[userID]; busy && activeCallID != callID {
+		return nil, utils.ErrConflict
+	}
+
+	call.Participants[userID] = model.CallParticipantStateJoined
+	m.byUser[userID] = callID
+	if call.State == model.CallStateRinging {
+		now := time.Now()
+		call.State = model.CallStateActive
+		call.AnsweredAt = &now
+	}
+
+	m.broadcast(call, model.EventTypeCallUpdated, call)
+	return copyCallSession(call), nil
+}
+
+func (m *CallManager) Decline(userID string, callID string) (*model.CallSession, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	call, err := m.participantCall(userID, callID)
+	if err != nil {
+		return nil, err
+	}
+
+	if call.Participants[userID] != model.CallParticipantStateRinging {
+		return nil, utils.ErrConflict
+	}
+
+	call.Participants[userID] = model.CallParticipantStateDeclined
+	m.broadcast(call, model.EventTypeCallUpdated, call)
+	m.endIfOver(call)
+	return copyCallSession(call), nil
+}
+
+func (m *CallManager) HangUp(userID string, callID string) (*model.CallSession, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	call, err := m.participantCall(userID, callID)
+	if err != nil {
+		return nil, err
+	}
+
+	m.leave(call, userID)
+	return copyCallSession(call), nil
+}
+
+// HangUpAll removes the user from their current call, if any, for example
+// when their last realtime connection went away.
+func (m *CallManager) HangUpAll(userID string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	callID, found := m.byUser[userID]
+	if !found {
+		return
+	}
+	m.leave(m.calls[callID], userID)
+}
+
+// Relay forwards an SDP offer or answer, or an ICE candidate, to another
+// participant of the call.
+func (m *CallManager) Relay(userID string, callID string, targetUserID string, eventType model.EventType, data json.RawMessage) error {
+	switch eventType {
+	case model.EventTypeCallOffer, model.EventTypeCallAnswer, model.EventTypeCallCandidate:
+	default:
+		return utils.ErrInvalidInput
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	call, err := m.participantCall(userID, callID)
+	if err != nil {
+		return err
+	}
+
+	if call.Participants[userID] != model.CallParticipantStateJoined {
+		return utils.ErrForbidden
+	}
+	switch call.Participants[targetUserID] {
+	case model.CallParticipantStateJoined, model.CallParticipantStateRinging:
+	default:
+		return utils.ErrNotFound
+	}
+
+	m.sendToUser(targetUserID, eventType, call, &model.CallSignalPayload{
+		CallID:     callID,
+		FromUserID: userID,
+		Data:       data,
+	})
+	return nil
+}
+
+func (m *CallManager) GetCall(callID string) (*model.CallSession, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	call, found := m.calls[callID]
+	if !found {
+		return nil, utils.ErrNotFound
+	}
+	return copyCallSession(call), nil
+}
+
+func (m *CallManager) ringTimedOut(callID string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	call, found := m.calls[callID]
+	if !found {
+		return
+	}
+	delete(m.ringTimers, callID)
+
+	for participantID, state := range call.Participants {
+		if state == model.CallParticipantStateRinging {
+			call.Participants[participantID] = model.CallParticipantStateMissed
+		}
+	}
+
+	m.broadcast(call, model.EventTypeCallUpdated, call)
+	m.endIfOver(call)
+}
+
+func (m *CallManager) leave(call *model.CallSession, userID string) {
+	if call.Participants[userID] != model.CallParticipantStateJoined {
+		return
+	}
+
+	call.Participants[userID] = model.CallParticipantStateLeft
+	delete(m.byUser, userID)
+
+	// the caller hanging up before anyone answered cancels the call
+	if call.State == model.CallStateRinging && userID == call.InitiatorID {
+		for participantID, state := range call.Participants {
+			if state == model.CallParticipantStateRinging {
+				call.Participants[participantID] = model.CallParticipantStateMissed
+			}
+		}
+	}
+
+	m.broadcast(call, model.EventTypeCallUpdated, call)
+	m.endIfOver(call)
+}
+
+// endIfOver ends the call when nobody is left to talk to: fewer than two
+// people are in it and nobody else is still ringing.
+func (m *CallManager) endIfOver(call *model.CallSession) {
+	joined := 0
+	for _, state := range call.Participants {
+		if state == model.CallParticipantStateJoined {
+			joined++
+		}
+	}
+
+	if joined >= 2 || (joined == 1 && m.ringingCount(call) > 0) {
+		return
+	}
+	m.endLocked(call)
+}
+
+func (m *CallManager) endLocked(call *model.CallSession) {
+	now := time.Now()
+	call.State = model.CallStateEnded
+	call.EndedAt = &now
+	call.Result = callResult(call)
+
+	if timer, found := m.ringTimers[call.ID]; found {
+		timer.Stop()
+		delete(m.ringTimers, call.ID)
+	}
+	for participantID, state := range call.Participants {
+		if state == model.CallParticipantStateJoined {
+			call.Participants[participantID] = model.CallParticipantStateLeft
+		}
+		if m.byUser[participantID] == call.ID {
+			delete(m.byUser, participantID)
+		}
+	}
+	delete(m.calls, call.ID)
+	delete(m.byConversation, call.ConversationID)
+
+	m.broadcast(call, model.EventTypeCallEnded, call)
+
+	if m.onEnded != nil {
+		go m.onEnded(copyCallSession(call))
+	}
+}
+
+func (m *CallManager) participantCall(userID string, callID string) (*model.CallSession, error) {
+	call, found := m.calls[callID]
+	if !found {
+		return nil, utils.ErrNotFound
+	}
+	if _, isParticipant := call.Participants[userID]; !isParticipant {
+		return nil, utils.ErrForbidden
+	}
+	return call, nil
+}
+
+func (m *CallManager) ringingCount(call *model.CallSession) int {
+	count := 0
+	for _, state := range call.Participants {
+		if state == model.CallParticipantStateRinging {
+			count++
+		}
+	}
+	return count
+}
+
+func (m *CallManager) broadcast(call *model.CallSession, eventType model.EventType, payload any) {
+	for participantID := range call.Participants {
+		m.sendToUser(participantID, eventType, call, payload)
+	}
+}
+
+func (m *CallManager) sendToUser(userID string, eventType model.EventType, call *model.CallSession, payload any) {
+	if snapshot, isCall := payload.(*model.CallSession); isCall {
+		payload = copyCallSession(snapshot)
+	}
+	m.hub.Publish(UserStream(userID), eventType, call.ConversationID, payload)
+}
+
+func callResult(call *model.CallSession) model.CallResult {
+	if call.AnsweredAt != nil {
+		return model.CallResultCompleted
+	}
+
+	result := model.CallResultCancelled
+	for participantID, state := range call.Participants {
+		if participantID == call.InitiatorID {
+			continue
+		}
+		switch state {
+		case model.CallParticipantStateDeclined:
+			return model.CallResultDeclined
+		case model.CallParticipantStateBusy:
+			result = model.CallResultBusy
+		case model.CallParticipantStateMissed:
+			if result != model.CallResultBusy {
+				result = model.CallResultMissed
+			}
+		}
+	}
+	return result
+}
+
+// copyCallSession snapshots the call so published events are not changed by
+// later state transitions.
+func copyCallSession(call *model.CallSession) *model.CallSession {
+	snapshot := *call
+	snapshot.Participants = make(map[string]model.CallParticipantState, len(call.Participants))
+	for participantID, state := range call.Participants {
+		snapshot.Participants[participantID] = state
+	}
+	return &snapshot
+}
@@ -0,0 +1,282 @@
+package controllers
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/badaccuracyid/softeng_backend/src/model"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+const testConversationID = "conversation"
+
+// callTest drives a CallManager on its own hub and collects the calls it
+// reports as ended.
+type callTest struct {
+	t       *testing.T
+	hub     *EventHub
+	manager *CallManager
+	ended   chan *model.CallSession
+}
+
+func newCallTest(t *testing.T, ringTimeout time.Duration) *callTest {
+	test := &callTest{
+		t:     t,
+		hub:   NewEventHub(),
+		ended: make(chan *model.CallSession, 10),
+	}
+	test.manager = NewCallManager(test.hub, ringTimeout, func(call *model.CallSession) {
+		test.ended <- call
+	})
+	return test
+}
+
+// subscribe returns the events published to the user from now on.
+func (c *callTest) subscribe(userID string) chan *model.Event {
+	subscription, _ := c.hub.Subscribe(UserStream(userID), userID, 0)
+	c.t.Cleanup(func() { c.hub.Unsubscribe(subscription) })
+	return subscription.EventChannel
+}
+
+func (c *callTest) start(userID string, memberIDs ...string) *model.CallSession {
+	c.t.Helper()
+	call, err := c.manager.Start(userID, testConversationID, memberIDs, model.CallMediaAudio)
+	if err != nil {
+		c.t.Fatalf("Start: %v", err)
+	}
+	return call
+}
+
+func (c *callTest) waitEnded() *model.CallSession {
+	c.t.Helper()
+	select {
+	case call := <-c.ended:
+		return call
+	case <-time.After(time.Second):
+		c.t.Fatal("call did not end")
+		return nil
+	}
+}
+
+func (c *callTest) expectNotEnded() {
+	c.t.Helper()
+	select {
+	case call := <-c.ended:
+		c.t.Fatalf("call ended with %s", call.Result)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func nextEvent(t *testing.T, events chan *model.Event, eventType model.EventType) *model.Event {
+	t.Helper()
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case event := <-events:
+			if event.Type == eventType {
+				return event
+			}
+		case <-timeout:
+			t.Fatalf("no %s event", eventType)
+			return nil
+		}
+	}
+}
+
+func expectParticipant(t *testing.T, call *model.CallSession, userID string, state model.CallParticipantState) {
+	t.Helper()
+	if got := call.Participants[userID]; got != state {
+		t.Errorf("participant %s is %s, want %s", userID, got, state)
+	}
+}
+
+func TestCallRingAndAccept(t *testing.T) {
+	test := newCallTest(t, time.Minute)
+	bobEvents := test.subscribe("bob")
+
+	call := test.start("alice", "alice", "bob", "carol")
+	if call.State != model.CallStateRinging {
+		t.Errorf("state = %s, want RINGING", call.State)
+	}
+	expectParticipant(t, call, "alice", model.CallParticipantStateJoined)
+	expectParticipant(t, call, "bob", model.CallParticipantStateRinging)
+	expectParticipant(t, call, "carol", model.CallParticipantStateRinging)
+
+	ringing := nextEvent(t, bobEvents, model.EventTypeCallRinging)
+	if ringing.Payload.(*model.CallSession).ID != call.ID {
+		t.Errorf("bob was rung for another call")
+	}
+
+	call, err := test.manager.Accept("bob", call.ID)
+	if err != nil {
+		t.Fatalf("Accept: %v", err)
+	}
+	if call.State != model.CallStateActive || call.AnsweredAt == nil {
+		t.Errorf("state = %s, answered at %v, want an answered ACTIVE call", call.State, call.AnsweredAt)
+	}
+	expectParticipant(t, call, "bob", model.CallParticipantStateJoined)
+	expectParticipant(t, call, "carol", model.CallParticipantStateRinging)
+
+	// starting a call where one is going on joins it
+	call = test.start("carol", "alice", "bob", "carol")
+	expectParticipant(t, call, "carol", model.CallParticipantStateJoined)
+	test.expectNotEnded()
+}
+
+func TestCallDecline(t *testing.T) {
+	test := newCallTest(t, time.Minute)
+	aliceEvents := test.subscribe("alice")
+
+	call := test.start("alice", "alice", "bob")
+	if _, err := test.manager.Decline("bob", call.ID); err != nil {
+		t.Fatalf("Decline: %v", err)
+	}
+
+	ended := test.waitEnded()
+	if ended.Result != model.CallResultDeclined {
+		t.Errorf("result = %s, want DECLINED", ended.Result)
+	}
+	expectParticipant(t, ended, "bob", model.CallParticipantStateDeclined)
+	nextEvent(t, aliceEvents, model.EventTypeCallEnded)
+
+	if _, err := test.manager.GetCall(call.ID); err == nil {
+		t.Error("the ended call is still there")
+	}
+	// alice is free again
+	test.start("alice", "alice", "bob")
+}
+
+func TestCallBusy(t *testing.T) {
+	test := newCallTest(t, time.Minute)
+
+	first, err := test.manager.Start("bob", "other", []string{"bob", "carol"}, model.CallMediaVideo)
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	call := test.start("alice", "alice", "bob")
+	expectParticipant(t, call, "bob", model.CallParticipantStateBusy)
+
+	ended := test.waitEnded()
+	if ended.ID != call.ID || ended.Result != model.CallResultBusy {
+		t.Errorf("ended %s with %s, want %s with BUSY", ended.ID, ended.Result, call.ID)
+	}
+
+	if _, err := test.manager.GetCall(first.ID); err != nil {
+		t.Errorf("bob's call is gone: %v", err)
+	}
+	// bob cannot start another call while in one
+	if _, err := test.manager.Start("bob", testConversationID, []string{"alice", "bob"}, model.CallMediaAudio); err == nil {
+		t.Error("bob started a second call")
+	}
+}
+
+func TestCallRingTimeout(t *testing.T) {
+	test := newCallTest(t, 20*time.Millisecond)
+	bobEvents := test.subscribe("bob")
+
+	test.start("alice", "alice", "bob")
+
+	ended := test.waitEnded()
+	if ended.Result != model.CallResultMissed {
+		t.Errorf("result = %s, want MISSED", ended.Result)
+	}
+	expectParticipant(t, ended, "bob", model.CallParticipantStateMissed)
+	nextEvent(t, bobEvents, model.EventTypeCallEnded)
+}
+
+func TestCallRingTimeoutAfterAnswer(t *testing.T) {
+	test := newCallTest(t, 20*time.Millisecond)
+
+	call := test.start("alice", "alice", "bob", "carol")
+	if _, err := test.manager.Accept("bob", call.ID); err != nil {
+		t.Fatalf("Accept: %v", err)
+	}
+
+	// carol missing the call leaves alice and bob talking
+	test.expectNotEnded()
+	call, err := test.manager.GetCall(call.ID)
+	if err != nil {
+		t.Fatalf("GetCall: %v", err)
+	}
+	expectParticipant(t, call, "carol", model.CallParticipantStateMissed)
+}
+
+func TestCallHangUp(t *testing.T) {
+	test := newCallTest(t, time.Minute)
+
+	call := test.start("alice", "alice", "bob")
+	if _, err := test.manager.Accept("bob", call.ID); err != nil {
+		t.Fatalf("Accept: %v", err)
+	}
+	if _, err := test.manager.HangUp("bob", call.ID); err != nil {
+		t.Fatalf("HangUp: %v", err)
+	}
+
+	ended := test.waitEnded()
+	if ended.Result != model.CallResultCompleted || ended.EndedAt == nil {
+		t.Errorf("result = %s, ended at %v, want COMPLETED", ended.Result, ended.EndedAt)
+	}
+	expectParticipant(t, ended, "alice", model.CallParticipantStateLeft)
+	expectParticipant(t, ended, "bob", model.CallParticipantStateLeft)
+}
+
+func TestCallCancelledByCaller(t *testing.T) {
+	test := newCallTest(t, time.Minute)
+
+	call := test.start("alice", "alice", "bob")
+	if _, err := test.manager.HangUp("alice", call.ID); err != nil {
+		t.Fatalf("HangUp: %v", err)
+	}
+
+	ended := test.waitEnded()
+	expectParticipant(t, ended, "bob", model.CallParticipantStateMissed)
+	if ended.Result != model.CallResultMissed {
+		t.Errorf("result = %s, want MISSED", ended.Result)
+	}
+}
+
+func TestCallHistoryEntry(t *testing.T) {
+	// statements are only generated, the entry is checked on the event the
+	// conversation receives
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost", PreferSimpleProtocol: true}),
+		&gorm.Config{DryRun: true, DisableAutomaticPing: true, SkipDefaultTransaction: true})
+	if err != nil {
+		t.Fatalf("gorm.Open: %v", err)
+	}
+
+	subscription, _ := eventHub.Subscribe(ConversationStream(testConversationID), "test", 0)
+	defer eventHub.Unsubscribe(subscription)
+
+	manager := NewCallManager(NewEventHub(), time.Minute, newChatController(db).recordCallHistory)
+	call, err := manager.Start("alice", testConversationID, []string{"alice", "bob"}, model.CallMediaVideo)
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if _, err := manager.Accept("bob", call.ID); err != nil {
+		t.Fatalf("Accept: %v", err)
+	}
+	if _, err := manager.HangUp("alice", call.ID); err != nil {
+		t.Fatalf("HangUp: %v", err)
+	}
+
+	event := nextEvent(t, subscription.EventChannel, model.EventTypeMessageCreated)
+	message := event.Payload.(*model.Message)
+	if message.ContentType != model.MessageContentTypeSystem || message.SenderID != "alice" {
+		t.Fatalf("message is %s from %s, want SYSTEM from alice", message.ContentType, message.SenderID)
+	}
+
+	content := &model.SystemMessageContent{}
+	if err := json.Unmarshal([]byte(message.Content), content); err != nil {
+		t.Fatalf("content: %v", err)
+	}
+	if content.Type != model.SystemMessageTypeCall || content.Call == nil {
+		t.Fatalf("content = %+v, want a CALL entry", content)
+	}
+	if content.Call.CallID != call.ID || content.Call.Result != model.CallResultCompleted ||
+		content.Call.Media != model.CallMediaVideo || len(content.Call.ParticipantIDs) != 2 {
+		t.Errorf("call summary = %+v", content.Call)
+	}
+}
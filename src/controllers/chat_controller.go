@@ -1,41 +1,96 @@
 package controllers
 
 import (
+	"encoding/json"
 	"errors"
-	"sync"
+	"fmt"
+	"html"
+	"log"
+	"strings"
+	"time"
 
 	"github.com/badaccuracyid/softeng_backend/src/database/dao"
+	"github.com/badaccuracyid/softeng_backend/src/markdown"
 	"github.com/badaccuracyid/softeng_backend/src/model"
+	"github.com/badaccuracyid/softeng_backend/src/storage"
 	"github.com/badaccuracyid/softeng_backend/src/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
+const (
+	maxConversationTitleLength       = 128
+	maxConversationDescriptionLength = 1024
+	maxConversationTopicLength       = 256
+	maxConversationNicknameLength    = 64
+	defaultMaxPinnedMessages         = 50
+	maxReactionEmojiLength           = 32
+	maxMessageAttachments            = 10
+	maxForwardedMessages             = 100
+	maxForwardTargets                = 10
+	memberFanOutBatchSize            = 1000
+	maxClientMessageIDLength         = 64
+	defaultIdempotencyRetention      = 24 * time.Hour
+)
+
 type ChatController interface {
 	SetContext(ctx *gin.Context)
 
 	CreateConversation(input model.CreateConversationInput) (*model.Conversation, error)
+	GetOrCreateDirectConversation(userID string) (*model.Conversation, bool, error)
 	GetConversation(id string) (*model.Conversation, error)
-	GetConversationsForUser() ([]*model.Conversation, error)
+	UpdateConversation(id string, input model.UpdateConversationInput) (*model.Conversation, error)
+	SetMessageTimer(id string, input model.SetMessageTimerInput) (*model.Conversation, error)
+	GetConversationsForUser(filter model.ConversationFilter) ([]*model.Conversation, error)
+	UpdateConversationSettings(id string, input model.UpdateConversationSettingsInput) (*model.ConversationMember, error)
+	MarkConversationRead(id string) (*model.ConversationMember, error)
 	DeleteConversation(id string) error
 
 	SendMessage(input model.SendMessageInput) (*model.Message, error)
+	ForwardMessages(input model.ForwardMessagesInput) ([]*model.Message, error)
+
+	PinMessage(messageID string) (*model.PinnedMessage, error)
+	UnpinMessage(messageID string) error
+	GetPinnedMessages(conversationID string) ([]*model.PinnedMessage, error)
+
+	React(messageID string, input model.ReactInput) ([]*model.ReactionCount, error)
+	Unreact(messageID string, input model.ReactInput) ([]*model.ReactionCount, error)
+	GetReactions(messageID string) ([]*model.ReactionCount, error)
+	ViewMessages(conversationID string, input model.ViewMessagesInput) error
+	GetRenderedMessage(messageID string) (*model.RenderedMessage, error)
 
 	AddUserToConversation(conversationID string, userID string) (*model.Conversation, error)
 	RemoveUserFromConversation(conversationID string, userID string) (*model.Conversation, error)
 
-	NewMessageSubscription(conversationID string) (<-chan *model.Message, chan<- struct{}, error)
+	AuthorizeEventStream(conversationID string) (string, error)
+	SubscribeEvents(stream string, lastEventID int64) (*model.EventSubscription, []*model.Event)
+	UnsubscribeEvents(subscription *model.EventSubscription)
+	PollEvents(stream string, lastEventID int64, timeout time.Duration) *model.EventPollResult
+	SendTypingSignal(userID string, conversationID string, eventType model.EventType) error
+	HandleClientEvent(userID string, event model.ClientEvent) error
+
+	HandleCallEvent(userID string, event model.ClientEvent) (*model.CallSession, error)
+	GetCall(callID string) (*model.CallSession, error)
+	LeaveCallsOnDisconnect(userID string)
 }
 
 type chatController struct {
-	ctx     *gin.Context
-	chatDAO *dao.ChatDAO
+	ctx                  *gin.Context
+	chatDAO              *dao.ChatDAO
+	maxPinnedMessages    int
+	idempotencyRetention time.Duration
 }
 
 func NewChatController(db *gorm.DB) ChatController {
+	return newChatController(db)
+}
+
+func newChatController(db *gorm.DB) *chatController {
 	return &chatController{
-		chatDAO: dao.NewChatDAO(db),
+		chatDAO:              dao.NewChatDAO(db),
+		maxPinnedMessages:    utils.GetEnvInt("MAX_PINNED_MESSAGES", defaultMaxPinnedMessages),
+		idempotencyRetention: utils.GetEnvDuration("IDEMPOTENCY_KEY_RETENTION", defaultIdempotencyRetention),
 	}
 }
 
@@ -67,140 +122,1327 @@ func (s *chatController) CreateConversation(input model.CreateConversationInput)
 		participantUser = append(participantUser, user)
 	}
 
+	kind := input.Kind
+	if kind == "" {
+		kind = model.ConversationKindGroup
+	}
+	if kind != model.ConversationKindGroup && kind != model.ConversationKindChannel {
+		return nil, utils.ErrInvalidInput
+	}
+
+	// a group of two is the direct conversation, which must stay the only one
+	// between them
+	if kind == model.ConversationKindGroup && len(participantUser) == 2 {
+		otherID := participantUser[0].ID
+		if otherID == userId {
+			otherID = participantUser[1].ID
+		}
+		conversation, _, err := s.GetOrCreateDirectConversation(otherID)
+		return conversation, err
+	}
+
+	if err := requireDirectMessagesAllowed(s.chatDAO.DB, userId, participantUser); err != nil {
+		return nil, err
+	}
+
 	conversation := &model.Conversation{
 		ID:      uuid.New().String(),
 		Title:   input.Title,
+		Kind:    kind,
 		Members: participantUser,
 	}
 
-	if err := s.chatDAO.CreateConversation(conversation); err != nil {
+	// a conversation is never left without its admin
+	err = s.chatDAO.DB.Transaction(func(tx *gorm.DB) error {
+		chatDAO := dao.NewChatDAO(tx)
+		if err := chatDAO.CreateConversation(conversation); err != nil {
+			return err
+		}
+		return chatDAO.SetMemberRole(conversation.ID, userId, model.MemberRoleAdmin)
+	})
+	if err != nil {
 		return nil, err
 	}
 
+	hideEmails(conversation.Members)
+	s.publishConversationEvent(conversation.ID, model.EventTypeConversationCreated, conversation)
 	return conversation, nil
 }
 
+// GetOrCreateDirectConversation returns the DM between the current user and
+// the given user, creating it the first time. The boolean reports whether it
+// was created by this call.
+func (s *chatController) GetOrCreateDirectConversation(userID string) (*model.Conversation, bool, error) {
+	currentUserId := utils.GetCurrentUserID(s.ctx)
+	if currentUserId == "" {
+		return nil, false, utils.ErrUnauthenticated
+	}
+	if userID == "" || userID == currentUserId {
+		return nil, false, utils.ErrInvalidInput
+	}
+
+	userController := NewUserService(s.chatDAO.DB)
+	userController.SetContext(s.ctx)
+
+	members, err := userController.GetUsersByID([]string{currentUserId, userID})
+	if err != nil {
+		return nil, false, err
+	}
+	if len(members) != 2 {
+		return nil, false, utils.ErrNotFound
+	}
+
+	directKey := directConversationKey(currentUserId, userID)
+	if err := requireDirectMessagesAllowed(s.chatDAO.DB, currentUserId, members); err != nil {
+		// a conversation started before the setting changed stays usable
+		existing, lookupErr := s.chatDAO.GetDirectConversation(directKey)
+		if lookupErr != nil {
+			return nil, false, err
+		}
+		return existing, false, nil
+	}
+
+	conversation, created, err := s.chatDAO.GetOrCreateDirectConversation(&model.Conversation{
+		ID:        uuid.New().String(),
+		Kind:      model.ConversationKindDirect,
+		DirectKey: &directKey,
+		Members:   members,
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	if created {
+		hideEmails(conversation.Members)
+		s.publishConversationEvent(conversation.ID, model.EventTypeConversationCreated, conversation)
+	}
+	return conversation, created, nil
+}
+
+// GetConversation returns the conversation with its history to its members.
 func (s *chatController) GetConversation(id string) (*model.Conversation, error) {
+	userId := utils.GetCurrentUserID(s.ctx)
+	if userId == "" {
+		return nil, utils.ErrUnauthenticated
+	}
+
+	if _, err := s.chatDAO.GetConversationSummary(id); err != nil {
+		return nil, err
+	}
+	isMember, err := s.chatDAO.IsConversationMember(id, userId)
+	if err != nil {
+		return nil, err
+	}
+	if !isMember {
+		return nil, utils.ErrForbidden
+	}
+
 	return s.chatDAO.GetConversationByID(id)
 }
 
-func (s *chatController) GetConversationsForUser() ([]*model.Conversation, error) {
+// UpdateConversation changes the metadata of a group, only the fields present
+// in the input are touched. Admins only.
+func (s *chatController) UpdateConversation(id string, input model.UpdateConversationInput) (*model.Conversation, error) {
+	userId := utils.GetCurrentUserID(s.ctx)
+	if err := requireConversationAdmin(s.chatDAO, id, userId); err != nil {
+		return nil, err
+	}
+
+	conversation, err := s.chatDAO.GetConversationByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if conversation.Kind == model.ConversationKindDirect {
+		return nil, utils.ErrForbidden
+	}
+
+	columns := map[string]interface{}{}
+	changes := map[string]string{}
+
+	if input.Title != nil && *input.Title != conversation.Title {
+		title := strings.TrimSpace(*input.Title)
+		if title == "" || len(title) > maxConversationTitleLength {
+			return nil, utils.ErrInvalidInput
+		}
+		columns["title"] = title
+		changes["title"] = title
+	}
+	if input.Description != nil && *input.Description != conversation.Description {
+		if len(*input.Description) > maxConversationDescriptionLength {
+			return nil, utils.ErrInvalidInput
+		}
+		columns["description"] = *input.Description
+		changes["description"] = *input.Description
+	}
+	if input.Topic != nil && *input.Topic != conversation.Topic {
+		if len(*input.Topic) > maxConversationTopicLength {
+			return nil, utils.ErrInvalidInput
+		}
+		columns["topic"] = *input.Topic
+		changes["topic"] = *input.Topic
+	}
+	currentAvatarID := ""
+	if conversation.AvatarID != nil {
+		currentAvatarID = *conversation.AvatarID
+	}
+	if input.AvatarID != nil && *input.AvatarID != currentAvatarID {
+		avatarID, err := s.validateAvatar(*input.AvatarID)
+		if err != nil {
+			return nil, err
+		}
+		columns["avatar_id"] = avatarID
+		changes["avatarId"] = *input.AvatarID
+	}
+
+	if len(columns) == 0 {
+		return conversation, nil
+	}
+
+	if err := s.chatDAO.UpdateConversationColumns(id, columns); err != nil {
+		return nil, err
+	}
+
+	conversation, err = s.chatDAO.GetConversationByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.publishConversationEvent(id, model.EventTypeMetadataChanged, conversation)
+	_, _ = s.createSystemMessage(id, &model.SystemMessageContent{
+		Type:    model.SystemMessageTypeMetadataChanged,
+		ActorID: userId,
+		Changes: changes,
+	})
+
+	return conversation, nil
+}
+
+// validateAvatar checks the attachment is an image the current user uploaded
+// and returns the value to store, nil when the avatar is removed.
+func (s *chatController) validateAvatar(avatarID string) (*string, error) {
+	if avatarID == "" {
+		return nil, nil
+	}
+
+	attachment, err := dao.NewAttachmentDAO(s.chatDAO.DB).GetAttachmentByID(avatarID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, utils.ErrInvalidInput
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if attachment.UploaderID != utils.GetCurrentUserID(s.ctx) {
+		return nil, utils.ErrForbidden
+	}
+	if !attachment.IsImage() {
+		return nil, utils.ErrInvalidInput
+	}
+	return &attachment.ID, nil
+}
+
+func (s *chatController) GetConversationsForUser(filter model.ConversationFilter) ([]*model.Conversation, error) {
+	userId := utils.GetCurrentUserID(s.ctx)
+	conversations, err := s.chatDAO.GetConversationsForUser(userId, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	conversationIDs := make([]string, len(conversations))
+	for i, conversation := range conversations {
+		conversationIDs[i] = conversation.ID
+	}
+
+	memberships, err := s.chatDAO.GetMembershipsForUser(userId, conversationIDs)
+	if err != nil {
+		return nil, err
+	}
+	unreadCounts, err := s.chatDAO.GetUnreadCounts(userId, conversationIDs)
+	if err != nil {
+		return nil, err
+	}
+	drafts, err := dao.NewDraftDAO(s.chatDAO.DB).GetDrafts(userId, conversationIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, conversation := range conversations {
+		conversation.Settings = memberships[conversation.ID]
+		conversation.UnreadCount = unreadCounts[conversation.ID]
+		conversation.Draft = drafts[conversation.ID]
+	}
+
+	return conversations, nil
+}
+
+// UpdateConversationSettings changes the private settings the current user
+// keeps on a conversation. Only the user's own devices are told about it.
+func (s *chatController) UpdateConversationSettings(id string, input model.UpdateConversationSettingsInput) (*model.ConversationMember, error) {
+	userId := utils.GetCurrentUserID(s.ctx)
+	if userId == "" {
+		return nil, utils.ErrUnauthenticated
+	}
+
+	member, err := s.chatDAO.GetMember(id, userId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, utils.ErrForbidden
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	columns := map[string]interface{}{}
+
+	if input.Muted != nil {
+		switch {
+		case !*input.Muted:
+			member.MutedForever = false
+			member.MutedUntil = nil
+		case input.MutedUntil == nil:
+			member.MutedForever = true
+			member.MutedUntil = nil
+		case input.MutedUntil.After(now):
+			member.MutedForever = false
+			member.MutedUntil = input.MutedUntil
+		default:
+			return nil, utils.ErrInvalidInput
+		}
+		columns["muted_forever"] = member.MutedForever
+		columns["muted_until"] = member.MutedUntil
+	} else if input.MutedUntil != nil {
+		return nil, utils.ErrInvalidInput
+	}
+
+	if input.Pinned != nil {
+		member.PinnedAt = nil
+		if *input.Pinned {
+			member.PinnedAt = &now
+		}
+		columns["pinned_at"] = member.PinnedAt
+	}
+
+	if input.Archived != nil {
+		member.ArchivedAt = nil
+		if *input.Archived {
+			member.ArchivedAt = &now
+		}
+		columns["archived_at"] = member.ArchivedAt
+	}
+
+	if input.Nickname != nil {
+		nickname := strings.TrimSpace(*input.Nickname)
+		if len(nickname) > maxConversationNicknameLength {
+			return nil, utils.ErrInvalidInput
+		}
+		member.Nickname = nickname
+		columns["nickname"] = nickname
+	}
+
+	if len(columns) == 0 {
+		return member, nil
+	}
+
+	if err := s.chatDAO.UpdateMemberColumns(id, userId, columns); err != nil {
+		return nil, err
+	}
+
+	eventHub.Publish(UserStream(userId), model.EventTypeSettingsChanged, id, member)
+	return member, nil
+}
+
+// MarkConversationRead moves the current user's read marker to now, the
+// user's other devices get the new marker.
+func (s *chatController) MarkConversationRead(id string) (*model.ConversationMember, error) {
 	userId := utils.GetCurrentUserID(s.ctx)
-	return s.chatDAO.GetConversationsForUser(userId)
+	if userId == "" {
+		return nil, utils.ErrUnauthenticated
+	}
+
+	member, err := s.chatDAO.GetMember(id, userId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, utils.ErrForbidden
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	member.LastReadAt = &now
+	if err := s.chatDAO.UpdateMemberColumns(id, userId, map[string]interface{}{"last_read_at": now}); err != nil {
+		return nil, err
+	}
+
+	eventHub.Publish(UserStream(userId), model.EventTypeSettingsChanged, id, member)
+	return member, nil
 }
 
 func (s *chatController) DeleteConversation(id string) error {
 	return s.chatDAO.DeleteConversation(id)
 }
 
+// SendMessage sends a message as the current user.
 func (s *chatController) SendMessage(input model.SendMessageInput) (*model.Message, error) {
+	userId := utils.GetCurrentUserID(s.ctx)
+	if userId == "" {
+		return nil, utils.ErrUnauthenticated
+	}
+	if input.SenderID != "" && input.SenderID != userId {
+		return nil, fmt.Errorf("%w: messages can only be sent as the current user", utils.ErrForbidden)
+	}
+
+	input.SenderID = userId
+	message, stored, err := s.storeMessageAs(input)
+	if err != nil {
+		return nil, err
+	}
+
+	// a replay was already pushed the first time
+	if stored {
+		s.publishMessage(message)
+		s.clearDraft(input.SenderID, message)
+	}
+	return message, nil
+}
+
+// storeMessageAs stores a message as input.SenderID, whoever the current user
+// is, without pushing it. It is for messages the server sends on a user's
+// behalf, which are pushed once their own transaction committed. The boolean is
+// false when the message was stored by an earlier request with the same
+// ClientMessageID, which is returned instead.
+func (s *chatController) storeMessageAs(input model.SendMessageInput) (*model.Message, bool, error) {
+	if !input.ContentType.IsValid() || input.ContentType == model.MessageContentTypeSystem {
+		return nil, false, utils.ErrInvalidInput
+	}
+	if input.Format == "" {
+		input.Format = model.MessageFormatPlain
+	}
+	formatted, err := formatContent(input.ContentType, input.Format, input.Content)
+	if err != nil {
+		return nil, false, err
+	}
+
+	conversation, err := s.postableConversation(input.ConversationID, input.SenderID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	attachments, err := s.senderAttachments(input.SenderID, input.AttachmentIDs)
+	if err != nil {
+		return nil, false, err
+	}
+
 	message := &model.Message{
 		ID:             uuid.New().String(),
 		ConversationID: input.ConversationID,
 		SenderID:       input.SenderID,
 		ContentType:    input.ContentType,
 		Content:        input.Content,
+		Format:         input.Format,
+		Formatted:      formatted,
+		Attachments:    attachments,
+	}
+	if input.ContentType == model.MessageContentTypePoll {
+		message.Poll, err = newPoll(input.Poll)
+		if err != nil {
+			return nil, false, err
+		}
+		message.Content = message.Poll.Question
+	}
+	if input.ClientMessageID == "" {
+		if err := storeMessage(s.chatDAO, conversation, message); err != nil {
+			return nil, false, err
+		}
+		return message, true, nil
+	}
+	if len(input.ClientMessageID) > maxClientMessageIDLength {
+		return nil, false, utils.ErrInvalidInput
 	}
+	message.ClientMessageID = &input.ClientMessageID
 
-	if err := s.chatDAO.CreateMessage(message); err != nil {
+	// the key and the message are stored together, a retry either finds
+	// both or neither
+	var original *model.Message
+	err = s.chatDAO.DB.Transaction(func(tx *gorm.DB) error {
+		chatDAO := dao.NewChatDAO(tx)
+		claimed, err := chatDAO.ClaimClientMessageID(&model.MessageIdempotencyKey{
+			SenderID:        input.SenderID,
+			ClientMessageID: input.ClientMessageID,
+			MessageID:       message.ID,
+		}, time.Now().Add(-s.idempotencyRetention))
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			original, err = chatDAO.GetMessageByClientID(input.SenderID, input.ClientMessageID)
+			return err
+		}
+
+		return storeMessage(chatDAO, conversation, message)
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	if original != nil {
+		return original, false, nil
+	}
+	return message, true, nil
+}
+
+// GetRenderedMessage turns a message the current user can read into plain
+// text and HTML, formatted or not.
+func (s *chatController) GetRenderedMessage(messageID string) (*model.RenderedMessage, error) {
+	message, err := s.memberMessage(messageID, utils.GetCurrentUserID(s.ctx))
+	if err != nil {
 		return nil, err
 	}
 
-	triggerSubscription(input.ConversationID, message)
-	return message, nil
+	rendered := &model.RenderedMessage{MessageID: message.ID}
+	if message.Formatted != nil {
+		rendered.PlainText = markdown.RenderPlainText(message.Formatted)
+		rendered.HTML = markdown.RenderHTML(message.Formatted)
+		return rendered, nil
+	}
+
+	rendered.PlainText = message.Content
+	rendered.HTML = strings.ReplaceAll(html.EscapeString(message.Content), "\n", "<br>")
+	return rendered, nil
 }
 
-func (s *chatController) AddUserToConversation(conversationID string, userID string) (*model.Conversation, error) {
-	userService := NewUserService(s.chatDAO.DB)
-	userService.SetContext(s.ctx)
+// clearDraft empties the sender's draft of the conversation once they sent a
+// message there themselves, unless another device saved a newer draft since.
+// Messages sent for the user, like scheduled ones, leave the draft alone.
+func (s *chatController) clearDraft(userID string, message *model.Message) {
+	if utils.GetCurrentUserID(s.ctx) != userID {
+		return
+	}
 
-	user, err := userService.GetUserByID(userID)
+	draftDAO := dao.NewDraftDAO(s.chatDAO.DB)
+	cleared, err := draftDAO.ClearDraft(userID, message.ConversationID, message.CreatedAt)
 	if err != nil {
-		return nil, err
+		log.Printf("clear draft of %s: %v", message.ConversationID, err)
+		return
+	}
+	if !cleared {
+		return
+	}
+
+	eventHub.Publish(UserStream(userID), model.EventTypeDraftUpdated, message.ConversationID, &model.MessageDraft{
+		UserID:         userID,
+		ConversationID: message.ConversationID,
+		Attachments:    []*model.Attachment{},
+		UpdatedAt:      message.CreatedAt,
+	})
+}
+
+// ForwardMessages copies messages the current user can read into
+// conversations they can post in. Attachments are shared with the original
+// rather than copied.
+func (s *chatController) ForwardMessages(input model.ForwardMessagesInput) ([]*model.Message, error) {
+	userId := utils.GetCurrentUserID(s.ctx)
+	if userId == "" {
+		return nil, utils.ErrUnauthenticated
+	}
+
+	messageIDs := uniqueStrings(input.MessageIDs)
+	targetIDs := uniqueStrings(input.TargetConversationIDs)
+	if len(messageIDs) == 0 || len(messageIDs) > maxForwardedMessages ||
+		len(targetIDs) == 0 || len(targetIDs) > maxForwardTargets {
+		return nil, utils.ErrInvalidInput
 	}
 
-	conversation, err := s.GetConversation(conversationID)
+	originals, err := s.chatDAO.GetMessagesByID(messageIDs)
 	if err != nil {
 		return nil, err
 	}
+	if len(originals) != len(messageIDs) {
+		return nil, utils.ErrNotFound
+	}
 
-	if membersContainsUser(conversation.Members, user) {
-		return conversation, nil
+	readable := make(map[string]bool)
+	now := time.Now()
+	for _, original := range originals {
+		// a poll is voted on where it was posted
+		if original.ContentType == model.MessageContentTypeSystem || original.ContentType == model.MessageContentTypePoll {
+			return nil, utils.ErrInvalidInput
+		}
+		if original.ExpiresAt != nil && !original.ExpiresAt.After(now) {
+			return nil, utils.ErrNotFound
+		}
+		if _, checked := readable[original.ConversationID]; checked {
+			continue
+		}
+
+		isMember, err := s.chatDAO.IsConversationMember(original.ConversationID, userId)
+		if err != nil {
+			return nil, err
+		}
+		if !isMember {
+			return nil, utils.ErrForbidden
+		}
+		readable[original.ConversationID] = true
+	}
+
+	// every target is checked before anything is sent
+	targets := make([]*model.Conversation, len(targetIDs))
+	for i, targetID := range targetIDs {
+		targets[i], err = s.postableConversation(targetID, userId)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	conversation.Members = append(conversation.Members, user)
+	forwarded := []*model.Message{}
+	for _, target := range targets {
+		for _, original := range originals {
+			message := &model.Message{
+				ID:             uuid.New().String(),
+				ConversationID: target.ID,
+				SenderID:       userId,
+				ContentType:    original.ContentType,
+				Content:        original.Content,
+				Format:         original.Format,
+				Formatted:      original.Formatted,
+				Attachments:    original.Attachments,
+			}
+			forwardOrigin(message, original, userId)
 
-	if err := s.chatDAO.DB.Association("Members").Append(user); err != nil {
-		return nil, err
+			message, err := s.deliverMessage(target, message)
+			if err != nil {
+				return nil, err
+			}
+			forwarded = append(forwarded, message)
+		}
 	}
 
-	return conversation, nil
+	return forwarded, nil
 }
 
-func (s *chatController) RemoveUserFromConversation(conversationID string, userID string) (*model.Conversation, error) {
-	userService := NewUserService(s.chatDAO.DB)
-	userService.SetContext(s.ctx)
+// forwardOrigin points the forwarded message at the origin of the original,
+// which is the original itself unless it was a forward too.
+func forwardOrigin(message *model.Message, original *model.Message, forwarderID string) {
+	if original.ForwardedFromMessageID != nil {
+		message.ForwardedFromMessageID = original.ForwardedFromMessageID
+		message.ForwardedFromSenderID = original.ForwardedFromSenderID
+		message.ForwardedFromName = original.ForwardedFromName
+		return
+	}
 
-	user, err := userService.GetUserByID(userID)
+	message.ForwardedFromMessageID = &original.ID
+	if !original.Sender.HideForwardSender || original.SenderID == forwarderID {
+		message.ForwardedFromSenderID = &original.SenderID
+		message.ForwardedFromName = original.Sender.DisplayName
+	}
+}
+
+// postableConversation loads the conversation if the user can post in it:
+// they are a member, and an admin when it is a channel.
+func (s *chatController) postableConversation(conversationID string, userID string) (*model.Conversation, error) {
+	conversation, err := s.chatDAO.GetConversationSummary(conversationID)
 	if err != nil {
 		return nil, err
 	}
 
-	conversation, err := s.GetConversation(conversationID)
+	isMember, err := s.chatDAO.IsConversationMember(conversation.ID, userID)
 	if err != nil {
 		return nil, err
 	}
-
-	if !membersContainsUser(conversation.Members, user) {
-		return conversation, nil
+	if !isMember {
+		return nil, utils.ErrForbidden
 	}
-
-	if err := s.chatDAO.DB.Association("Members").Delete(user); err != nil {
-		return nil, err
+	// everyone reads a channel, only its admins post
+	if conversation.Kind == model.ConversationKindChannel {
+		if err := requireConversationAdmin(s.chatDAO, conversation.ID, userID); err != nil {
+			return nil, err
+		}
 	}
 
 	return conversation, nil
 }
 
-var (
-	subscriptions      = make(map[string][]*model.MessageSubscription)
-	subscriptionsMutex sync.Mutex
-)
-
-func (s *chatController) NewMessageSubscription(conversationID string) (<-chan *model.Message, chan<- struct{}, error) {
-	subscription := &model.MessageSubscription{
-		MessageChannel: make(chan *model.Message),
-		DoneChannel:    make(chan struct{}),
+// deliverMessage stores a message for the conversation and pushes it.
+func (s *chatController) deliverMessage(conversation *model.Conversation, message *model.Message) (*model.Message, error) {
+	if err := storeMessage(s.chatDAO, conversation, message); err != nil {
+		return nil, err
 	}
 
-	onSubscribe(conversationID, subscription)
-	return subscription.MessageChannel, subscription.DoneChannel, nil
+	s.publishMessage(message)
+	return message, nil
 }
 
-func onSubscribe(conversationId string, subscription *model.MessageSubscription) {
-	subscriptionsMutex.Lock()
-	defer subscriptionsMutex.Unlock()
-	subscriptions[conversationId] = append(subscriptions[conversationId], subscription)
+// storeMessage saves the message, starting its disappearing timer if the
+// conversation has one.
+func storeMessage(chatDAO *dao.ChatDAO, conversation *model.Conversation, message *model.Message) error {
+	if conversation.MessageTTL > 0 {
+		expiresAt := time.Now().Add(time.Duration(conversation.MessageTTL) * time.Second)
+		message.ExpiresAt = &expiresAt
+	}
+
+	return chatDAO.CreateMessage(message)
 }
 
-func triggerSubscription(conversationId string, message *model.Message) {
-	subscriptionsMutex.Lock()
-	defer subscriptionsMutex.Unlock()
+func (s *chatController) publishMessage(message *model.Message) {
+	typingTracker.Stop(message.ConversationID, message.SenderID)
+	s.publishConversationEvent(message.ConversationID, model.EventTypeMessageCreated, message)
+	queueLinkPreviews(message)
+}
 
-	subscribers, found := subscriptions[conversationId]
-	if found {
-		var activeSubscribers []*model.MessageSubscription
-		for _, subscriber := range subscribers {
-			select {
-			case <-subscriber.DoneChannel:
-				// Remove inactive subscriber
-				continue
-			case subscriber.MessageChannel <- message:
-				// Message sent successfully
-				activeSubscribers = append(activeSubscribers, subscriber)
-			}
+// formatContent validates the format of a new message and parses MARKDOWN
+// content into its tree, PLAIN content has none.
+func formatContent(contentType model.MessageContentType, format model.MessageFormat, content string) (*model.FormattedNode, error) {
+	switch format {
+	case model.MessageFormatPlain:
+		return nil, nil
+	case model.MessageFormatMarkdown:
+		if contentType != model.MessageContentTypeText {
+			return nil, utils.ErrInvalidInput
 		}
-		subscriptions[conversationId] = activeSubscribers
+		return markdown.Parse(content)
+	}
+	return nil, utils.ErrInvalidInput
+}
+
+// senderAttachments loads the attachments of a new message, they must have
+// been uploaded by its sender.
+func (s *chatController) senderAttachments(senderID string, attachmentIDs []string) ([]*model.Attachment, error) {
+	attachmentIDs = uniqueStrings(attachmentIDs)
+	if len(attachmentIDs) == 0 {
+		return nil, nil
+	}
+	if len(attachmentIDs) > maxMessageAttachments {
+		return nil, utils.ErrInvalidInput
+	}
+
+	attachments, err := dao.NewAttachmentDAO(s.chatDAO.DB).GetAttachmentsByID(attachmentIDs)
+	if err != nil {
+		return nil, err
+	}
+	if len(attachments) != len(attachmentIDs) {
+		return nil, utils.ErrInvalidInput
+	}
+	for _, attachment := range attachments {
+		if attachment.UploaderID != senderID {
+			return nil, utils.ErrForbidden
+		}
+	}
+	return attachments, nil
+}
+
+// SetMessageTimer turns disappearing messages on or off. Admins set it in
+// groups and channels, either side in a direct conversation. Messages already
+// sent keep the expiry they had.
+func (s *chatController) SetMessageTimer(id string, input model.SetMessageTimerInput) (*model.Conversation, error) {
+	if !isAllowedMessageTTL(input.MessageTTL) {
+		return nil, utils.ErrInvalidInput
+	}
+
+	userId := utils.GetCurrentUserID(s.ctx)
+	conversation, err := s.chatDAO.GetConversationSummary(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if conversation.Kind == model.ConversationKindDirect {
+		isMember, err := s.chatDAO.IsConversationMember(id, userId)
+		if err != nil {
+			return nil, err
+		}
+		if !isMember {
+			return nil, utils.ErrForbidden
+		}
+	} else if err := requireConversationAdmin(s.chatDAO, id, userId); err != nil {
+		return nil, err
+	}
+
+	if conversation.MessageTTL == input.MessageTTL {
+		return conversation, nil
+	}
+
+	if err := s.chatDAO.UpdateConversationColumns(id, map[string]interface{}{"message_ttl": input.MessageTTL}); err != nil {
+		return nil, err
+	}
+	conversation.MessageTTL = input.MessageTTL
+
+	_, _ = s.createSystemMessage(id, &model.SystemMessageContent{
+		Type:       model.SystemMessageTypeTimerChanged,
+		ActorID:    userId,
+		MessageTTL: &input.MessageTTL,
+	})
+	s.publishConversationEvent(id, model.EventTypeMetadataChanged, conversation)
+	return conversation, nil
+}
+
+// allowedMessageTTLs are the disappearing messages timers offered to users.
+var allowedMessageTTLs = []time.Duration{
+	24 * time.Hour,
+	7 * 24 * time.Hour,
+	90 * 24 * time.Hour,
+}
+
+func isAllowedMessageTTL(seconds int64) bool {
+	if seconds == 0 {
+		return true
+	}
+	for _, ttl := range allowedMessageTTLs {
+		if int64(ttl.Seconds()) == seconds {
+			return true
+		}
+	}
+	return false
+}
+
+// PinMessage pins the message to its conversation, only admins can pin and
+// at most MAX_PINNED_MESSAGES messages can be pinned at once.
+func (s *chatController) PinMessage(messageID string) (*model.PinnedMessage, error) {
+	message, err := s.chatDAO.GetMessageByID(messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	userId := utils.GetCurrentUserID(s.ctx)
+	if err := requireConversationAdmin(s.chatDAO, message.ConversationID, userId); err != nil {
+		return nil, err
+	}
+
+	if message.ContentType == model.MessageContentTypeSystem {
+		return nil, utils.ErrInvalidInput
+	}
+
+	pin := &model.PinnedMessage{
+		ConversationID: message.ConversationID,
+		MessageID:      message.ID,
+		PinnedByID:     userId,
+	}
+	pinned, err := s.chatDAO.PinMessage(pin, s.maxPinnedMessages)
+	if errors.Is(err, dao.ErrLimitReached) {
+		return nil, fmt.Errorf("%w: at most %d messages can be pinned", utils.ErrConflict, s.maxPinnedMessages)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !pinned {
+		return nil, utils.ErrConflict
+	}
+	pin.Message = message
+
+	_, _ = s.createSystemMessage(message.ConversationID, &model.SystemMessageContent{
+		Type:      model.SystemMessageTypeMessagePinned,
+		ActorID:   userId,
+		MessageID: message.ID,
+	})
+	s.publishConversationEvent(message.ConversationID, model.EventTypeMessagePinned, pin)
+	return pin, nil
+}
+
+func (s *chatController) UnpinMessage(messageID string) error {
+	message, err := s.chatDAO.GetMessageByID(messageID)
+	if err != nil {
+		return err
+	}
+
+	userId := utils.GetCurrentUserID(s.ctx)
+	if err := requireConversationAdmin(s.chatDAO, message.ConversationID, userId); err != nil {
+		return err
+	}
+
+	unpinned, err := s.chatDAO.UnpinMessage(message.ConversationID, message.ID)
+	if err != nil {
+		return err
+	}
+	if !unpinned {
+		return utils.ErrNotFound
+	}
+
+	_, _ = s.createSystemMessage(message.ConversationID, &model.SystemMessageContent{
+		Type:      model.SystemMessageTypeMessageUnpinned,
+		ActorID:   userId,
+		MessageID: message.ID,
+	})
+	s.publishConversationEvent(message.ConversationID, model.EventTypeMessageUnpinned, &model.PinnedMessage{
+		ConversationID: message.ConversationID,
+		MessageID:      message.ID,
+	})
+	return nil
+}
+
+func (s *chatController) GetPinnedMessages(conversationID string) ([]*model.PinnedMessage, error) {
+	userId := utils.GetCurrentUserID(s.ctx)
+	isMember, err := s.chatDAO.IsConversationMember(conversationID, userId)
+	if err != nil {
+		return nil, err
+	}
+	if !isMember {
+		return nil, utils.ErrForbidden
+	}
+
+	return s.chatDAO.GetPinnedMessages(conversationID)
+}
+
+// React adds the current user's reaction to the message. Every member can
+// react, including the readers of a channel.
+func (s *chatController) React(messageID string, input model.ReactInput) ([]*model.ReactionCount, error) {
+	return s.changeReaction(messageID, input, true)
+}
+
+func (s *chatController) Unreact(messageID string, input model.ReactInput) ([]*model.ReactionCount, error) {
+	return s.changeReaction(messageID, input, false)
+}
+
+func (s *chatController) changeReaction(messageID string, input model.ReactInput, add bool) ([]*model.ReactionCount, error) {
+	emoji := strings.TrimSpace(input.Emoji)
+	if emoji == "" || len(emoji) > maxReactionEmojiLength || strings.ContainsAny(emoji, " \t\n") {
+		return nil, utils.ErrInvalidInput
+	}
+
+	userId := utils.GetCurrentUserID(s.ctx)
+	message, err := s.memberMessage(messageID, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	var changed bool
+	if add {
+		changed, err = s.chatDAO.AddReaction(&model.MessageReaction{MessageID: message.ID, UserID: userId, Emoji: emoji})
+	} else {
+		changed, err = s.chatDAO.RemoveReaction(message.ID, userId, emoji)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	counts, err := s.chatDAO.GetReactionCounts(message.ID, userId)
+	if err != nil {
+		return nil, err
+	}
+	if !changed {
+		return counts, nil
+	}
+
+	// Reacted is about the current user, it means nothing to the others
+	published := make([]*model.ReactionCount, len(counts))
+	for i, count := range counts {
+		published[i] = &model.ReactionCount{Emoji: count.Emoji, Count: count.Count}
+	}
+	s.publishConversationEvent(message.ConversationID, model.EventTypeReactionChanged, &model.ReactionPayload{
+		MessageID: message.ID,
+		UserID:    userId,
+		Emoji:     emoji,
+		Added:     add,
+		Counts:    published,
+	})
+	return counts, nil
+}
+
+func (s *chatController) GetReactions(messageID string) ([]*model.ReactionCount, error) {
+	userId := utils.GetCurrentUserID(s.ctx)
+	message, err := s.memberMessage(messageID, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.chatDAO.GetReactionCounts(message.ID, userId)
+}
+
+// ViewMessages counts the current user once towards the view count of each of
+// the messages. Views are not pushed as events, a popular channel post would
+// flood every reader with them.
+func (s *chatController) ViewMessages(conversationID string, input model.ViewMessagesInput) error {
+	userId := utils.GetCurrentUserID(s.ctx)
+	if userId == "" {
+		return utils.ErrUnauthenticated
+	}
+	if len(input.MessageIDs) == 0 {
+		return nil
+	}
+
+	isMember, err := s.chatDAO.IsConversationMember(conversationID, userId)
+	if err != nil {
+		return err
+	}
+	if !isMember {
+		return utils.ErrForbidden
+	}
+
+	return s.chatDAO.RecordViews(conversationID, userId, input.MessageIDs)
+}
+
+// memberMessage loads the message if the user is a member of its
+// conversation.
+func (s *chatController) memberMessage(messageID string, userID string) (*model.Message, error) {
+	if userID == "" {
+		return nil, utils.ErrUnauthenticated
+	}
+
+	message, err := s.chatDAO.GetMessageByID(messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	isMember, err := s.chatDAO.IsConversationMember(message.ConversationID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !isMember {
+		return nil, utils.ErrForbidden
+	}
+	return message, nil
+}
+
+// messagesDeleted tells the members of each conversation which messages are
+// gone and which pins went with them, then removes the blobs of attachments
+// nothing uses anymore.
+func (s *chatController) messagesDeleted(deleted *dao.DeletedMessages) {
+	byConversation := make(map[string][]string)
+	for _, message := range deleted.Messages {
+		byConversation[message.ConversationID] = append(byConversation[message.ConversationID], message.ID)
+	}
+	for conversationID, messageIDs := range byConversation {
+		s.publishConversationEvent(conversationID, model.EventTypeMessagesDeleted, &model.MessagesDeletedPayload{
+			MessageIDs: messageIDs,
+		})
+	}
+
+	for _, pin := range deleted.Unpinned {
+		pin.Message = nil
+		s.publishConversationEvent(pin.ConversationID, model.EventTypeMessageUnpinned, pin)
+	}
+
+	blobStorage := storage.GetBlobStorage()
+	for _, attachment := range deleted.Orphans {
+		if err := blobStorage.Delete(attachment.StorageKey); err != nil {
+			log.Printf("delete attachment %s: %v", attachment.ID, err)
+		}
+	}
+}
+
+func (s *chatController) AddUserToConversation(conversationID string, userID string) (*model.Conversation, error) {
+	conversation, added, err := s.addMember(conversationID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if added != nil {
+		s.publishConversationEvent(conversationID, model.EventTypeMemberAdded, added)
+	}
+	return conversation, nil
+}
+
+// addMember adds the user to the conversation without telling anyone, so it
+// can run in a transaction. It returns the user when they were not a member
+// already.
+func (s *chatController) addMember(conversationID string, userID string) (*model.Conversation, *model.User, error) {
+	userService := NewUserService(s.chatDAO.DB)
+	userService.SetContext(s.ctx)
+
+	user, err := userService.GetUserByID(userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	conversation, err := s.chatDAO.GetConversationSummary(conversationID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	isMember, err := s.chatDAO.IsConversationMember(conversationID, user.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if isMember {
+		return conversation, nil, nil
+	}
+
+	if conversation.Kind == model.ConversationKindDirect {
+		return nil, nil, utils.ErrForbidden
+	}
+
+	if err := s.chatDAO.DB.Model(conversation).Association("Members").Append(user); err != nil {
+		return nil, nil, err
+	}
+
+	hideEmails([]*model.User{user})
+	return conversation, user, nil
+}
+
+func (s *chatController) RemoveUserFromConversation(conversationID string, userID string) (*model.Conversation, error) {
+	userService := NewUserService(s.chatDAO.DB)
+	userService.SetContext(s.ctx)
+
+	user, err := userService.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	conversation, err := s.chatDAO.GetConversationSummary(conversationID)
+	if err != nil {
+		return nil, err
+	}
+
+	isMember, err := s.chatDAO.IsConversationMember(conversationID, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !isMember {
+		return conversation, nil
+	}
+
+	if err := s.chatDAO.DB.Model(conversation).Association("Members").Delete(user); err != nil {
+		return nil, err
+	}
+
+	hideEmails([]*model.User{user})
+	s.publishConversationEvent(conversationID, model.EventTypeMemberRemoved, user)
+	eventHub.Publish(UserStream(user.ID), model.EventTypeMemberRemoved, conversationID, user)
+	// streams are only authorized when they connect
+	eventHub.DropUser(ConversationStream(conversationID), user.ID)
+	return conversation, nil
+}
+
+// AuthorizeEventStream is the single authorization path shared by the
+// WebSocket, Server-Sent Events and long-polling transports. An empty
+// conversationID selects the current user's own event stream.
+func (s *chatController) AuthorizeEventStream(conversationID string) (string, error) {
+	userId := utils.GetCurrentUserID(s.ctx)
+	if userId == "" {
+		return "", utils.ErrUnauthenticated
+	}
+
+	if conversationID == "" {
+		return UserStream(userId), nil
+	}
+
+	isMember, err := s.chatDAO.IsConversationMember(conversationID, userId)
+	if err != nil {
+		return "", err
+	}
+	if !isMember {
+		return "", utils.ErrForbidden
+	}
+
+	return ConversationStream(conversationID), nil
+}
+
+func (s *chatController) SubscribeEvents(stream string, lastEventID int64) (*model.EventSubscription, []*model.Event) {
+	return eventHub.Subscribe(stream, utils.GetCurrentUserID(s.ctx), lastEventID)
+}
+
+func (s *chatController) UnsubscribeEvents(subscription *model.EventSubscription) {
+	eventHub.Unsubscribe(subscription)
+}
+
+func (s *chatController) PollEvents(stream string, lastEventID int64, timeout time.Duration) *model.EventPollResult {
+	events, cursor := eventHub.Wait(stream, utils.GetCurrentUserID(s.ctx), lastEventID, timeout)
+	return &model.EventPollResult{
+		Events:      events,
+		LastEventID: cursor,
+	}
+}
+
+// SendTypingSignal takes the user explicitly because it is called for every
+// frame read from a websocket, which is not a request of its own.
+func (s *chatController) SendTypingSignal(userID string, conversationID string, eventType model.EventType) error {
+	if userID == "" {
+		return utils.ErrUnauthenticated
+	}
+
+	switch eventType {
+	case model.EventTypeTypingStarted:
+		// limited before the lookup, so a flood of frames stays off the database
+		if !typingTracker.Allow(userID) {
+			return utils.ErrRateLimited
+		}
+
+		isMember, err := s.chatDAO.IsConversationMember(conversationID, userID)
+		if err != nil {
+			return err
+		}
+		if !isMember {
+			return utils.ErrForbidden
+		}
+
+		typingTracker.Start(conversationID, userID)
+		return nil
+	case model.EventTypeTypingStopped:
+		// only ends a session Start let in, which needs no lookup
+		typingTracker.Stop(conversationID, userID)
+		return nil
+	}
+
+	return utils.ErrInvalidInput
+}
+
+// HandleClientEvent dispatches a frame received on the realtime channel.
+func (s *chatController) HandleClientEvent(userID string, event model.ClientEvent) error {
+	switch event.Type {
+	case model.EventTypeTypingStarted, model.EventTypeTypingStopped:
+		return s.SendTypingSignal(userID, event.ConversationID, event.Type)
+	}
+
+	_, err := s.HandleCallEvent(userID, event)
+	return err
+}
+
+// HandleCallEvent applies a call control or signaling event sent by the user.
+// Like SendTypingSignal the user is explicit so websocket frames can use it.
+func (s *chatController) HandleCallEvent(userID string, event model.ClientEvent) (*model.CallSession, error) {
+	if userID == "" {
+		return nil, utils.ErrUnauthenticated
+	}
+
+	switch event.Type {
+	case model.EventTypeCallStart:
+		isMember, err := s.chatDAO.IsConversationMember(event.ConversationID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if !isMember {
+			return nil, utils.ErrForbidden
+		}
+
+		// a call would ring every subscriber of a channel
+		conversation, err := s.chatDAO.GetConversationSummary(event.ConversationID)
+		if err != nil {
+			return nil, err
+		}
+		if conversation.Kind == model.ConversationKindChannel {
+			return nil, fmt.Errorf("%w: channels have no calls", utils.ErrForbidden)
+		}
+
+		memberIDs, err := s.chatDAO.GetConversationMemberIDs(event.ConversationID)
+		if err != nil {
+			return nil, err
+		}
+		return callManager.Start(userID, event.ConversationID, memberIDs, event.Media)
+	case model.EventTypeCallAccept:
+		return callManager.Accept(userID, event.CallID)
+	case model.EventTypeCallDecline:
+		return callManager.Decline(userID, event.CallID)
+	case model.EventTypeCallHangUp:
+		return callManager.HangUp(userID, event.CallID)
+	case model.EventTypeCallOffer, model.EventTypeCallAnswer, model.EventTypeCallCandidate:
+		return nil, callManager.Relay(userID, event.CallID, event.TargetUserID, event.Type, event.Data)
+	}
+
+	return nil, utils.ErrInvalidInput
+}
+
+func (s *chatController) GetCall(callID string) (*model.CallSession, error) {
+	call, err := callManager.GetCall(callID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, isParticipant := call.Participants[utils.GetCurrentUserID(s.ctx)]; !isParticipant {
+		return nil, utils.ErrForbidden
+	}
+
+	return call, nil
+}
+
+// LeaveCallsOnDisconnect hangs the user up once their last realtime
+// connection is gone.
+func (s *chatController) LeaveCallsOnDisconnect(userID string) {
+	if presenceTracker.IsOnline(userID) {
+		return
+	}
+
+	callManager.HangUpAll(userID)
+}
+
+func (s *chatController) recordCallHistory(call *model.CallSession) {
+	summary := &model.CallSummary{
+		CallID:      call.ID,
+		InitiatorID: call.InitiatorID,
+		Media:       call.Media,
+		Result:      call.Result,
+	}
+	if call.AnsweredAt != nil && call.EndedAt != nil {
+		summary.DurationSeconds = int64(call.EndedAt.Sub(*call.AnsweredAt).Seconds())
+	}
+	for participantID := range call.Participants {
+		summary.ParticipantIDs = append(summary.ParticipantIDs, participantID)
+	}
+
+	_, _ = s.createSystemMessage(call.ConversationID, &model.SystemMessageContent{
+		Type:    model.SystemMessageTypeCall,
+		ActorID: call.InitiatorID,
+		Call:    summary,
+	})
+}
+
+// createSystemMessage stores a SYSTEM timeline entry on behalf of the actor
+// and pushes it like any other message.
+func (s *chatController) createSystemMessage(conversationID string, content *model.SystemMessageContent) (*model.Message, error) {
+	data, err := json.Marshal(content)
+	if err != nil {
+		return nil, err
+	}
+
+	message := &model.Message{
+		ID:             uuid.New().String(),
+		ConversationID: conversationID,
+		SenderID:       content.ActorID,
+		ContentType:    model.MessageContentTypeSystem,
+		Content:        string(data),
+	}
+
+	if err := s.chatDAO.CreateMessage(message); err != nil {
+		return nil, err
+	}
+
+	s.publishConversationEvent(conversationID, model.EventTypeMessageCreated, message)
+	return message, nil
+}
+
+// publishConversationEvent sends the event to the conversation stream and to
+// the user streams of the members who are connected. Members are read a
+// batch at a time and each batch takes the hub lock once, so the fan-out of
+// a large channel stays cheap.
+func (s *chatController) publishConversationEvent(conversationID string, eventType model.EventType, payload any) {
+	eventHub.Publish(ConversationStream(conversationID), eventType, conversationID, payload)
+
+	_ = s.chatDAO.ForEachMemberIDBatch(conversationID, memberFanOutBatchSize, func(memberIDs []string) {
+		streams := make([]string, len(memberIDs))
+		for i, memberID := range memberIDs {
+			streams[i] = UserStream(memberID)
+		}
+		eventHub.Fanout(streams, eventType, conversationID, payload)
+	})
+}
+
+// requireConversationAdmin returns nil only when the user is an admin of the
+// conversation.
+func requireConversationAdmin(chatDAO *dao.ChatDAO, conversationID string, userID string) error {
+	if userID == "" {
+		return utils.ErrUnauthenticated
+	}
+
+	member, err := chatDAO.GetMember(conversationID, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return utils.ErrForbidden
+	}
+	if err != nil {
+		return err
+	}
+
+	if member.Role != model.MemberRoleAdmin {
+		return utils.ErrForbidden
+	}
+	return nil
+}
+
+func directConversationKey(userID string, otherUserID string) string {
+	if userID > otherUserID {
+		userID, otherUserID = otherUserID, userID
+	}
+	return userID + ":" + otherUserID
+}
+
+// hideEmails clears the emails of users sent to a whole conversation, the
+// current user's own one included.
+func hideEmails(users []*model.User) {
+	for _, user := range users {
+		user.Email = ""
 	}
 }
 
@@ -0,0 +1,307 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/badaccuracyid/softeng_backend/src/database/dao"
+	"github.com/badaccuracyid/softeng_backend/src/model"
+	"github.com/badaccuracyid/softeng_backend/src/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ContactController interface {
+	SetContext(ctx *gin.Context)
+
+	SendRequest(userID string) (*model.ContactRelation, error)
+	AcceptRequest(userID string) (*model.ContactRelation, error)
+	DeclineRequest(userID string) (*model.ContactRelation, error)
+	CancelRequest(userID string) (*model.ContactRelation, error)
+	RemoveContact(userID string) (*model.ContactRelation, error)
+
+	GetContacts() ([]*model.ContactEntry, error)
+	GetIncomingRequests() ([]*model.ContactRequest, error)
+	GetOutgoingRequests() ([]*model.ContactRequest, error)
+	GetRelation(userID string) (*model.ContactRelation, error)
+}
+
+type contactController struct {
+	ctx        *gin.Context
+	contactDAO *dao.ContactDAO
+	userDAO    *dao.UserDAO
+}
+
+func NewContactController(db *gorm.DB) ContactController {
+	return &contactController{
+		contactDAO: dao.NewContactDAO(db),
+		userDAO:    dao.NewUserDAO(db),
+	}
+}
+
+func (s *contactController) SetContext(ctx *gin.Context) {
+	s.ctx = ctx
+}
+
+// SendRequest asks the user to become a contact of the current user. When
+// the user had already asked the current user, their request is accepted
+// instead.
+func (s *contactController) SendRequest(userID string) (*model.ContactRelation, error) {
+	userId, err := s.otherUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	isContact, err := s.contactDAO.IsContact(userId, userID)
+	if err != nil {
+		return nil, err
+	}
+	if isContact {
+		return &model.ContactRelation{UserID: userID, State: model.ContactStateContact}, nil
+	}
+
+	request := &model.ContactRequest{
+		PairKey:     directConversationKey(userId, userID),
+		SenderID:    userId,
+		RecipientID: userID,
+	}
+	created, err := s.contactDAO.CreateContactRequest(request)
+	if err != nil {
+		return nil, err
+	}
+
+	if !created {
+		pending, err := s.contactDAO.GetContactRequest(request.PairKey)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// answered in the meantime, the client can try again
+			return nil, utils.ErrConflict
+		}
+		if err != nil {
+			return nil, err
+		}
+		if pending.SenderID == userID {
+			return s.AcceptRequest(userID)
+		}
+		return &model.ContactRelation{UserID: userID, State: model.ContactStateOutgoing}, nil
+	}
+
+	publishContactEvent(model.EventTypeContactRequested, request)
+	return &model.ContactRelation{UserID: userID, State: model.ContactStateOutgoing}, nil
+}
+
+// AcceptRequest accepts the request the user sent to the current user.
+func (s *contactController) AcceptRequest(userID string) (*model.ContactRelation, error) {
+	userId, err := s.otherUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := s.contactDAO.AcceptContactRequest(directConversationKey(userId, userID), userId)
+	if err != nil {
+		return nil, err
+	}
+	if request == nil {
+		return nil, fmt.Errorf("%w: no pending request from this user", utils.ErrNotFound)
+	}
+
+	publishContactEvent(model.EventTypeContactAdded, request)
+	return &model.ContactRelation{UserID: userID, State: model.ContactStateContact}, nil
+}
+
+// DeclineRequest turns down the request the user sent to the current user,
+// they may send another one later.
+func (s *contactController) DeclineRequest(userID string) (*model.ContactRelation, error) {
+	return s.closeRequest(userID, userID)
+}
+
+// CancelRequest takes back the request the current user sent to the user.
+func (s *contactController) CancelRequest(userID string) (*model.ContactRelation, error) {
+	return s.closeRequest(userID, utils.GetCurrentUserID(s.ctx))
+}
+
+func (s *contactController) closeRequest(userID string, senderID string) (*model.ContactRelation, error) {
+	userId, err := s.otherUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	request := &model.ContactRequest{
+		PairKey:     directConversationKey(userId, userID),
+		SenderID:    senderID,
+		RecipientID: userId,
+	}
+	if senderID == userId {
+		request.RecipientID = userID
+	}
+
+	closed, err := s.contactDAO.DeleteContactRequest(request.PairKey, senderID)
+	if err != nil {
+		return nil, err
+	}
+	if !closed {
+		return nil, fmt.Errorf("%w: no pending request with this user", utils.ErrNotFound)
+	}
+
+	publishContactEvent(model.EventTypeContactRequestClosed, request)
+	return &model.ContactRelation{UserID: userID, State: model.ContactStateNone}, nil
+}
+
+// RemoveContact removes the user from the current user's contacts, and the
+// current user from theirs.
+func (s *contactController) RemoveContact(userID string) (*model.ContactRelation, error) {
+	userId, err := s.otherUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	removed, err := s.contactDAO.RemoveContact(userId, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !removed {
+		return nil, fmt.Errorf("%w: this user is not a contact", utils.ErrNotFound)
+	}
+
+	publishContactEvent(model.EventTypeContactRemoved, &model.ContactRequest{SenderID: userId, RecipientID: userID})
+	return &model.ContactRelation{UserID: userID, State: model.ContactStateNone}, nil
+}
+
+// GetContacts lists the current user's contacts with their presence, the
+// latest added first.
+func (s *contactController) GetContacts() ([]*model.ContactEntry, error) {
+	userId := utils.GetCurrentUserID(s.ctx)
+	if userId == "" {
+		return nil, utils.ErrUnauthenticated
+	}
+
+	contacts, err := s.contactDAO.GetContacts(userId)
+	if err != nil {
+		return nil, err
+	}
+	if len(contacts) == 0 {
+		return []*model.ContactEntry{}, nil
+	}
+
+	contactIDs := make([]string, 0, len(contacts))
+	for _, contact := range contacts {
+		contactIDs = append(contactIDs, contact.ContactID)
+	}
+	users, err := s.userDAO.GetUsersByID(contactIDs)
+	if err != nil {
+		return nil, err
+	}
+	usersByID := make(map[string]*model.User, len(users))
+	for _, user := range users {
+		usersByID[user.ID] = user
+	}
+
+	entries := make([]*model.ContactEntry, 0, len(contacts))
+	for _, contact := range contacts {
+		user, found := usersByID[contact.ContactID]
+		if !found {
+			continue
+		}
+		presence := buildPresence(user, userId)
+		user.Email = ""
+		entries = append(entries, &model.ContactEntry{User: user, Presence: presence, Since: contact.CreatedAt})
+	}
+
+	return entries, nil
+}
+
+func (s *contactController) GetIncomingRequests() ([]*model.ContactRequest, error) {
+	userId := utils.GetCurrentUserID(s.ctx)
+	if userId == "" {
+		return nil, utils.ErrUnauthenticated
+	}
+
+	return s.contactDAO.GetIncomingContactRequests(userId)
+}
+
+func (s *contactController) GetOutgoingRequests() ([]*model.ContactRequest, error) {
+	userId := utils.GetCurrentUserID(s.ctx)
+	if userId == "" {
+		return nil, utils.ErrUnauthenticated
+	}
+
+	return s.contactDAO.GetOutgoingContactRequests(userId)
+}
+
+// GetRelation tells whether the user is a contact of the current user, or
+// whether a request between them is pending.
+func (s *contactController) GetRelation(userID string) (*model.ContactRelation, error) {
+	userId, err := s.otherUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	relation := &model.ContactRelation{UserID: userID, State: model.ContactStateNone}
+	isContact, err := s.contactDAO.IsContact(userId, userID)
+	if err != nil {
+		return nil, err
+	}
+	if isContact {
+		relation.State = model.ContactStateContact
+		return relation, nil
+	}
+
+	request, err := s.contactDAO.GetContactRequest(directConversationKey(userId, userID))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return relation, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	relation.State = model.ContactStateIncoming
+	if request.SenderID == userId {
+		relation.State = model.ContactStateOutgoing
+	}
+	return relation, nil
+}
+
+// otherUser checks the user exists and is not the current user, whose ID it
+// returns.
+func (s *contactController) otherUser(userID string) (string, error) {
+	userId := utils.GetCurrentUserID(s.ctx)
+	if userId == "" {
+		return "", utils.ErrUnauthenticated
+	}
+	if userID == "" || userID == userId {
+		return "", utils.ErrInvalidInput
+	}
+
+	if _, err := s.userDAO.GetUserByID(userID); err != nil {
+		return "", err
+	}
+	return userId, nil
+}
+
+// publishContactEvent tells both users, on all their devices.
+func publishContactEvent(eventType model.EventType, request *model.ContactRequest) {
+	eventHub.Publish(UserStream(request.SenderID), eventType, "", request)
+	eventHub.Publish(UserStream(request.RecipientID), eventType, "", request)
+}
+
+// requireDirectMessagesAllowed checks that the users who only let their
+// contacts start a conversation with them have the initiator as a contact.
+func requireDirectMessagesAllowed(db *gorm.DB, initiatorID string, users []*model.User) error {
+	restricted := []string{}
+	for _, user := range users {
+		if user.ID != initiatorID && user.DirectMessages == model.DirectMessagePolicyContacts {
+			restricted = append(restricted, user.ID)
+		}
+	}
+	if len(restricted) == 0 {
+		return nil
+	}
+
+	contactIDs, err := dao.NewContactDAO(db).GetContactIDsAmong(initiatorID, restricted)
+	if err != nil {
+		return err
+	}
+	if len(contactIDs) < len(restricted) {
+		return fmt.Errorf("%w: only their contacts can start a conversation with this user", utils.ErrForbidden)
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package controllers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/badaccuracyid/softeng_backend/src/database/dao"
+	"github.com/badaccuracyid/softeng_backend/src/model"
+	"github.com/badaccuracyid/softeng_backend/src/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type DraftController interface {
+	SetContext(ctx *gin.Context)
+
+	SaveDraft(conversationID string, input model.SaveDraftInput) (*model.MessageDraft, error)
+	GetDrafts() ([]*model.MessageDraft, error)
+}
+
+type draftController struct {
+	ctx      *gin.Context
+	draftDAO *dao.DraftDAO
+	chatDAO  *dao.ChatDAO
+}
+
+func NewDraftController(db *gorm.DB) DraftController {
+	return &draftController{
+		draftDAO: dao.NewDraftDAO(db),
+		chatDAO:  dao.NewChatDAO(db),
+	}
+}
+
+func (s *draftController) SetContext(ctx *gin.Context) {
+	s.ctx = ctx
+}
+
+// SaveDraft stores the draft the current user is composing in the
+// conversation. When another device already saved a newer one, that draft
+// is returned unchanged instead.
+func (s *draftController) SaveDraft(conversationID string, input model.SaveDraftInput) (*model.MessageDraft, error) {
+	userId := utils.GetCurrentUserID(s.ctx)
+	if userId == "" {
+		return nil, utils.ErrUnauthenticated
+	}
+	if input.UpdatedAt.IsZero() {
+		return nil, utils.ErrInvalidInput
+	}
+
+	isMember, err := s.chatDAO.IsConversationMember(conversationID, userId)
+	if err != nil {
+		return nil, err
+	}
+	if !isMember {
+		return nil, utils.ErrForbidden
+	}
+
+	draft := &model.MessageDraft{
+		UserID:         userId,
+		ConversationID: conversationID,
+		Content:        input.Content,
+		UpdatedAt:      input.UpdatedAt,
+	}
+	// a clock running ahead must not pin the draft against later edits
+	if now := time.Now(); draft.UpdatedAt.After(now) {
+		draft.UpdatedAt = now
+	}
+
+	if input.ReplyToMessageID != nil && *input.ReplyToMessageID != "" {
+		replyTo, err := s.chatDAO.GetMessageByID(*input.ReplyToMessageID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.ErrInvalidInput
+		}
+		if err != nil {
+			return nil, err
+		}
+		if replyTo.ConversationID != conversationID {
+			return nil, utils.ErrInvalidInput
+		}
+		draft.ReplyToMessageID = &replyTo.ID
+	}
+
+	draft.Attachments, err = newChatController(s.chatDAO.DB).senderAttachments(userId, input.AttachmentIDs)
+	if err != nil {
+		return nil, err
+	}
+	if draft.Attachments == nil {
+		draft.Attachments = []*model.Attachment{}
+	}
+
+	saved, err := s.draftDAO.SaveDraft(draft)
+	if err != nil {
+		return nil, err
+	}
+	if !saved {
+		return s.draftDAO.GetDraft(userId, conversationID)
+	}
+
+	eventHub.Publish(UserStream(userId), model.EventTypeDraftUpdated, conversationID, draft)
+	return draft, nil
+}
+
+func (s *draftController) GetDrafts() ([]*model.MessageDraft, error) {
+	userId := utils.GetCurrentUserID(s.ctx)
+	if userId == "" {
+		return nil, utils.ErrUnauthenticated
+	}
+
+	return s.draftDAO.GetDraftsForUser(userId)
+}
@@ -0,0 +1,364 @@
+package controllers
+
+import (
+	"sync"
+	"time"
+
+	"github.com/badaccuracyid/softeng_backend/src/model"
+)
+
+const (
+	eventBacklogSize       = 256
+	eventSubscriptionQueue = 64
+	// eventStreamIdleTTL is how long a stream nobody subscribes to keeps its
+	// backlog after its last event or subscriber
+	eventStreamIdleTTL = 10 * time.Minute
+)
+
+// EventHub fans realtime events out to the subscribers of a stream and keeps
+// a short backlog per stream so clients can resume after reconnecting.
+// Event IDs are global and increasing, so a single Last-Event-ID is enough to
+// resume any stream.
+//
+// Streams nobody subscribes to are dropped once idle for idleTTL, so the hub
+// does not grow with every conversation and user that ever had an event.
+type EventHub struct {
+	mutex     sync.Mutex
+	lastID    int64
+	streams   map[string]*eventStream
+	idleTTL   time.Duration
+	lastSweep time.Time
+	// droppedID is the latest event a stream that does not exist may have
+	// missed, because the stream was dropped or skipped by Fanout. A stream
+	// created again cannot replay anything up to it.
+	droppedID int64
+}
+
+type eventStream struct {
+	backlog      []*model.Event
+	evictedID    int64
+	subscribers  map[*model.EventSubscription]struct{}
+	lastActiveAt time.Time
+}
+
+var eventHub = NewEventHub()
+
+func NewEventHub() *EventHub {
+	return &EventHub{
+		streams:   make(map[string]*eventStream),
+		idleTTL:   eventStreamIdleTTL,
+		lastSweep: time.Now(),
+	}
+}
+
+func ConversationStream(conversationID string) string {
+	return "conversation:" + conversationID
+}
+
+func UserStream(userID string) string {
+	return "user:" + userID
+}
+
+func (h *EventHub) stream(name string) *eventStream {
+	stream, found := h.streams[name]
+	if !found {
+		stream = &eventStream{
+			evictedID:    h.droppedID,
+			subscribers:  make(map[*model.EventSubscription]struct{}),
+			lastActiveAt: time.Now(),
+		}
+		h.streams[name] = stream
+	}
+	return stream
+}
+
+// dropIdleStreams drops the streams without subscribers that have been idle
+// for the TTL. It looks at most once per TTL, so publishing stays cheap.
+func (h *EventHub) dropIdleStreams(now time.Time) {
+	if now.Sub(h.lastSweep) < h.idleTTL {
+		return
+	}
+	h.lastSweep = now
+
+	for name, stream := range h.streams {
+		if len(stream.subscribers) > 0 || now.Sub(stream.lastActiveAt) < h.idleTTL {
+			continue
+		}
+		if len(stream.backlog) > 0 {
+			h.droppedID = max(h.droppedID, stream.backlog[len(stream.backlog)-1].ID)
+		}
+		delete(h.streams, name)
+	}
+}
+
+func (h *EventHub) Publish(streamName string, eventType model.EventType, conversationID string, payload any) *model.Event {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.lastID++
+	event := &model.Event{
+		ID:             h.lastID,
+		Type:           eventType,
+		ConversationID: conversationID,
+		Payload:        payload,
+		CreatedAt:      time.Now(),
+	}
+	h.dropIdleStreams(event.CreatedAt)
+
+	stream := h.stream(streamName)
+	stream.lastActiveAt = event.CreatedAt
+	stream.append(event)
+
+	for subscription := range stream.subscribers {
+		select {
+		case subscription.EventChannel <- event:
+		default:
+			// Slow consumer, drop it so the client reconnects and resumes
+			// from its last event instead of stalling the hub.
+			h.removeSubscription(stream, subscription)
+		}
+	}
+
+	return event
+}
+
+// Fanout publishes one event to those of the streams that exist, such as
+// the user streams of the members of a conversation who are connected or
+// were until recently. No stream is created for the others, which would keep
+// a backlog for every member, and a client resuming one of them later
+// resyncs. Publishing this way does not keep a stream from idling.
+func (h *EventHub) Fanout(streamNames []string, eventType model.EventType, conversationID string, payload any) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.lastID++
+	event := &model.Event{
+		ID:             h.lastID,
+		Type:           eventType,
+		ConversationID: conversationID,
+		Payload:        payload,
+		CreatedAt:      time.Now(),
+	}
+	h.dropIdleStreams(event.CreatedAt)
+
+	for _, streamName := range streamNames {
+		stream, found := h.streams[streamName]
+		if !found {
+			h.droppedID = event.ID
+			continue
+		}
+
+		stream.append(event)
+		for subscription := range stream.subscribers {
+			select {
+			case subscription.EventChannel <- event:
+			default:
+				h.removeSubscription(stream, subscription)
+			}
+		}
+	}
+}
+
+// Signal delivers an ephemeral event to the current subscribers of the stream
+// except those belonging to excludeUserID. Signals are not numbered, not kept
+// in the backlog and never drop a subscriber when its queue is full.
+func (h *EventHub) Signal(streamName string, eventType model.EventType, conversationID string, payload any, excludeUserID string) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	stream, found := h.streams[streamName]
+	if !found {
+		return
+	}
+
+	event := &model.Event{
+		Type:           eventType,
+		ConversationID: conversationID,
+		Payload:        payload,
+		CreatedAt:      time.Now(),
+	}
+
+	for subscription := range stream.subscribers {
+		if subscription.UserID == excludeUserID {
+			continue
+		}
+		select {
+		case subscription.EventChannel <- event:
+		default:
+		}
+	}
+}
+
+// Subscribe registers a new subscription on the stream. When lastEventID is
+// set, the events after it that are still in the backlog are returned so the
+// caller can replay them before reading from the subscription.
+func (h *EventHub) Subscribe(streamName string, userID string, lastEventID int64) (*model.EventSubscription, []*model.Event) {
+	subscription, missed, _ := h.subscribe(streamName, userID, lastEventID)
+	return subscription, missed
+}
+
+// subscribe also returns the latest event ID when the subscription started,
+// every event after it is delivered to the subscription.
+func (h *EventHub) subscribe(streamName string, userID string, lastEventID int64) (*model.EventSubscription, []*model.Event, int64) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	subscription := &model.EventSubscription{
+		Stream:       streamName,
+		UserID:       userID,
+		EventChannel: make(chan *model.Event, eventSubscriptionQueue),
+		DoneChannel:  make(chan struct{}),
+	}
+
+	h.dropIdleStreams(time.Now())
+	stream := h.stream(streamName)
+	stream.subscribers[subscription] = struct{}{}
+
+	return subscription, stream.eventsAfter(lastEventID), h.lastID
+}
+
+func (h *EventHub) Unsubscribe(subscription *model.EventSubscription) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	stream, found := h.streams[subscription.Stream]
+	if !found {
+		return
+	}
+	h.removeSubscription(stream, subscription)
+}
+
+// DropUser ends the user's subscriptions to the stream when they lose access
+// to it. Their clients see the stream close and are authorized again when
+// they reconnect.
+func (h *EventHub) DropUser(streamName string, userID string) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	stream, found := h.streams[streamName]
+	if !found {
+		return
+	}
+	for subscription := range stream.subscribers {
+		if subscription.UserID == userID {
+			h.removeSubscription(stream, subscription)
+		}
+	}
+}
+
+// Wait returns the events after lastEventID, blocking until one arrives or
+// the timeout expires. The returned cursor is what the client should send
+// on its next poll.
+func (h *EventHub) Wait(streamName string, userID string, lastEventID int64, timeout time.Duration) ([]*model.Event, int64) {
+	subscription, missed, subscribedAt := h.subscribe(streamName, userID, lastEventID)
+	defer h.Unsubscribe(subscription)
+
+	// nothing of the stream up to subscribedAt is newer than what the client
+	// saw, or it would have been missed
+	cursor := max(lastEventID, subscribedAt)
+	if len(missed) > 0 {
+		if missed[0].Type == model.EventTypeResync {
+			// the client reloads its state, then continues from here
+			return missed, subscribedAt
+		}
+		return missed, missed[len(missed)-1].ID
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	events := []*model.Event{}
+	select {
+	case event, ok := <-subscription.EventChannel:
+		if !ok {
+			return events, cursor
+		}
+		events = append(events, event)
+	case <-timer.C:
+		return events, cursor
+	}
+
+	// take whatever else arrived meanwhile, the cursor must not pass an event
+	// that is not returned
+drain:
+	for len(events) < eventSubscriptionQueue {
+		select {
+		case event, ok := <-subscription.EventChannel:
+			if !ok {
+				break drain
+			}
+			events = append(events, event)
+		default:
+			break drain
+		}
+	}
+	return events, eventsCursor(cursor, events)
+}
+
+// eventsCursor is the ID of the last numbered event, signals are not
+// numbered and do not move the cursor.
+func eventsCursor(cursor int64, events []*model.Event) int64 {
+	for _, event := range events {
+		cursor = max(cursor, event.ID)
+	}
+	return cursor
+}
+
+func (h *EventHub) LastEventID() int64 {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.lastID
+}
+
+func (h *EventHub) SubscriberCount(streamName string) int {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	stream, found := h.streams[streamName]
+	if !found {
+		return 0
+	}
+	return len(stream.subscribers)
+}
+
+func (h *EventHub) removeSubscription(stream *eventStream, subscription *model.EventSubscription) {
+	if _, found := stream.subscribers[subscription]; !found {
+		return
+	}
+
+	delete(stream.subscribers, subscription)
+	close(subscription.DoneChannel)
+	close(subscription.EventChannel)
+	if len(stream.subscribers) == 0 {
+		stream.lastActiveAt = time.Now()
+	}
+}
+
+func (s *eventStream) append(event *model.Event) {
+	s.backlog = append(s.backlog, event)
+	if len(s.backlog) > eventBacklogSize {
+		s.evictedID = s.backlog[0].ID
+		s.backlog = s.backlog[1:]
+	}
+}
+
+func (s *eventStream) eventsAfter(lastEventID int64) []*model.Event {
+	if lastEventID <= 0 {
+		return nil
+	}
+
+	if lastEventID < s.evictedID {
+		return []*model.Event{{
+			Type:      model.EventTypeResync,
+			CreatedAt: time.Now(),
+		}}
+	}
+
+	var events []*model.Event
+	for _, event := range s.backlog {
+		if event.ID > lastEventID {
+			events = append(events, event)
+		}
+	}
+	return events
+}
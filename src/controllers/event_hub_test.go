@@ -0,0 +1,175 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/badaccuracyid/softeng_backend/src/model"
+)
+
+func newIdleTestHub(idleTTL time.Duration) *EventHub {
+	hub := NewEventHub()
+	hub.idleTTL = idleTTL
+	return hub
+}
+
+func (h *EventHub) hasStream(name string) bool {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	_, found := h.streams[name]
+	return found
+}
+
+func TestEventHubDropsIdleStreams(t *testing.T) {
+	hub := newIdleTestHub(20 * time.Millisecond)
+
+	seen := hub.Publish("idle", model.EventTypeMessageCreated, "", nil)
+	idle := hub.Publish("idle", model.EventTypeMessageCreated, "", nil)
+	subscription, _ := hub.Subscribe("watched", "user", 0)
+	defer hub.Unsubscribe(subscription)
+	hub.Publish("watched", model.EventTypeMessageCreated, "", nil)
+
+	time.Sleep(40 * time.Millisecond)
+	hub.Publish("busy", model.EventTypeMessageCreated, "", nil)
+
+	if hub.hasStream("idle") {
+		t.Error("the idle stream was kept")
+	}
+	if !hub.hasStream("watched") {
+		t.Error("a stream with a subscriber was dropped")
+	}
+	if !hub.hasStream("busy") {
+		t.Error("the stream just published to is missing")
+	}
+
+	// what the dropped stream held cannot be replayed, the client resyncs
+	resumed, missed := hub.Subscribe("idle", "user", seen.ID)
+	defer hub.Unsubscribe(resumed)
+	if len(missed) != 1 || missed[0].Type != model.EventTypeResync {
+		t.Errorf("missed = %v, want a single RESYNC", missed)
+	}
+
+	// a client that saw everything has nothing to resync
+	caughtUp, missed := hub.Subscribe("idle", "other", idle.ID)
+	defer hub.Unsubscribe(caughtUp)
+	if len(missed) != 0 {
+		t.Errorf("missed = %v, want nothing", missed)
+	}
+}
+
+func TestEventHubKeepsStreamsUntilLastSubscriberIdles(t *testing.T) {
+	hub := newIdleTestHub(20 * time.Millisecond)
+
+	subscription, _ := hub.Subscribe("stream", "user", 0)
+	hub.Publish("stream", model.EventTypeMessageCreated, "", nil)
+	time.Sleep(40 * time.Millisecond)
+	hub.Unsubscribe(subscription)
+
+	// the TTL starts over when the last subscriber leaves
+	hub.Publish("other", model.EventTypeMessageCreated, "", nil)
+	if !hub.hasStream("stream") {
+		t.Error("the stream was dropped as soon as its subscriber left")
+	}
+
+	time.Sleep(40 * time.Millisecond)
+	hub.Publish("other", model.EventTypeMessageCreated, "", nil)
+	if hub.hasStream("stream") {
+		t.Error("the stream was kept after idling without subscribers")
+	}
+}
+
+func TestEventHubWaitDoesNotSkipEvents(t *testing.T) {
+	hub := NewEventHub()
+	first := hub.Publish("stream", model.EventTypeMessageCreated, "", nil)
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		hub.Publish("stream", model.EventTypeMessageCreated, "", nil)
+		hub.Publish("other", model.EventTypeMessageCreated, "", nil)
+		hub.Publish("stream", model.EventTypeMessageCreated, "", nil)
+	}()
+
+	// however the events are split between polls, each one is returned once
+	var received []*model.Event
+	cursor := first.ID
+	for len(received) < 2 {
+		events, next := hub.Wait("stream", "user", cursor, time.Second)
+		if len(events) == 0 {
+			t.Fatalf("no events after %d", cursor)
+		}
+		if last := events[len(events)-1].ID; next != last {
+			t.Fatalf("cursor = %d, want the last event returned %d", next, last)
+		}
+		received = append(received, events...)
+		cursor = next
+	}
+	if len(received) != 2 || received[0].ID >= received[1].ID {
+		t.Errorf("received %v, want the two events in order", received)
+	}
+}
+
+func TestEventHubWaitResync(t *testing.T) {
+	hub := newIdleTestHub(20 * time.Millisecond)
+	seen := hub.Publish("stream", model.EventTypeMessageCreated, "", nil)
+	hub.Publish("stream", model.EventTypeMessageCreated, "", nil)
+	time.Sleep(40 * time.Millisecond)
+	latest := hub.Publish("other", model.EventTypeMessageCreated, "", nil)
+
+	events, cursor := hub.Wait("stream", "user", seen.ID, time.Second)
+	if len(events) != 1 || events[0].Type != model.EventTypeResync {
+		t.Fatalf("events = %v, want a single RESYNC", events)
+	}
+	if cursor != latest.ID {
+		t.Errorf("cursor = %d, want the latest event %d", cursor, latest.ID)
+	}
+}
+
+func TestEventHubDropUser(t *testing.T) {
+	hub := NewEventHub()
+	removed, _ := hub.Subscribe("stream", "removed", 0)
+	kept, _ := hub.Subscribe("stream", "kept", 0)
+	defer hub.Unsubscribe(kept)
+
+	hub.DropUser("stream", "removed")
+	hub.Publish("stream", model.EventTypeMessageCreated, "", nil)
+
+	if _, ok := <-removed.EventChannel; ok {
+		t.Error("the removed user still receives the stream")
+	}
+	if event := <-kept.EventChannel; event.Type != model.EventTypeMessageCreated {
+		t.Errorf("kept subscriber got %s", event.Type)
+	}
+}
+
+func TestEventHubFanout(t *testing.T) {
+	hub := NewEventHub()
+	online, _ := hub.Subscribe("online", "online", 0)
+	defer hub.Unsubscribe(online)
+	left, _ := hub.Subscribe("left", "left", 0)
+	hub.Unsubscribe(left)
+	before := hub.Publish("other", model.EventTypeMessageCreated, "", nil)
+
+	hub.Fanout([]string{"online", "left", "offline"}, model.EventTypeMessageCreated, "conversation", nil)
+
+	event := <-online.EventChannel
+	if event.ConversationID != "conversation" {
+		t.Errorf("online subscriber got %+v", event)
+	}
+	if hub.hasStream("offline") {
+		t.Error("a stream was created for the offline user")
+	}
+
+	// a recent stream keeps the event for the client to resume
+	resumed, missed := hub.Subscribe("left", "left", before.ID)
+	defer hub.Unsubscribe(resumed)
+	if len(missed) != 1 || missed[0].ID != event.ID {
+		t.Errorf("missed = %v, want the fanned out event", missed)
+	}
+
+	// the offline user cannot tell what they missed
+	reconnected, missed := hub.Subscribe("offline", "offline", before.ID)
+	defer hub.Unsubscribe(reconnected)
+	if len(missed) != 1 || missed[0].Type != model.EventTypeResync {
+		t.Errorf("missed = %v, want a single RESYNC", missed)
+	}
+}
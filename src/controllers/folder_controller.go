@@ -0,0 +1,207 @@
+package controllers
+
+import (
+	"strings"
+
+	"github.com/badaccuracyid/softeng_backend/src/database/dao"
+	"github.com/badaccuracyid/softeng_backend/src/model"
+	"github.com/badaccuracyid/softeng_backend/src/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	maxChatFolders          = 20
+	maxChatFolderNameLength = 64
+)
+
+type FolderController interface {
+	SetContext(ctx *gin.Context)
+
+	CreateFolder(input model.ChatFolderInput) (*model.ChatFolder, error)
+	GetFolders() ([]*model.ChatFolder, error)
+	UpdateFolder(id string, input model.ChatFolderInput) (*model.ChatFolder, error)
+	DeleteFolder(id string) error
+
+	GetFolderConversations(id string) ([]*model.Conversation, error)
+}
+
+type folderController struct {
+	ctx       *gin.Context
+	folderDAO *dao.FolderDAO
+	chatDAO   *dao.ChatDAO
+}
+
+func NewFolderController(db *gorm.DB) FolderController {
+	return &folderController{
+		folderDAO: dao.NewFolderDAO(db),
+		chatDAO:   dao.NewChatDAO(db),
+	}
+}
+
+func (s *folderController) SetContext(ctx *gin.Context) {
+	s.ctx = ctx
+}
+
+func (s *folderController) CreateFolder(input model.ChatFolderInput) (*model.ChatFolder, error) {
+	userId := utils.GetCurrentUserID(s.ctx)
+	if userId == "" {
+		return nil, utils.ErrUnauthenticated
+	}
+
+	folders, err := s.folderDAO.GetFoldersForUser(userId)
+	if err != nil {
+		return nil, err
+	}
+	if len(folders) >= maxChatFolders {
+		return nil, utils.ErrConflict
+	}
+
+	folder := &model.ChatFolder{
+		ID:     uuid.New().String(),
+		UserID: userId,
+	}
+	if err := s.applyInput(folder, input); err != nil {
+		return nil, err
+	}
+
+	if err := s.folderDAO.SaveFolder(folder); err != nil {
+		return nil, err
+	}
+
+	return folder, nil
+}
+
+// GetFolders lists the current user's folders in their order, each with the
+// total of unread messages of its conversations.
+func (s *folderController) GetFolders() ([]*model.ChatFolder, error) {
+	userId := utils.GetCurrentUserID(s.ctx)
+	if userId == "" {
+		return nil, utils.ErrUnauthenticated
+	}
+
+	folders, err := s.folderDAO.GetFoldersForUser(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, folder := range folders {
+		conversationIDs, err := s.chatDAO.GetConversationIDsForUser(userId, folder.Filter())
+		if err != nil {
+			return nil, err
+		}
+		if len(conversationIDs) == 0 {
+			continue
+		}
+
+		unreadCounts, err := s.chatDAO.GetUnreadCounts(userId, conversationIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, count := range unreadCounts {
+			folder.UnreadCount += count
+		}
+	}
+
+	return folders, nil
+}
+
+func (s *folderController) UpdateFolder(id string, input model.ChatFolderInput) (*model.ChatFolder, error) {
+	folder, err := s.ownFolder(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.applyInput(folder, input); err != nil {
+		return nil, err
+	}
+
+	if err := s.folderDAO.SaveFolder(folder); err != nil {
+		return nil, err
+	}
+
+	return folder, nil
+}
+
+func (s *folderController) DeleteFolder(id string) error {
+	if _, err := s.ownFolder(id); err != nil {
+		return err
+	}
+
+	return s.folderDAO.DeleteFolder(id)
+}
+
+// GetFolderConversations lists the conversations of the folder like the
+// inbox does, pinned first and then by latest message.
+func (s *folderController) GetFolderConversations(id string) ([]*model.Conversation, error) {
+	folder, err := s.ownFolder(id)
+	if err != nil {
+		return nil, err
+	}
+
+	chatController := NewChatController(s.chatDAO.DB)
+	chatController.SetContext(s.ctx)
+	return chatController.GetConversationsForUser(folder.Filter())
+}
+
+func (s *folderController) applyInput(folder *model.ChatFolder, input model.ChatFolderInput) error {
+	name := strings.TrimSpace(input.Name)
+	if name == "" || len(name) > maxChatFolderNameLength {
+		return utils.ErrInvalidInput
+	}
+
+	folder.Name = name
+	folder.Position = input.Position
+	folder.ConversationIDs = uniqueStrings(input.ConversationIDs)
+	folder.Unread = input.Unread
+	folder.DirectOnly = input.DirectOnly
+	folder.GroupsOnly = input.GroupsOnly
+	folder.Muted = input.Muted
+	folder.Mentions = input.Mentions
+
+	// a folder is either a list or a set of rules, and needs one of them
+	hasList := len(folder.ConversationIDs) > 0
+	if hasList == folder.HasRules() || (folder.DirectOnly && folder.GroupsOnly) {
+		return utils.ErrInvalidInput
+	}
+
+	if hasList {
+		memberships, err := s.chatDAO.GetMembershipsForUser(folder.UserID, folder.ConversationIDs)
+		if err != nil {
+			return err
+		}
+		if len(memberships) != len(folder.ConversationIDs) {
+			return utils.ErrForbidden
+		}
+	}
+	return nil
+}
+
+func (s *folderController) ownFolder(id string) (*model.ChatFolder, error) {
+	userId := utils.GetCurrentUserID(s.ctx)
+	if userId == "" {
+		return nil, utils.ErrUnauthenticated
+	}
+
+	folder, err := s.folderDAO.GetFolderByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if folder.UserID != userId {
+		return nil, utils.ErrNotFound
+	}
+	return folder, nil
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	unique := []string{}
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+	return unique
+}
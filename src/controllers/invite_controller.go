@@ -0,0 +1,350 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/badaccuracyid/softeng_backend/src/database/dao"
+	"github.com/badaccuracyid/softeng_backend/src/model"
+	"github.com/badaccuracyid/softeng_backend/src/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const inviteTokenSize = 18
+
+var ErrInviteUnavailable = fmt.Errorf("%w: invite link is revoked, expired or used up", utils.ErrForbidden)
+
+// errJoinRequestPending rolls back the use of an invite when the user has a
+// request pending already.
+var errJoinRequestPending = errors.New("join request already pending")
+
+type InviteController interface {
+	SetContext(ctx *gin.Context)
+
+	CreateInvite(input model.CreateInviteInput) (*model.ConversationInvite, error)
+	GetInvites(conversationID string) ([]*model.ConversationInvite, error)
+	RevokeInvite(id string) (*model.ConversationInvite, error)
+
+	PreviewInvite(token string) (*model.InvitePreview, error)
+	JoinWithInvite(token string) (*model.JoinResult, error)
+
+	GetJoinRequests(conversationID string) ([]*model.JoinRequest, error)
+	ApproveJoinRequest(id string) (*model.JoinRequest, error)
+	RejectJoinRequest(id string) (*model.JoinRequest, error)
+}
+
+type inviteController struct {
+	ctx       *gin.Context
+	inviteDAO *dao.InviteDAO
+	chatDAO   *dao.ChatDAO
+}
+
+func NewInviteController(db *gorm.DB) InviteController {
+	return &inviteController{
+		inviteDAO: dao.NewInviteDAO(db),
+		chatDAO:   dao.NewChatDAO(db),
+	}
+}
+
+func (s *inviteController) SetContext(ctx *gin.Context) {
+	s.ctx = ctx
+}
+
+func (s *inviteController) CreateInvite(input model.CreateInviteInput) (*model.ConversationInvite, error) {
+	userId := utils.GetCurrentUserID(s.ctx)
+	if err := requireConversationAdmin(s.chatDAO, input.ConversationID, userId); err != nil {
+		return nil, err
+	}
+
+	conversation, err := s.chatDAO.GetConversationSummary(input.ConversationID)
+	if err != nil {
+		return nil, err
+	}
+	if conversation.Kind == model.ConversationKindDirect {
+		return nil, utils.ErrForbidden
+	}
+
+	if input.MaxUses != nil && *input.MaxUses <= 0 {
+		return nil, utils.ErrInvalidInput
+	}
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(time.Now()) {
+		return nil, utils.ErrInvalidInput
+	}
+
+	token, err := utils.GenerateToken(inviteTokenSize)
+	if err != nil {
+		return nil, err
+	}
+
+	invite := &model.ConversationInvite{
+		ID:               uuid.New().String(),
+		ConversationID:   input.ConversationID,
+		Token:            token,
+		CreatedByID:      userId,
+		ExpiresAt:        input.ExpiresAt,
+		MaxUses:          input.MaxUses,
+		RequiresApproval: input.RequiresApproval,
+	}
+
+	if err := s.inviteDAO.CreateInvite(invite); err != nil {
+		return nil, err
+	}
+
+	return invite, nil
+}
+
+func (s *inviteController) GetInvites(conversationID string) ([]*model.ConversationInvite, error) {
+	userId := utils.GetCurrentUserID(s.ctx)
+	if err := requireConversationAdmin(s.chatDAO, conversationID, userId); err != nil {
+		return nil, err
+	}
+
+	return s.inviteDAO.GetInvitesForConversation(conversationID)
+}
+
+func (s *inviteController) RevokeInvite(id string) (*model.ConversationInvite, error) {
+	invite, err := s.inviteDAO.GetInviteByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	userId := utils.GetCurrentUserID(s.ctx)
+	if err := requireConversationAdmin(s.chatDAO, invite.ConversationID, userId); err != nil {
+		return nil, err
+	}
+
+	if err := s.inviteDAO.RevokeInvite(id); err != nil {
+		return nil, err
+	}
+
+	invite.Revoked = true
+	return invite, nil
+}
+
+func (s *inviteController) PreviewInvite(token string) (*model.InvitePreview, error) {
+	invite, err := s.usableInvite(token)
+	if err != nil {
+		return nil, err
+	}
+
+	conversation := &model.Conversation{}
+	if err := s.chatDAO.DB.First(conversation, "id = ?", invite.ConversationID).Error; err != nil {
+		return nil, err
+	}
+
+	memberCount, err := s.chatDAO.CountMembers(invite.ConversationID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.InvitePreview{
+		ConversationID:   conversation.ID,
+		Title:            conversation.Title,
+		MemberCount:      memberCount,
+		RequiresApproval: invite.RequiresApproval,
+		ExpiresAt:        invite.ExpiresAt,
+	}, nil
+}
+
+// JoinWithInvite adds the current user to the conversation of the invite, or
+// files a join request when the invite needs an admin's approval. Both count
+// as one use of the invite.
+func (s *inviteController) JoinWithInvite(token string) (*model.JoinResult, error) {
+	userId := utils.GetCurrentUserID(s.ctx)
+	if userId == "" {
+		return nil, utils.ErrUnauthenticated
+	}
+
+	invite, err := s.usableInvite(token)
+	if err != nil {
+		return nil, err
+	}
+
+	isMember, err := s.chatDAO.IsConversationMember(invite.ConversationID, userId)
+	if err != nil {
+		return nil, err
+	}
+	if isMember {
+		conversation, err := s.chatDAO.GetConversationSummary(invite.ConversationID)
+		if err != nil {
+			return nil, err
+		}
+		return &model.JoinResult{Status: model.JoinRequestStatusApproved, Conversation: conversation}, nil
+	}
+
+	if invite.RequiresApproval {
+		pending, err := s.inviteDAO.GetPendingJoinRequest(invite.ConversationID, userId)
+		if err == nil {
+			return &model.JoinResult{Status: model.JoinRequestStatusPending, Request: pending}, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+
+	// the use is only counted when the user gets in or their request is
+	// filed, and nobody is told before both are stored
+	var request *model.JoinRequest
+	var conversation *model.Conversation
+	var added *model.User
+	err = s.inviteDAO.DB.Transaction(func(tx *gorm.DB) error {
+		inviteDAO := dao.NewInviteDAO(tx)
+		consumed, err := inviteDAO.ConsumeInvite(invite.ID, time.Now())
+		if err != nil {
+			return err
+		}
+		if !consumed {
+			return ErrInviteUnavailable
+		}
+
+		if invite.RequiresApproval {
+			request = &model.JoinRequest{
+				ID:             uuid.New().String(),
+				ConversationID: invite.ConversationID,
+				UserID:         userId,
+				InviteID:       invite.ID,
+				Status:         model.JoinRequestStatusPending,
+			}
+			created, err := inviteDAO.CreateJoinRequest(request)
+			if err != nil {
+				return err
+			}
+			if !created {
+				return errJoinRequestPending
+			}
+			return nil
+		}
+
+		chatController := newChatController(tx)
+		chatController.SetContext(s.ctx)
+		conversation, added, err = chatController.addMember(invite.ConversationID, userId)
+		return err
+	})
+	if errors.Is(err, errJoinRequestPending) {
+		// a concurrent join of the same user filed it first
+		pending, err := s.inviteDAO.GetPendingJoinRequest(invite.ConversationID, userId)
+		if err != nil {
+			return nil, err
+		}
+		return &model.JoinResult{Status: model.JoinRequestStatusPending, Request: pending}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if request != nil {
+		s.notifyAdmins(invite.ConversationID, model.EventTypeJoinRequested, request)
+		return &model.JoinResult{Status: model.JoinRequestStatusPending, Request: request}, nil
+	}
+
+	if added != nil {
+		newChatController(s.chatDAO.DB).publishConversationEvent(invite.ConversationID, model.EventTypeMemberAdded, added)
+	}
+	return &model.JoinResult{Status: model.JoinRequestStatusApproved, Conversation: conversation}, nil
+}
+
+func (s *inviteController) GetJoinRequests(conversationID string) ([]*model.JoinRequest, error) {
+	userId := utils.GetCurrentUserID(s.ctx)
+	if err := requireConversationAdmin(s.chatDAO, conversationID, userId); err != nil {
+		return nil, err
+	}
+
+	return s.inviteDAO.GetPendingJoinRequests(conversationID)
+}
+
+func (s *inviteController) ApproveJoinRequest(id string) (*model.JoinRequest, error) {
+	var added *model.User
+	request, err := s.reviewJoinRequest(id, model.JoinRequestStatusApproved, func(tx *gorm.DB, request *model.JoinRequest) error {
+		chatController := newChatController(tx)
+		chatController.SetContext(s.ctx)
+
+		var err error
+		_, added, err = chatController.addMember(request.ConversationID, request.UserID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if added != nil {
+		newChatController(s.chatDAO.DB).publishConversationEvent(request.ConversationID, model.EventTypeMemberAdded, added)
+	}
+	return request, nil
+}
+
+func (s *inviteController) RejectJoinRequest(id string) (*model.JoinRequest, error) {
+	return s.reviewJoinRequest(id, model.JoinRequestStatusRejected, nil)
+}
+
+// reviewJoinRequest stores the review together with what apply does, so an
+// approval is never stored without its member. Nobody is told before both
+// are committed.
+func (s *inviteController) reviewJoinRequest(id string, status model.JoinRequestStatus, apply func(tx *gorm.DB, request *model.JoinRequest) error) (*model.JoinRequest, error) {
+	request, err := s.inviteDAO.GetJoinRequestByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	userId := utils.GetCurrentUserID(s.ctx)
+	if err := requireConversationAdmin(s.chatDAO, request.ConversationID, userId); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	err = s.inviteDAO.DB.Transaction(func(tx *gorm.DB) error {
+		reviewed, err := dao.NewInviteDAO(tx).ReviewJoinRequest(id, status, userId, now)
+		if err != nil {
+			return err
+		}
+		if !reviewed {
+			return utils.ErrConflict
+		}
+
+		if apply == nil {
+			return nil
+		}
+		return apply(tx, request)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	request.Status = status
+	request.ReviewedByID = &userId
+	request.ReviewedAt = &now
+
+	eventHub.Publish(UserStream(request.UserID), model.EventTypeJoinRequestReviewed, request.ConversationID, request)
+	s.notifyAdmins(request.ConversationID, model.EventTypeJoinRequestReviewed, request)
+	return request, nil
+}
+
+// usableInvite looks the invite up and checks it can still be used. The use
+// count is checked again atomically when the invite is consumed.
+func (s *inviteController) usableInvite(token string) (*model.ConversationInvite, error) {
+	invite, err := s.inviteDAO.GetInviteByToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if invite.Revoked ||
+		(invite.ExpiresAt != nil && !invite.ExpiresAt.After(time.Now())) ||
+		(invite.MaxUses != nil && invite.UseCount >= *invite.MaxUses) {
+		return nil, ErrInviteUnavailable
+	}
+
+	return invite, nil
+}
+
+func (s *inviteController) notifyAdmins(conversationID string, eventType model.EventType, payload any) {
+	adminIDs, err := s.chatDAO.GetConversationAdminIDs(conversationID)
+	if err != nil {
+		return
+	}
+
+	for _, adminID := range adminIDs {
+		eventHub.Publish(UserStream(adminID), eventType, conversationID, payload)
+	}
+}
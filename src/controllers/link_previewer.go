@@ -0,0 +1,160 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/badaccuracyid/softeng_backend/src/model"
+	"github.com/badaccuracyid/softeng_backend/src/unfurl"
+	"gorm.io/gorm"
+)
+
+const maxLinkPreviews = 3
+
+// bareURLPattern finds the links typed straight into a message, the
+// punctuation that usually follows a link in a sentence is trimmed after.
+var bareURLPattern = regexp.MustCompile(`https?://[^\s<>"'` + "`" + `]+`)
+
+// linkPreviewer is the one Run started, messages sent while there is none
+// get no previews.
+var linkPreviewer *LinkPreviewer
+
+// LinkPreviewer fetches the previews of the links in new TEXT messages in
+// the background, then pushes the message again with its previews.
+type LinkPreviewer struct {
+	db       *gorm.DB
+	unfurler *unfurl.Unfurler
+	queue    chan *model.Message
+}
+
+func NewLinkPreviewer(db *gorm.DB, unfurler *unfurl.Unfurler, queueSize int) *LinkPreviewer {
+	return &LinkPreviewer{
+		db:       db,
+		unfurler: unfurler,
+		queue:    make(chan *model.Message, queueSize),
+	}
+}
+
+// Run starts taking the messages senders queue with as many workers, it
+// never returns.
+func (p *LinkPreviewer) Run(workers int) {
+	linkPreviewer = p
+	for i := 1; i < workers; i++ {
+		go p.work()
+	}
+	p.work()
+}
+
+// Enqueue hands the message over to the workers when it has links. It never
+// blocks the sender, a message is left without previews when the queue is
+// full.
+func (p *LinkPreviewer) Enqueue(message *model.Message) {
+	if message.ContentType != model.MessageContentTypeText || len(messageURLs(message)) == 0 {
+		return
+	}
+
+	select {
+	case p.queue <- message:
+	default:
+		log.Printf("link previewer: queue full, skipping message %s", message.ID)
+	}
+}
+
+func (p *LinkPreviewer) work() {
+	for message := range p.queue {
+		if err := p.Preview(context.Background(), message); err != nil {
+			log.Printf("link previewer: message %s: %v", message.ID, err)
+		}
+	}
+}
+
+// Preview fetches the previews of the message's links, stores the ones that
+// worked and tells the members. Links without a preview are skipped.
+func (p *LinkPreviewer) Preview(ctx context.Context, message *model.Message) error {
+	previews := []*model.LinkPreview{}
+	for _, link := range messageURLs(message) {
+		preview, err := p.unfurler.Unfurl(ctx, link)
+		if err != nil {
+			continue
+		}
+		previews = append(previews, preview)
+	}
+	if len(previews) == 0 {
+		return nil
+	}
+
+	controller := newChatController(p.db)
+	err := controller.chatDAO.SaveLinkPreviews(message.ID, previews)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	updated, err := controller.chatDAO.GetMessageByID(message.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	controller.publishConversationEvent(updated.ConversationID, model.EventTypeMessageUpdated, updated)
+	return nil
+}
+
+// queueLinkPreviews is called for every message sent.
+func queueLinkPreviews(message *model.Message) {
+	if linkPreviewer != nil {
+		linkPreviewer.Enqueue(message)
+	}
+}
+
+// messageURLs lists the distinct links of a message in the order they
+// appear, the ones inside code excepted.
+func messageURLs(message *model.Message) []string {
+	urls := []string{}
+	seen := make(map[string]bool)
+	add := func(link string) {
+		if len(urls) < maxLinkPreviews && !seen[link] {
+			seen[link] = true
+			urls = append(urls, link)
+		}
+	}
+
+	addBare := func(text string) {
+		for _, link := range bareURLPattern.FindAllString(text, -1) {
+			add(strings.TrimRight(link, ".,;:!?)]}"))
+		}
+	}
+
+	if message.Formatted != nil {
+		var walk func(node *model.FormattedNode)
+		walk = func(node *model.FormattedNode) {
+			switch node.Type {
+			case model.FormattedNodeTypeLink:
+				if !strings.HasPrefix(node.URL, "mailto:") {
+					add(node.URL)
+				}
+			case model.FormattedNodeTypeText:
+				addBare(node.Text)
+			}
+			// links in code are not meant to be followed
+			if node.Type == model.FormattedNodeTypeCode || node.Type == model.FormattedNodeTypeCodeBlock {
+				return
+			}
+			for _, child := range node.Children {
+				walk(child)
+			}
+		}
+		walk(message.Formatted)
+		return urls
+	}
+
+	addBare(message.Content)
+	return urls
+}
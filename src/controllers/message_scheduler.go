@@ -0,0 +1,124 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/badaccuracyid/softeng_backend/src/database/dao"
+	"github.com/badaccuracyid/softeng_backend/src/model"
+	"gorm.io/gorm"
+)
+
+// MessageScheduler delivers the scheduled messages that are due. Every
+// message is sent in its own transaction that also locks and marks the
+// scheduled row, so several instances can run side by side: a row locked by
+// one of them is skipped by the others, and once the transaction commits the
+// row is no longer pending. The events of a message only go out once its
+// transaction committed, so clients never see a message that was rolled back.
+type MessageScheduler struct {
+	db        *gorm.DB
+	interval  time.Duration
+	batchSize int
+}
+
+func NewMessageScheduler(db *gorm.DB, interval time.Duration, batchSize int) *MessageScheduler {
+	return &MessageScheduler{
+		db:        db,
+		interval:  interval,
+		batchSize: batchSize,
+	}
+}
+
+// Run delivers due messages every interval, it never returns.
+func (s *MessageScheduler) Run() {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.DeliverDue()
+	}
+}
+
+// DeliverDue sends up to one batch of due messages and tells how many were
+// handled, sent or failed.
+func (s *MessageScheduler) DeliverDue() int {
+	handled := 0
+	for handled < s.batchSize {
+		found, err := s.deliverNext()
+		if err != nil {
+			log.Printf("message scheduler: %v", err)
+			break
+		}
+		if !found {
+			break
+		}
+		handled++
+	}
+	return handled
+}
+
+func (s *MessageScheduler) deliverNext() (bool, error) {
+	var failed *model.ScheduledMessage
+	var delivered *model.Message
+	found := false
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		scheduledDAO := dao.NewScheduledMessageDAO(tx)
+		scheduled, err := scheduledDAO.LockNextDue(time.Now())
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		found = true
+
+		// the normal send path, so membership and channel rules are checked
+		// again and the message is fanned out like any other
+		if err := tx.SavePoint("deliver").Error; err != nil {
+			return err
+		}
+		message, stored, sendErr := newChatController(tx).storeMessageAs(model.SendMessageInput{
+			SenderID:       scheduled.SenderID,
+			ConversationID: scheduled.ConversationID,
+			Content:        scheduled.Content,
+			ContentType:    scheduled.ContentType,
+			Format:         scheduled.Format,
+			// should the row be picked again, the message is not sent twice
+			ClientMessageID: "scheduled:" + scheduled.ID,
+		})
+		if sendErr != nil {
+			if err := tx.RollbackTo("deliver").Error; err != nil {
+				return err
+			}
+
+			scheduled.Status = model.ScheduledMessageStatusFailed
+			scheduled.Error = sendErr.Error()
+			failed = scheduled
+			return tx.Model(scheduled).Updates(map[string]interface{}{
+				"status": scheduled.Status,
+				"error":  scheduled.Error,
+			}).Error
+		}
+
+		if stored {
+			delivered = message
+		}
+		return tx.Model(scheduled).Updates(map[string]interface{}{
+			"status":     model.ScheduledMessageStatusSent,
+			"message_id": message.ID,
+		}).Error
+	})
+	if err != nil {
+		return false, err
+	}
+
+	if delivered != nil {
+		newChatController(s.db).publishMessage(delivered)
+	}
+	if failed != nil {
+		eventHub.Publish(UserStream(failed.SenderID), model.EventTypeScheduledFailed, failed.ConversationID, failed)
+	}
+	return found, nil
+}
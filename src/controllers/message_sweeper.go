@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MessageSweeper hard-deletes disappearing messages once they expired,
+// together with the attachments nothing else uses, and tells the members. It
+// also forgets client message IDs older than their retention window.
+// Instances running side by side skip the rows the others are deleting.
+type MessageSweeper struct {
+	db        *gorm.DB
+	interval  time.Duration
+	batchSize int
+}
+
+func NewMessageSweeper(db *gorm.DB, interval time.Duration, batchSize int) *MessageSweeper {
+	return &MessageSweeper{
+		db:        db,
+		interval:  interval,
+		batchSize: batchSize,
+	}
+}
+
+// Run sweeps every interval, it never returns.
+func (s *MessageSweeper) Run() {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.Sweep()
+	}
+}
+
+// Sweep deletes expired messages a batch at a time until none are left and
+// tells how many were deleted. Client message IDs past their retention window
+// go as well.
+func (s *MessageSweeper) Sweep() int {
+	controller := newChatController(s.db)
+
+	cutoff := time.Now().Add(-controller.idempotencyRetention)
+	if err := controller.chatDAO.DeleteClientMessageIDsBefore(cutoff); err != nil {
+		log.Printf("message sweeper: %v", err)
+	}
+
+	total := 0
+	for {
+		deleted, err := controller.chatDAO.DeleteExpiredMessages(time.Now(), s.batchSize)
+		if err != nil {
+			log.Printf("message sweeper: %v", err)
+			return total
+		}
+
+		controller.messagesDeleted(deleted)
+		total += len(deleted.Messages)
+		if len(deleted.Messages) < s.batchSize {
+			return total
+		}
+	}
+}
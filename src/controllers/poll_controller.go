@@ -0,0 +1,300 @@
+package controllers
+
+import (
+	"strings"
+	"time"
+
+	"github.com/badaccuracyid/softeng_backend/src/database/dao"
+	"github.com/badaccuracyid/softeng_backend/src/model"
+	"github.com/badaccuracyid/softeng_backend/src/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	minPollOptions          = 2
+	maxPollOptions          = 10
+	maxPollQuestionLength   = 300
+	maxPollOptionTextLength = 100
+)
+
+type PollController interface {
+	SetContext(ctx *gin.Context)
+
+	Vote(messageID string, input model.VotePollInput) (*model.PollResults, error)
+	ClosePoll(messageID string) (*model.PollResults, error)
+	GetResults(messageID string) (*model.PollResults, error)
+}
+
+type pollController struct {
+	ctx     *gin.Context
+	pollDAO *dao.PollDAO
+	chatDAO *dao.ChatDAO
+}
+
+func NewPollController(db *gorm.DB) PollController {
+	return &pollController{
+		pollDAO: dao.NewPollDAO(db),
+		chatDAO: dao.NewChatDAO(db),
+	}
+}
+
+func (s *pollController) SetContext(ctx *gin.Context) {
+	s.ctx = ctx
+}
+
+// Vote replaces the current user's vote until the poll closes, and streams
+// how the totals moved to the members.
+func (s *pollController) Vote(messageID string, input model.VotePollInput) (*model.PollResults, error) {
+	userId := utils.GetCurrentUserID(s.ctx)
+	message, poll, err := s.memberPoll(messageID, userId)
+	if err != nil {
+		return nil, err
+	}
+	if poll.IsClosed(time.Now()) {
+		return nil, utils.ErrConflict
+	}
+
+	optionIDs := uniqueStrings(input.OptionIDs)
+	if len(optionIDs) > 1 && !poll.MultipleChoice {
+		return nil, utils.ErrInvalidInput
+	}
+	for _, optionID := range optionIDs {
+		if !pollHasOption(poll, optionID) {
+			return nil, utils.ErrInvalidInput
+		}
+	}
+
+	var delta *model.PollDelta
+	err = s.pollDAO.ReplaceVote(messageID, userId, optionIDs, func(tx *dao.PollDAO, previous []string) error {
+		// the poll may have been closed while the vote waited for the lock
+		current, err := tx.GetPoll(messageID)
+		if err != nil {
+			return err
+		}
+		if current.IsClosed(time.Now()) {
+			return utils.ErrConflict
+		}
+
+		counts, err := tx.CountVotes(messageID)
+		if err != nil {
+			return err
+		}
+		totalVoters, err := tx.CountVoters(messageID)
+		if err != nil {
+			return err
+		}
+
+		delta = &model.PollDelta{MessageID: messageID, TotalVoters: totalVoters, Deltas: []*model.PollOptionDelta{}}
+		for _, option := range poll.Options {
+			change := int64(0)
+			if containsString(optionIDs, option.ID) {
+				change++
+			}
+			if containsString(previous, option.ID) {
+				change--
+			}
+			if change != 0 {
+				delta.Deltas = append(delta.Deltas, &model.PollOptionDelta{
+					OptionID: option.ID,
+					Delta:    change,
+					Votes:    counts[option.ID],
+				})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(delta.Deltas) > 0 {
+		if !poll.Anonymous {
+			delta.VoterID = userId
+			delta.VoterOptionIDs = optionIDs
+		}
+		s.publishPollEvent(message.ConversationID, delta)
+	}
+
+	return s.results(poll, userId)
+}
+
+// ClosePoll stops the voting early, only the author of the poll or an admin
+// of the conversation can close it.
+func (s *pollController) ClosePoll(messageID string) (*model.PollResults, error) {
+	userId := utils.GetCurrentUserID(s.ctx)
+	message, poll, err := s.memberPoll(messageID, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if message.SenderID != userId {
+		if err := requireConversationAdmin(s.chatDAO, message.ConversationID, userId); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	if poll.IsClosed(now) {
+		return nil, utils.ErrConflict
+	}
+	closed, err := s.pollDAO.ClosePoll(messageID, now)
+	if err != nil {
+		return nil, err
+	}
+	if !closed {
+		return nil, utils.ErrConflict
+	}
+	poll.ClosedAt = &now
+
+	results, err := s.results(poll, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	s.publishPollEvent(message.ConversationID, &model.PollDelta{
+		MessageID:   messageID,
+		TotalVoters: results.TotalVoters,
+		Closed:      true,
+		Deltas:      []*model.PollOptionDelta{},
+	})
+	return results, nil
+}
+
+func (s *pollController) GetResults(messageID string) (*model.PollResults, error) {
+	userId := utils.GetCurrentUserID(s.ctx)
+	_, poll, err := s.memberPoll(messageID, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.results(poll, userId)
+}
+
+func (s *pollController) results(poll *model.Poll, userID string) (*model.PollResults, error) {
+	votes, err := s.pollDAO.GetVotes(poll.MessageID)
+	if err != nil {
+		return nil, err
+	}
+
+	results := &model.PollResults{
+		MessageID:   poll.MessageID,
+		Closed:      poll.IsClosed(time.Now()),
+		Options:     make([]*model.PollOptionResults, len(poll.Options)),
+		MyOptionIDs: []string{},
+	}
+
+	byOption := make(map[string]*model.PollOptionResults, len(poll.Options))
+	for i, option := range poll.Options {
+		results.Options[i] = &model.PollOptionResults{OptionID: option.ID}
+		byOption[option.ID] = results.Options[i]
+	}
+
+	voters := make(map[string]bool)
+	for _, vote := range votes {
+		option, found := byOption[vote.OptionID]
+		if !found {
+			continue
+		}
+
+		option.Votes++
+		voters[vote.UserID] = true
+		if !poll.Anonymous {
+			option.VoterIDs = append(option.VoterIDs, vote.UserID)
+		}
+		if vote.UserID == userID {
+			results.MyOptionIDs = append(results.MyOptionIDs, vote.OptionID)
+		}
+	}
+	results.TotalVoters = int64(len(voters))
+
+	return results, nil
+}
+
+// memberPoll loads the poll of the message if the user is a member of its
+// conversation.
+func (s *pollController) memberPoll(messageID string, userID string) (*model.Message, *model.Poll, error) {
+	if userID == "" {
+		return nil, nil, utils.ErrUnauthenticated
+	}
+
+	message, err := s.chatDAO.GetMessageByID(messageID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if message.Poll == nil {
+		return nil, nil, utils.ErrNotFound
+	}
+
+	isMember, err := s.chatDAO.IsConversationMember(message.ConversationID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !isMember {
+		return nil, nil, utils.ErrForbidden
+	}
+
+	return message, message.Poll, nil
+}
+
+func (s *pollController) publishPollEvent(conversationID string, delta *model.PollDelta) {
+	chatController := newChatController(s.chatDAO.DB)
+	chatController.publishConversationEvent(conversationID, model.EventTypePollUpdated, delta)
+}
+
+// newPoll builds the poll of a new POLL message from the sender's input.
+func newPoll(input *model.CreatePollInput) (*model.Poll, error) {
+	if input == nil {
+		return nil, utils.ErrInvalidInput
+	}
+
+	question := strings.TrimSpace(input.Question)
+	if question == "" || len(question) > maxPollQuestionLength {
+		return nil, utils.ErrInvalidInput
+	}
+	if len(input.Options) < minPollOptions || len(input.Options) > maxPollOptions {
+		return nil, utils.ErrInvalidInput
+	}
+	if input.ClosesAt != nil && !input.ClosesAt.After(time.Now()) {
+		return nil, utils.ErrInvalidInput
+	}
+
+	poll := &model.Poll{
+		Question:       question,
+		MultipleChoice: input.MultipleChoice,
+		Anonymous:      input.Anonymous,
+		ClosesAt:       input.ClosesAt,
+	}
+	for i, text := range input.Options {
+		text = strings.TrimSpace(text)
+		if text == "" || len(text) > maxPollOptionTextLength {
+			return nil, utils.ErrInvalidInput
+		}
+		poll.Options = append(poll.Options, &model.PollOption{
+			ID:       uuid.New().String(),
+			Position: i,
+			Text:     text,
+		})
+	}
+
+	return poll, nil
+}
+
+func pollHasOption(poll *model.Poll, optionID string) bool {
+	for _, option := range poll.Options {
+		if option.ID == optionID {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+package controllers
+
+import (
+	"sync"
+	"time"
+)
+
+// PresenceTracker counts the live realtime connections of every user across
+// all of their devices. A user is online while that count is above zero.
+type PresenceTracker struct {
+	mutex        sync.Mutex
+	connections  map[string]int
+	expiryTimers map[string]*time.Timer
+}
+
+var presenceTracker = NewPresenceTracker()
+
+func NewPresenceTracker() *PresenceTracker {
+	return &PresenceTracker{
+		connections:  make(map[string]int),
+		expiryTimers: make(map[string]*time.Timer),
+	}
+}
+
+// Connect records a new connection and reports whether the user just came
+// online.
+func (p *PresenceTracker) Connect(userID string) bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.connections[userID]++
+	return p.connections[userID] == 1
+}
+
+// Disconnect releases a connection and reports whether it was the user's
+// last one.
+func (p *PresenceTracker) Disconnect(userID string) bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	count, found := p.connections[userID]
+	if !found {
+		return false
+	}
+
+	if count <= 1 {
+		delete(p.connections, userID)
+		return true
+	}
+
+	p.connections[userID] = count - 1
+	return false
+}
+
+func (p *PresenceTracker) IsOnline(userID string) bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.connections[userID] > 0
+}
+
+func (p *PresenceTracker) ConnectionCount(userID string) int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.connections[userID]
+}
+
+// ScheduleExpiry runs onExpire when the user's explicit status expires,
+// replacing any previously scheduled expiry. A nil expiresAt only cancels.
+func (p *PresenceTracker) ScheduleExpiry(userID string, expiresAt *time.Time, onExpire func()) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if timer, found := p.expiryTimers[userID]; found {
+		timer.Stop()
+		delete(p.expiryTimers, userID)
+	}
+
+	if expiresAt == nil {
+		return
+	}
+
+	var timer *time.Timer
+	timer = time.AfterFunc(time.Until(*expiresAt), func() {
+		p.mutex.Lock()
+		if p.expiryTimers[userID] == timer {
+			delete(p.expiryTimers, userID)
+		}
+		p.mutex.Unlock()
+
+		onExpire()
+	})
+	p.expiryTimers[userID] = timer
+}
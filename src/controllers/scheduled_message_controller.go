@@ -0,0 +1,196 @@
+package controllers
+
+import (
+	"time"
+
+	"github.com/badaccuracyid/softeng_backend/src/database/dao"
+	"github.com/badaccuracyid/softeng_backend/src/model"
+	"github.com/badaccuracyid/softeng_backend/src/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	maxPendingScheduledMessages = 100
+	maxScheduleAhead            = 365 * 24 * time.Hour
+)
+
+type ScheduledMessageController interface {
+	SetContext(ctx *gin.Context)
+
+	ScheduleMessage(input model.ScheduleMessageInput) (*model.ScheduledMessage, error)
+	GetScheduledMessages(conversationID string) ([]*model.ScheduledMessage, error)
+	UpdateScheduledMessage(id string, input model.UpdateScheduledMessageInput) (*model.ScheduledMessage, error)
+	CancelScheduledMessage(id string) (*model.ScheduledMessage, error)
+}
+
+type scheduledMessageController struct {
+	ctx          *gin.Context
+	scheduledDAO *dao.ScheduledMessageDAO
+	chatDAO      *dao.ChatDAO
+}
+
+func NewScheduledMessageController(db *gorm.DB) ScheduledMessageController {
+	return &scheduledMessageController{
+		scheduledDAO: dao.NewScheduledMessageDAO(db),
+		chatDAO:      dao.NewChatDAO(db),
+	}
+}
+
+func (s *scheduledMessageController) SetContext(ctx *gin.Context) {
+	s.ctx = ctx
+}
+
+// ScheduleMessage stores the message for the scheduler. Permissions are
+// checked now to fail early and again when it is delivered, since they can
+// change in between.
+func (s *scheduledMessageController) ScheduleMessage(input model.ScheduleMessageInput) (*model.ScheduledMessage, error) {
+	userId := utils.GetCurrentUserID(s.ctx)
+	if userId == "" {
+		return nil, utils.ErrUnauthenticated
+	}
+
+	scheduled := &model.ScheduledMessage{
+		ID:             uuid.New().String(),
+		SenderID:       userId,
+		ConversationID: input.ConversationID,
+		Content:        input.Content,
+		ContentType:    input.ContentType,
+		Format:         input.Format,
+		SendAt:         input.SendAt,
+		Status:         model.ScheduledMessageStatusPending,
+	}
+	if scheduled.Format == "" {
+		scheduled.Format = model.MessageFormatPlain
+	}
+	if err := validateScheduledContent(scheduled); err != nil {
+		return nil, err
+	}
+
+	if _, err := newChatController(s.chatDAO.DB).postableConversation(input.ConversationID, userId); err != nil {
+		return nil, err
+	}
+
+	pending, err := s.scheduledDAO.CountPendingForUser(userId)
+	if err != nil {
+		return nil, err
+	}
+	if pending >= maxPendingScheduledMessages {
+		return nil, utils.ErrConflict
+	}
+
+	if err := s.scheduledDAO.CreateScheduledMessage(scheduled); err != nil {
+		return nil, err
+	}
+
+	return scheduled, nil
+}
+
+func (s *scheduledMessageController) GetScheduledMessages(conversationID string) ([]*model.ScheduledMessage, error) {
+	userId := utils.GetCurrentUserID(s.ctx)
+	if userId == "" {
+		return nil, utils.ErrUnauthenticated
+	}
+
+	return s.scheduledDAO.GetPendingForUser(userId, conversationID)
+}
+
+func (s *scheduledMessageController) UpdateScheduledMessage(id string, input model.UpdateScheduledMessageInput) (*model.ScheduledMessage, error) {
+	scheduled, err := s.ownScheduledMessage(id)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := map[string]interface{}{}
+	if input.Content != nil {
+		scheduled.Content = *input.Content
+		columns["content"] = scheduled.Content
+	}
+	if input.ContentType != nil {
+		scheduled.ContentType = *input.ContentType
+		columns["content_type"] = scheduled.ContentType
+	}
+	if input.Format != nil {
+		scheduled.Format = *input.Format
+		columns["format"] = scheduled.Format
+	}
+	if input.SendAt != nil {
+		scheduled.SendAt = *input.SendAt
+		columns["send_at"] = scheduled.SendAt
+	}
+
+	if err := validateScheduledContent(scheduled); err != nil {
+		return nil, err
+	}
+	if len(columns) == 0 {
+		return scheduled, nil
+	}
+
+	// the user may have left the conversation, or lost the right to post in
+	// the channel, since the message was scheduled
+	if _, err := newChatController(s.chatDAO.DB).postableConversation(scheduled.ConversationID, scheduled.SenderID); err != nil {
+		return nil, err
+	}
+
+	return s.updatePending(scheduled, columns)
+}
+
+func (s *scheduledMessageController) CancelScheduledMessage(id string) (*model.ScheduledMessage, error) {
+	scheduled, err := s.ownScheduledMessage(id)
+	if err != nil {
+		return nil, err
+	}
+
+	scheduled.Status = model.ScheduledMessageStatusCancelled
+	return s.updatePending(scheduled, map[string]interface{}{"status": scheduled.Status})
+}
+
+// updatePending saves the changes unless the scheduler already delivered the
+// message, which is a conflict rather than a silent no-op.
+func (s *scheduledMessageController) updatePending(scheduled *model.ScheduledMessage, columns map[string]interface{}) (*model.ScheduledMessage, error) {
+	updated, err := s.scheduledDAO.UpdatePending(scheduled.ID, columns)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, utils.ErrConflict
+	}
+
+	return scheduled, nil
+}
+
+func (s *scheduledMessageController) ownScheduledMessage(id string) (*model.ScheduledMessage, error) {
+	userId := utils.GetCurrentUserID(s.ctx)
+	if userId == "" {
+		return nil, utils.ErrUnauthenticated
+	}
+
+	scheduled, err := s.scheduledDAO.GetScheduledMessageByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if scheduled.SenderID != userId {
+		return nil, utils.ErrNotFound
+	}
+	if scheduled.Status != model.ScheduledMessageStatusPending {
+		return nil, utils.ErrConflict
+	}
+	return scheduled, nil
+}
+
+func validateScheduledContent(scheduled *model.ScheduledMessage) error {
+	contentType := scheduled.ContentType
+	if !contentType.IsValid() || contentType == model.MessageContentTypeSystem || contentType == model.MessageContentTypePoll {
+		return utils.ErrInvalidInput
+	}
+	if _, err := formatContent(contentType, scheduled.Format, scheduled.Content); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if !scheduled.SendAt.After(now) || scheduled.SendAt.After(now.Add(maxScheduleAhead)) {
+		return utils.ErrInvalidInput
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package controllers
+
+import (
+	"sync"
+	"time"
+
+	"github.com/badaccuracyid/softeng_backend/src/model"
+)
+
+const (
+	// typingExpiry is how long a typing indicator stays on without being
+	// refreshed, so a client that disappears mid-typing is cleared.
+	typingExpiry = 6 * time.Second
+
+	typingRateWindow = 10 * time.Second
+	typingRateLimit  = 20
+)
+
+// TypingTracker keeps the in-memory typing state of every conversation.
+// Nothing here is persisted, signals only go out through the event hub.
+type TypingTracker struct {
+	mutex   sync.Mutex
+	hub     *EventHub
+	expiry  time.Duration
+	timers  map[typingKey]*time.Timer
+	windows map[string]*typingRateWindowState
+	// lastSweep is when the ended rate windows were last dropped
+	lastSweep time.Time
+}
+
+type typingKey struct {
+	conversationID string
+	userID         string
+}
+
+type typingRateWindowState struct {
+	startedAt time.Time
+	count     int
+}
+
+var typingTracker = NewTypingTracker(eventHub)
+
+func NewTypingTracker(hub *EventHub) *TypingTracker {
+	return &TypingTracker{
+		hub:       hub,
+		expiry:    typingExpiry,
+		timers:    make(map[typingKey]*time.Timer),
+		windows:   make(map[string]*typingRateWindowState),
+		lastSweep: time.Now(),
+	}
+}
+
+// Start marks the user as typing. Repeated calls only refresh the expiry, the
+// other subscribers are notified once per typing session. Callers check
+// Allow first.
+func (t *TypingTracker) Start(conversationID string, userID string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	key := typingKey{conversationID: conversationID, userID: userID}
+	timer, typing := t.timers[key]
+	if typing && timer.Stop() {
+		timer.Reset(t.expiry)
+		return
+	}
+
+	// a timer that fired already is waiting for the lock to expire the
+	// session, it gets replaced so it finds out it is stale
+	var expiry *time.Timer
+	expiry = time.AfterFunc(t.expiry, func() {
+		t.mutex.Lock()
+		defer t.mutex.Unlock()
+
+		// replaced by a refresh, or stopped, while waiting for the lock
+		if t.timers[key] != expiry {
+			return
+		}
+		delete(t.timers, key)
+		t.signal(key, model.EventTypeTypingStopped)
+	})
+	t.timers[key] = expiry
+	if !typing {
+		t.signal(key, model.EventTypeTypingStarted)
+	}
+}
+
+func (t *TypingTracker) Stop(conversationID string, userID string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	key := typingKey{conversationID: conversationID, userID: userID}
+	timer, found := t.timers[key]
+	if !found {
+		return
+	}
+
+	timer.Stop()
+	delete(t.timers, key)
+	t.signal(key, model.EventTypeTypingStopped)
+}
+
+func (t *TypingTracker) signal(key typingKey, eventType model.EventType) {
+	payload := &model.TypingPayload{UserID: key.userID}
+	t.hub.Signal(ConversationStream(key.conversationID), eventType, key.conversationID, payload, key.userID)
+}
+
+// Allow applies a fixed window rate limit per user across all conversations.
+func (t *TypingTracker) Allow(userID string) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	now := time.Now()
+	t.dropEndedWindows(now)
+
+	window, found := t.windows[userID]
+	if !found || now.Sub(window.startedAt) >= typingRateWindow {
+		t.windows[userID] = &typingRateWindowState{startedAt: now, count: 1}
+		return true
+	}
+
+	if window.count >= typingRateLimit {
+		return false
+	}
+
+	window.count++
+	return true
+}
+
+// dropEndedWindows forgets the users whose window ended, at most once per
+// window so the limit stays cheap.
+func (t *TypingTracker) dropEndedWindows(now time.Time) {
+	if now.Sub(t.lastSweep) < typingRateWindow {
+		return
+	}
+	t.lastSweep = now
+
+	for userID, window := range t.windows {
+		if now.Sub(window.startedAt) >= typingRateWindow {
+			delete(t.windows, userID)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/badaccuracyid/softeng_backend/src/model"
+)
+
+func newTypingTest(t *testing.T, expiry time.Duration) (*TypingTracker, chan *model.Event) {
+	hub := NewEventHub()
+	tracker := NewTypingTracker(hub)
+	tracker.expiry = expiry
+
+	subscription, _ := hub.Subscribe(ConversationStream("conversation"), "watcher", 0)
+	t.Cleanup(func() { hub.Unsubscribe(subscription) })
+	return tracker, subscription.EventChannel
+}
+
+func expectSignals(t *testing.T, events chan *model.Event, wait time.Duration, eventTypes ...model.EventType) {
+	t.Helper()
+	timeout := time.After(wait)
+	var got []model.EventType
+	for {
+		select {
+		case event := <-events:
+			got = append(got, event.Type)
+		case <-timeout:
+			if len(got) != len(eventTypes) {
+				t.Fatalf("signals = %v, want %v", got, eventTypes)
+			}
+			for i := range got {
+				if got[i] != eventTypes[i] {
+					t.Fatalf("signals = %v, want %v", got, eventTypes)
+				}
+			}
+			return
+		}
+	}
+}
+
+func TestTypingExpires(t *testing.T) {
+	tracker, events := newTypingTest(t, 20*time.Millisecond)
+
+	tracker.Start("conversation", "alice")
+	expectSignals(t, events, 60*time.Millisecond, model.EventTypeTypingStarted, model.EventTypeTypingStopped)
+}
+
+func TestTypingRefreshKeepsSession(t *testing.T) {
+	tracker, events := newTypingTest(t, 40*time.Millisecond)
+
+	for i := 0; i < 3; i++ {
+		tracker.Start("conversation", "alice")
+		time.Sleep(20 * time.Millisecond)
+	}
+	tracker.Stop("conversation", "alice")
+	expectSignals(t, events, 20*time.Millisecond, model.EventTypeTypingStarted, model.EventTypeTypingStopped)
+}
+
+func TestTypingStaleTimerIsIgnored(t *testing.T) {
+	tracker, events := newTypingTest(t, 10*time.Millisecond)
+	key := typingKey{conversationID: "conversation", userID: "alice"}
+
+	tracker.Start("conversation", "alice")
+
+	// the timer fires while the lock is held and the session is refreshed
+	// with a new timer, it must not end the refreshed session
+	tracker.mutex.Lock()
+	time.Sleep(30 * time.Millisecond)
+	refreshed := time.AfterFunc(time.Hour, func() {})
+	defer refreshed.Stop()
+	tracker.timers[key] = refreshed
+	tracker.mutex.Unlock()
+
+	expectSignals(t, events, 30*time.Millisecond, model.EventTypeTypingStarted)
+
+	tracker.mutex.Lock()
+	_, typing := tracker.timers[key]
+	tracker.mutex.Unlock()
+	if !typing {
+		t.Error("the stale timer ended the refreshed session")
+	}
+}
+
+func TestTypingRateWindowsAreDropped(t *testing.T) {
+	tracker := NewTypingTracker(NewEventHub())
+
+	for i := 0; i < typingRateLimit; i++ {
+		if !tracker.Allow("alice") {
+			t.Fatalf("signal %d was limited", i)
+		}
+	}
+	if tracker.Allow("alice") {
+		t.Error("the signal over the limit was allowed")
+	}
+
+	// once the window of alice ended, the next signal drops it
+	tracker.mutex.Lock()
+	tracker.windows["alice"].startedAt = time.Now().Add(-typingRateWindow)
+	tracker.lastSweep = time.Now().Add(-typingRateWindow)
+	tracker.mutex.Unlock()
+
+	if !tracker.Allow("bob") {
+		t.Fatal("bob was limited")
+	}
+	if _, found := tracker.windows["alice"]; found {
+		t.Error("the ended window of alice was kept")
+	}
+}
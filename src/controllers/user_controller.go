@@ -1,29 +1,74 @@
 package controllers
 
 import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"time"
+	"unicode/utf8"
+
 	"github.com/badaccuracyid/softeng_backend/src/database/dao"
 	"github.com/badaccuracyid/softeng_backend/src/model"
+	"github.com/badaccuracyid/softeng_backend/src/storage"
+	"github.com/badaccuracyid/softeng_backend/src/utils"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
+const (
+	maxDisplayNameLength = 64
+	maxBioLength         = 300
+	maxEmailLength       = 254
+	minPasswordLength    = 8
+	maxPasswordLength    = 128
+
+	minSearchQueryLength   = 2
+	maxSearchQueryLength   = 64
+	defaultSearchPageLimit = 20
+	maxSearchPageLimit     = 50
+)
+
 type UserController interface {
 	SetContext(ctx *gin.Context)
 	GetUserByID(id string) (*model.User, error)
 	GetUsersByID(ids []string) ([]*model.User, error)
-	UpdateUser(user model.User) (*model.User, error)
+	UpdateProfile(input model.UpdateProfileInput) (*model.User, error)
+	UploadAvatar(file *multipart.FileHeader) (*model.User, error)
+	GetAvatar(userID string, size int) (string, []byte, error)
+	ChangeEmail(input model.ChangeEmailInput) (*model.User, error)
+	ChangePassword(input model.ChangePasswordInput) error
 	CreateUser(user model.User) (*model.User, error)
+	CheckUsername(username string) (*model.UsernameAvailability, error)
+	LookupHandle(handle string) (*model.HandleLookup, error)
+	GetUsernameHistory() ([]*model.UsernameChange, error)
+	SearchUsers(query string, limit int, offset int) (*model.UserSearchPage, error)
 	Login(email string, password string) (*model.User, error)
+
+	ConnectPresence(userID string)
+	DisconnectPresence(userID string)
+	GetPresences(ids []string) ([]*model.Presence, error)
+	UpdateStatus(input model.UpdateStatusInput) (*model.Presence, error)
+	UpdatePrivacy(input model.UpdatePrivacyInput) (*model.User, error)
 }
 
 type userController struct {
 	ctx     *gin.Context
 	userDAO *dao.UserDAO
+	storage storage.BlobStorage
+
+	usernameCooldown      time.Duration
+	usernameRedirectGrace time.Duration
 }
 
 func NewUserService(db *gorm.DB) UserController {
 	return &userController{
-		userDAO: dao.NewUserDAO(db),
+		userDAO:               dao.NewUserDAO(db),
+		storage:               storage.GetBlobStorage(),
+		usernameCooldown:      utils.GetEnvDuration("USERNAME_CHANGE_COOLDOWN", defaultUsernameChangeCooldown),
+		usernameRedirectGrace: utils.GetEnvDuration("USERNAME_REDIRECT_GRACE", defaultUsernameRedirectGrace),
 	}
 }
 
@@ -37,6 +82,7 @@ func (s *userController) GetUserByID(id string) (*model.User, error) {
 		return nil, err
 	}
 
+	s.applyPrivacy(user)
 	return user, nil
 }
 
@@ -46,11 +92,35 @@ func (s *userController) GetUsersByID(ids []string) ([]*model.User, error) {
 		return nil, err
 	}
 
+	for _, user := range users {
+		s.applyPrivacy(user)
+	}
 	return users, nil
 }
 
 func (s *userController) CreateUser(user model.User) (*model.User, error) {
-	err := s.userDAO.CreateUser(&user)
+	user.Email = normalizeEmail(user.Email)
+	if _, err := s.userDAO.GetUserByEmail(user.Email); err == nil {
+		return nil, fmt.Errorf("%w: email is already in use", utils.ErrConflict)
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	user.Username = strings.TrimSpace(user.Username)
+	if user.Username != "" {
+		availability, err := s.usernameAvailability(user.Username, user.ID)
+		if err != nil {
+			return nil, err
+		}
+		if !availability.Available {
+			return nil, usernameError(availability)
+		}
+	}
+
+	err := s.userDAO.CreateUserUnique(&user)
+	if errors.Is(err, dao.ErrTaken) {
+		return nil, fmt.Errorf("%w: username or email is already taken", utils.ErrConflict)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -58,17 +128,286 @@ func (s *userController) CreateUser(user model.User) (*model.User, error) {
 	return &user, nil
 }
 
-func (s *userController) UpdateUser(user model.User) (*model.User, error) {
-	err := s.userDAO.UpdateUser(&user)
+// CheckUsername tells whether the current user could take the username.
+func (s *userController) CheckUsername(username string) (*model.UsernameAvailability, error) {
+	return s.usernameAvailability(strings.TrimSpace(username), utils.GetCurrentUserID(s.ctx))
+}
+
+// LookupHandle finds the user an @handle belongs to, or belonged to until
+// recently.
+func (s *userController) LookupHandle(handle string) (*model.HandleLookup, error) {
+	handle = strings.TrimPrefix(strings.TrimSpace(handle), "@")
+	if checkUsername(handle) == model.UsernameUnavailableReasonInvalid {
+		return nil, utils.ErrInvalidInput
+	}
+
+	user, err := s.userDAO.GetUserByUsername(handle)
+	if err == nil {
+		s.applyPrivacy(user)
+		return &model.HandleLookup{User: user}, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	redirect, err := s.userDAO.GetUsernameRedirect(handle, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	user, err = s.userDAO.GetUserByID(redirect.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.applyPrivacy(user)
+	return &model.HandleLookup{User: user, RedirectedFrom: redirect.OldUsername}, nil
+}
+
+// GetUsernameHistory lists the current user's username changes, latest first.
+func (s *userController) GetUsernameHistory() ([]*model.UsernameChange, error) {
+	userId := utils.GetCurrentUserID(s.ctx)
+	if userId == "" {
+		return nil, utils.ErrUnauthenticated
+	}
+
+	return s.userDAO.GetUsernameHistory(userId)
+}
+
+// SearchUsers looks the query up in the user directory. A query with an @
+// past its first character is taken as an email, which only matches
+// exactly. Emails are left off the results unless they were searched for.
+func (s *userController) SearchUsers(query string, limit int, offset int) (*model.UserSearchPage, error) {
+	userId := utils.GetCurrentUserID(s.ctx)
+	if userId == "" {
+		return nil, utils.ErrUnauthenticated
+	}
+
+	query = strings.TrimPrefix(strings.TrimSpace(query), "@")
+	length := utf8.RuneCountInString(query)
+	if length < minSearchQueryLength || length > maxSearchQueryLength {
+		return nil, utils.ErrInvalidInput
+	}
+	byEmail := strings.Contains(query, "@")
+	if byEmail {
+		address, err := mail.ParseAddress(query)
+		if err != nil || address.Name != "" {
+			return nil, utils.ErrInvalidInput
+		}
+	}
+
+	if limit <= 0 {
+		limit = defaultSearchPageLimit
+	}
+	if limit > maxSearchPageLimit || offset < 0 {
+		return nil, utils.ErrInvalidInput
+	}
+
+	// one more than asked tells whether there is a next page
+	results, err := s.userDAO.SearchUsers(userId, query, byEmail, limit+1, offset)
 	if err != nil {
 		return nil, err
 	}
 
-	return &user, nil
+	page := &model.UserSearchPage{Results: results}
+	if len(results) > limit {
+		page.Results = results[:limit]
+		nextOffset := offset + limit
+		page.NextOffset = &nextOffset
+	}
+	for _, result := range page.Results {
+		email := result.User.Email
+		s.applyPrivacy(result.User)
+		// whoever searched for the exact email knows it already
+		if byEmail {
+			result.User.Email = email
+		}
+	}
+	return page, nil
+}
+
+// UpdateProfile changes the current user's public profile, only the fields
+// present in the input are touched.
+func (s *userController) UpdateProfile(input model.UpdateProfileInput) (*model.User, error) {
+	userId := utils.GetCurrentUserID(s.ctx)
+	if userId == "" {
+		return nil, utils.ErrUnauthenticated
+	}
+
+	var username *string
+	columns := map[string]interface{}{}
+	if input.DisplayName != nil {
+		displayName := strings.TrimSpace(*input.DisplayName)
+		if displayName == "" || utf8.RuneCountInString(displayName) > maxDisplayNameLength {
+			return nil, utils.ErrInvalidInput
+		}
+		columns["display_name"] = displayName
+	}
+	if input.Username != nil {
+		trimmed := strings.TrimSpace(*input.Username)
+		if reason := checkUsername(trimmed); reason != "" {
+			return nil, usernameError(&model.UsernameAvailability{Username: trimmed, Reason: reason})
+		}
+		username = &trimmed
+	}
+	if input.Bio != nil {
+		bio := strings.TrimSpace(*input.Bio)
+		if utf8.RuneCountInString(bio) > maxBioLength {
+			return nil, utils.ErrInvalidInput
+		}
+		columns["bio"] = bio
+	}
+	var picture *model.Attachment
+	if input.ProfilePictureID != nil {
+		var err error
+		picture, err = s.profilePicture(userId, *input.ProfilePictureID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// the picture is processed and stored before the transaction, its files
+	// are deleted again when the changes are rolled back
+	var next, previous *model.Avatar
+	if input.ProfilePictureID != nil {
+		variants, err := s.attachmentAvatar(picture)
+		if err != nil {
+			return nil, err
+		}
+		if next, err = s.storeAvatar(variants); err != nil {
+			return nil, err
+		}
+	}
+
+	err := s.userDAO.DB.Transaction(func(tx *gorm.DB) error {
+		locked := *s
+		locked.userDAO = dao.NewUserDAO(tx)
+
+		// the username goes first, it is the change most likely to be refused
+		if username != nil {
+			if err := locked.changeUsername(userId, *username); err != nil {
+				return err
+			}
+		}
+
+		if input.ProfilePictureID != nil {
+			var err error
+			var pictureID *string
+			if picture != nil {
+				pictureID = &picture.ID
+			}
+			if previous, err = locked.userDAO.ReplaceAvatar(userId, next, pictureID); err != nil {
+				return err
+			}
+		}
+
+		if len(columns) > 0 {
+			return locked.userDAO.UpdateUserColumns(userId, columns)
+		}
+		return nil
+	})
+	if err != nil {
+		s.deleteAvatarFiles(next)
+		if errors.Is(err, dao.ErrTaken) {
+			return nil, fmt.Errorf("%w: username is already taken", utils.ErrConflict)
+		}
+		return nil, err
+	}
+	s.deleteAvatarFiles(previous)
+
+	return s.userDAO.GetUserByID(userId)
+}
+
+// ChangeEmail moves the current user to a new email address, which is also
+// what they log in with, so the current password is asked for.
+func (s *userController) ChangeEmail(input model.ChangeEmailInput) (*model.User, error) {
+	user, err := s.authenticatedUser(input.CurrentPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	address, err := mail.ParseAddress(strings.TrimSpace(input.NewEmail))
+	if err != nil || address.Name != "" || len(address.Address) > maxEmailLength {
+		return nil, utils.ErrInvalidInput
+	}
+	email := normalizeEmail(address.Address)
+	if email == normalizeEmail(user.Email) {
+		return user, nil
+	}
+
+	err = s.userDAO.UpdateUserColumnsUnique(user.ID, map[string]interface{}{"email": email})
+	if errors.Is(err, dao.ErrTaken) {
+		return nil, fmt.Errorf("%w: email is already in use", utils.ErrConflict)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	user.Email = email
+	return user, nil
+}
+
+// ChangePassword replaces the current user's password, once the current
+// one is confirmed.
+func (s *userController) ChangePassword(input model.ChangePasswordInput) error {
+	user, err := s.authenticatedUser(input.CurrentPassword)
+	if err != nil {
+		return err
+	}
+
+	if len(input.NewPassword) < minPasswordLength || len(input.NewPassword) > maxPasswordLength {
+		return utils.ErrInvalidInput
+	}
+	if input.NewPassword == input.CurrentPassword {
+		return utils.ErrInvalidInput
+	}
+
+	return s.userDAO.UpdateUserColumns(user.ID, map[string]interface{}{"password": input.NewPassword})
+}
+
+// authenticatedUser loads the current user after checking the password they
+// confirmed a sensitive change with.
+func (s *userController) authenticatedUser(password string) (*model.User, error) {
+	userId := utils.GetCurrentUserID(s.ctx)
+	if userId == "" {
+		return nil, utils.ErrUnauthenticated
+	}
+
+	user, err := s.userDAO.GetUserByID(userId)
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(user.Password), []byte(password)) != 1 {
+		return nil, fmt.Errorf("%w: current password is wrong", utils.ErrForbidden)
+	}
+	return user, nil
+}
+
+// profilePicture checks the attachment is an image the user uploaded, an
+// empty ID removes the picture.
+func (s *userController) profilePicture(userID string, attachmentID string) (*model.Attachment, error) {
+	if attachmentID == "" {
+		return nil, nil
+	}
+
+	attachment, err := dao.NewAttachmentDAO(s.userDAO.DB).GetAttachmentByID(attachmentID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, utils.ErrInvalidInput
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if attachment.UploaderID != userID {
+		return nil, utils.ErrForbidden
+	}
+	if !attachment.IsImage() {
+		return nil, utils.ErrInvalidInput
+	}
+	return attachment, nil
 }
 
 func (s *userController) Login(email string, password string) (*model.User, error) {
-	user, err := s.userDAO.GetUserByEmail(email)
+	user, err := s.userDAO.GetUserByEmail(normalizeEmail(email))
 	if err != nil {
 		return nil, err
 	}
@@ -79,3 +418,173 @@ func (s *userController) Login(email string, password string) (*model.User, erro
 
 	return user, nil
 }
+
+// normalizeEmail is how emails are stored and looked up, they are unique
+// regardless of case.
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// ConnectPresence and DisconnectPresence take the user explicitly because they
+// are called when a long-lived realtime connection opens and closes.
+func (s *userController) ConnectPresence(userID string) {
+	if userID == "" || !presenceTracker.Connect(userID) {
+		return
+	}
+
+	s.publishPresence(userID)
+}
+
+func (s *userController) DisconnectPresence(userID string) {
+	if userID == "" || !presenceTracker.Disconnect(userID) {
+		return
+	}
+
+	err := s.userDAO.UpdateUserColumns(userID, map[string]interface{}{
+		"last_seen_at": time.Now(),
+	})
+	if err != nil {
+		return
+	}
+
+	s.publishPresence(userID)
+}
+
+func (s *userController) GetPresences(ids []string) ([]*model.Presence, error) {
+	users, err := s.userDAO.GetUsersByID(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	viewerID := utils.GetCurrentUserID(s.ctx)
+	presences := make([]*model.Presence, 0, len(users))
+	for _, user := range users {
+		presences = append(presences, buildPresence(user, viewerID))
+	}
+
+	return presences, nil
+}
+
+func (s *userController) UpdateStatus(input model.UpdateStatusInput) (*model.Presence, error) {
+	userId := utils.GetCurrentUserID(s.ctx)
+	if userId == "" {
+		return nil, utils.ErrUnauthenticated
+	}
+
+	if !input.Status.IsValid() || input.Status == model.PresenceStatusOffline {
+		return nil, utils.ErrInvalidInput
+	}
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(time.Now()) {
+		return nil, utils.ErrInvalidInput
+	}
+
+	err := s.userDAO.UpdateUserColumns(userId, map[string]interface{}{
+		"status":            input.Status,
+		"status_text":       input.StatusText,
+		"status_expires_at": input.ExpiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// the request context is gone by the time the status expires
+	expiryController := &userController{userDAO: s.userDAO}
+	presenceTracker.ScheduleExpiry(userId, input.ExpiresAt, func() {
+		expiryController.publishPresence(userId)
+	})
+
+	return s.publishPresence(userId), nil
+}
+
+func (s *userController) UpdatePrivacy(input model.UpdatePrivacyInput) (*model.User, error) {
+	userId := utils.GetCurrentUserID(s.ctx)
+	if userId == "" {
+		return nil, utils.ErrUnauthenticated
+	}
+
+	columns := map[string]interface{}{}
+	if input.HideLastSeen != nil {
+		columns["hide_last_seen"] = *input.HideLastSeen
+	}
+	if input.HideForwardSender != nil {
+		columns["hide_forward_sender"] = *input.HideForwardSender
+	}
+	if input.Discoverability != nil {
+		if !input.Discoverability.IsValid() {
+			return nil, utils.ErrInvalidInput
+		}
+		columns["discoverability"] = *input.Discoverability
+	}
+	if input.DirectMessages != nil {
+		if !input.DirectMessages.IsValid() {
+			return nil, utils.ErrInvalidInput
+		}
+		columns["direct_messages"] = *input.DirectMessages
+	}
+
+	if len(columns) > 0 {
+		if err := s.userDAO.UpdateUserColumns(userId, columns); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.userDAO.GetUserByID(userId)
+}
+
+// publishPresence pushes the user's current presence to every user sharing a
+// conversation with them and to their own other devices.
+func (s *userController) publishPresence(userID string) *model.Presence {
+	user, err := s.userDAO.GetUserByID(userID)
+	if err != nil {
+		return nil
+	}
+
+	// built as seen by other users, so a hidden last-seen is never pushed
+	presence := buildPresence(user, "")
+
+	peerIDs, err := s.userDAO.GetConversationPeerIDs(userID)
+	if err != nil {
+		return presence
+	}
+
+	for _, peerID := range append(peerIDs, userID) {
+		eventHub.Publish(UserStream(peerID), model.EventTypePresenceChanged, "", presence)
+	}
+
+	return presence
+}
+
+// applyPrivacy clears what other users may not see of the user.
+func (s *userController) applyPrivacy(user *model.User) {
+	if user.ID == utils.GetCurrentUserID(s.ctx) {
+		return
+	}
+
+	user.Email = ""
+}
+
+func buildPresence(user *model.User, viewerID string) *model.Presence {
+	presence := &model.Presence{
+		UserID:     user.ID,
+		Status:     model.PresenceStatusOffline,
+		LastSeenAt: user.LastSeenAt,
+	}
+
+	if presenceTracker.IsOnline(user.ID) {
+		presence.Status = model.PresenceStatusOnline
+		presence.LastSeenAt = nil
+
+		if user.StatusExpiresAt == nil || user.StatusExpiresAt.After(time.Now()) {
+			if user.Status.IsValid() && user.Status != model.PresenceStatusOffline {
+				presence.Status = user.Status
+			}
+			presence.StatusText = user.StatusText
+		}
+	}
+
+	if user.HideLastSeen && user.ID != viewerID {
+		presence.LastSeenAt = nil
+	}
+
+	return presence
+}
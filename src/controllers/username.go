@@ -0,0 +1,143 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/badaccuracyid/softeng_backend/src/model"
+	"github.com/badaccuracyid/softeng_backend/src/utils"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultUsernameChangeCooldown = 7 * 24 * time.Hour
+	defaultUsernameRedirectGrace  = 14 * 24 * time.Hour
+)
+
+// usernamePattern allows letters, digits, dots and underscores, starting
+// with a letter. Dots may not end the username or follow each other.
+var usernamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_.]{2,31}$`)
+
+// reservedUsernames could be mistaken for the app itself or for a mention
+// of many users, they are compared in lower case.
+var reservedUsernames = map[string]bool{
+	"admin": true, "administrator": true, "root": true, "system": true,
+	"support": true, "help": true, "api": true, "me": true, "settings": true,
+	"null": true, "undefined": true, "everyone": true, "all": true, "here": true,
+	"moderator": true, "mod": true, "staff": true, "official": true,
+	"security": true, "www": true, "mail": true, "login": true, "logout": true,
+	"signup": true, "register": true, "account": true, "user": true,
+	"username": true, "handle": true, "anonymous": true,
+}
+
+// checkUsername returns why the username breaks the policy, or an empty
+// reason.
+func checkUsername(username string) model.UsernameUnavailableReason {
+	if !usernamePattern.MatchString(username) || strings.HasSuffix(username, ".") || strings.Contains(username, "..") {
+		return model.UsernameUnavailableReasonInvalid
+	}
+	if reservedUsernames[strings.ToLower(username)] {
+		return model.UsernameUnavailableReasonReserved
+	}
+	return ""
+}
+
+// usernameAvailability tells whether the user could take the username. A
+// user can always go back to a handle still redirecting to them.
+func (s *userController) usernameAvailability(username string, userID string) (*model.UsernameAvailability, error) {
+	availability := &model.UsernameAvailability{Username: username}
+	if reason := checkUsername(username); reason != "" {
+		availability.Reason = reason
+		return availability, nil
+	}
+
+	owner, err := s.userDAO.GetUserByUsername(username)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if owner != nil && owner.ID != userID {
+		availability.Reason = model.UsernameUnavailableReasonTaken
+		return availability, nil
+	}
+
+	redirect, err := s.userDAO.GetUsernameRedirect(username, time.Now())
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if redirect != nil && redirect.UserID != userID {
+		availability.Reason = model.UsernameUnavailableReasonTaken
+		return availability, nil
+	}
+
+	availability.Available = true
+	return availability, nil
+}
+
+// usernameError turns an unavailable username into the error to answer with.
+func usernameError(availability *model.UsernameAvailability) error {
+	switch availability.Reason {
+	case model.UsernameUnavailableReasonInvalid:
+		return fmt.Errorf("%w: username must be 3 to 32 letters, digits, dots or underscores starting with a letter", utils.ErrInvalidInput)
+	case model.UsernameUnavailableReasonReserved:
+		return fmt.Errorf("%w: username is reserved", utils.ErrInvalidInput)
+	}
+	return fmt.Errorf("%w: username is already taken", utils.ErrConflict)
+}
+
+// changeUsername moves the user to a new username. Their previous one keeps
+// leading to them for the grace period, and they have to wait for the
+// cooldown before changing again. Setting the first username or only its
+// case is not a change.
+//
+// It runs inside the caller's transaction. The checks run with the user's
+// row locked, so two changes of the same user cannot both pass the cooldown,
+// and with the row of the user holding the username locked, so the redirect
+// they leave when moving away from it is seen. A username another user took
+// meanwhile fails with dao.ErrTaken.
+func (s *userController) changeUsername(userID string, username string) error {
+	user, err := s.userDAO.GetUserForUpdate(userID)
+	if err != nil {
+		return err
+	}
+	if username == user.Username {
+		return nil
+	}
+	if err := s.userDAO.LockUsername(username); err != nil {
+		return err
+	}
+
+	availability, err := s.usernameAvailability(username, user.ID)
+	if err != nil {
+		return err
+	}
+	if !availability.Available {
+		return usernameError(availability)
+	}
+
+	now := time.Now()
+	var change *model.UsernameChange
+	if user.Username != "" && !strings.EqualFold(user.Username, username) {
+		last, err := s.userDAO.GetLastUsernameChange(user.ID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if last != nil && now.Before(last.ChangedAt.Add(s.usernameCooldown)) {
+			return fmt.Errorf("%w: username can be changed again after %s", utils.ErrRateLimited, last.ChangedAt.Add(s.usernameCooldown).Format(time.RFC3339))
+		}
+
+		change = &model.UsernameChange{
+			ID:            uuid.New().String(),
+			UserID:        user.ID,
+			OldUsername:   user.Username,
+			NewUsername:   username,
+			ChangedAt:     now,
+			RedirectUntil: now.Add(s.usernameRedirectGrace),
+		}
+	}
+
+	return s.userDAO.ChangeUsername(user.ID, username, change)
+}
@@ -0,0 +1,44 @@
+package dao
+
+import (
+	"github.com/badaccuracyid/softeng_backend/src/model"
+	"gorm.io/gorm"
+)
+
+type AttachmentDAO struct {
+	DB *gorm.DB
+}
+
+func NewAttachmentDAO(db *gorm.DB) *AttachmentDAO {
+	return &AttachmentDAO{
+		DB: db,
+	}
+}
+
+func (dao *AttachmentDAO) CreateAttachment(attachment *model.Attachment) error {
+	return dao.DB.Create(attachment).Error
+}
+
+func (dao *AttachmentDAO) GetAttachmentByID(id string) (*model.Attachment, error) {
+	attachment := &model.Attachment{}
+	err := dao.DB.First(attachment, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return attachment, nil
+}
+
+func (dao *AttachmentDAO) GetAttachmentsByID(ids []string) ([]*model.Attachment, error) {
+	var attachments []*model.Attachment
+	err := dao.DB.Find(&attachments, "id IN ?", ids).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return attachments, nil
+}
+
+func (dao *AttachmentDAO) DeleteAttachment(id string) error {
+	return dao.DB.Delete(&model.Attachment{}, "id = ?", id).Error
+}
@@ -1,12 +1,18 @@
 package dao
 
 import (
-	"fmt"
+	"errors"
+	"time"
 
 	"github.com/badaccuracyid/softeng_backend/src/model"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// ErrLimitReached is returned when a conversation already holds as many of
+// something as it may.
+var ErrLimitReached = errors.New("limit reached")
+
 type ChatDAO struct {
 	DB *gorm.DB
 }
@@ -21,33 +27,350 @@ func (dao *ChatDAO) CreateConversation(conversation *model.Conversation) error {
 	return dao.DB.Create(conversation).Error
 }
 
+// GetOrCreateDirectConversation inserts the direct conversation unless one
+// already exists for its DirectKey, and returns the stored one. The unique
+// index on direct_key settles concurrent requests for the same pair.
+func (dao *ChatDAO) GetOrCreateDirectConversation(conversation *model.Conversation) (*model.Conversation, bool, error) {
+	created := false
+	err := dao.DB.Transaction(func(tx *gorm.DB) error {
+		members := conversation.Members
+		result := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "direct_key"}},
+			DoNothing: true,
+		}).Omit(clause.Associations).Create(conversation)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		created = true
+		return tx.Model(conversation).Association("Members").Append(members)
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	existing, err := dao.GetDirectConversation(*conversation.DirectKey)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return existing, created, nil
+}
+
+// GetDirectConversation returns the direct conversation with its members.
+func (dao *ChatDAO) GetDirectConversation(directKey string) (*model.Conversation, error) {
+	conversation := &model.Conversation{}
+	err := dao.DB.Preload("Members", publicUser).First(conversation, "direct_key = ?", directKey).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return conversation, nil
+}
+
+// GetConversationByID loads the conversation with its messages. Members are
+// only loaded for direct and group conversations, a channel can have far too
+// many of them.
 func (dao *ChatDAO) GetConversationByID(id string) (*model.Conversation, error) {
 	conversation := &model.Conversation{}
-	err := dao.DB.Preload("Members").Preload("Messages.Sender").First(&conversation, "id = ?", id).Error
+	err := dao.DB.
+		Preload("Avatar").
+		Preload("Messages", "expires_at IS NULL OR expires_at > ?", time.Now()).
+		Preload("Messages.Sender", publicUser).
+		Preload("Messages.Attachments").
+		Preload("Messages.Poll.Options", orderPollOptions).
+		Preload("Messages.LinkPreviews", orderLinkPreviews).
+		First(&conversation, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if err := dao.preloadMembers([]*model.Conversation{conversation}); err != nil {
+		return nil, err
+	}
+	return conversation, nil
+}
+
+// GetConversationSummary loads the conversation row alone, without members or
+// messages.
+func (dao *ChatDAO) GetConversationSummary(id string) (*model.Conversation, error) {
+	conversation := &model.Conversation{}
+	err := dao.DB.Preload("Avatar").First(conversation, "id = ?", id).Error
 	if err != nil {
 		return nil, err
 	}
+
 	return conversation, nil
 }
 
-func (dao *ChatDAO) GetConversationsForUser(userID string) ([]*model.Conversation, error) {
+func (dao *ChatDAO) preloadMembers(conversations []*model.Conversation) error {
+	var ids []string
+	for _, conversation := range conversations {
+		if conversation.Kind != model.ConversationKindChannel {
+			ids = append(ids, conversation.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	var loaded []*model.Conversation
+	if err := dao.DB.Preload("Members", publicUser).Find(&loaded, "id IN ?", ids).Error; err != nil {
+		return err
+	}
+
+	members := make(map[string][]*model.User, len(loaded))
+	for _, conversation := range loaded {
+		members[conversation.ID] = conversation.Members
+	}
+	for _, conversation := range conversations {
+		if found, ok := members[conversation.ID]; ok {
+			conversation.Members = found
+		}
+	}
+	return nil
+}
+
+// unreadMessages matches the messages of others the member has not read yet,
+// used inside queries joining user_conversations.
+const unreadMessages = `
+	messages.conversation_id = user_conversations.conversation_id
+	AND messages.sender_id <> user_conversations.user_id
+	AND messages.created_at > COALESCE(user_conversations.last_read_at, user_conversations.joined_at)`
+
+// A mention is the member's handle with no handle character around it, so
+// @bob does not match @bobby or @bob.smith. Dots in the handle are escaped,
+// dots after it may end a sentence.
+var (
+	unreadMessagesExist = "EXISTS (SELECT 1 FROM messages WHERE" + unreadMessages + ")"
+	unreadMentionsExist = `EXISTS (
+		SELECT 1 FROM messages JOIN users ON users.id = user_conversations.user_id
+		WHERE` + unreadMessages + `
+		AND users.username <> ''
+		AND messages.content ~* ('(^|[^a-z0-9_.])@' || REPLACE(users.username, '.', '\.') || '\.*($|[^a-z0-9_.])'))`
+)
+
+// GetConversationsForUser lists the conversations of the user, pinned ones
+// first, then by latest activity.
+func (dao *ChatDAO) GetConversationsForUser(userID string, filter model.ConversationFilter) ([]*model.Conversation, error) {
 	conversations := []*model.Conversation{}
-	err := dao.DB.
-		Joins("JOIN user_conversations ON user_conversations.conversation_id = conversations.id").
-		Joins("JOIN users ON users.id = user_conversations.user_id").
-		Preload("Members").
-		Preload("Messages.Sender").
-		Where("users.id = ?", userID).
+	err := dao.conversationsForUser(userID, filter).
+		Preload("Avatar").
+		Preload("Messages", "expires_at IS NULL OR expires_at > ?", time.Now()).
+		Preload("Messages.Sender", publicUser).
+		Preload("Messages.Attachments").
+		Preload("Messages.Poll.Options", orderPollOptions).
+		Preload("Messages.LinkPreviews", orderLinkPreviews).
+		Order("user_conversations.pinned_at DESC NULLS LAST").
+		Order("COALESCE(conversations.last_message_at, conversations.created_at) DESC NULLS LAST").
 		Find(&conversations).Error
 	if err != nil {
 		return nil, err
 	}
 
-	fmt.Println(conversations, userID)
-
+	if err := dao.preloadMembers(conversations); err != nil {
+		return nil, err
+	}
 	return conversations, nil
 }
 
+// GetConversationIDsForUser is GetConversationsForUser without loading the
+// conversations, for counting.
+func (dao *ChatDAO) GetConversationIDsForUser(userID string, filter model.ConversationFilter) ([]string, error) {
+	var ids []string
+	err := dao.conversationsForUser(userID, filter).Pluck("conversations.id", &ids).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+func (dao *ChatDAO) conversationsForUser(userID string, filter model.ConversationFilter) *gorm.DB {
+	query := dao.DB.Model(&model.Conversation{}).
+		Joins("JOIN user_conversations ON user_conversations.conversation_id = conversations.id").
+		Where("user_conversations.user_id = ?", userID)
+
+	now := time.Now()
+	if filter.Archived != nil {
+		if *filter.Archived {
+			query = query.Where("user_conversations.archived_at IS NOT NULL")
+		} else {
+			query = query.Where("user_conversations.archived_at IS NULL")
+		}
+	}
+	if filter.Muted != nil {
+		if *filter.Muted {
+			query = query.Where("user_conversations.muted_forever OR user_conversations.muted_until > ?", now)
+		} else {
+			// spelled out, NOT of a NULL muted_until would drop the row
+			query = query.Where("NOT user_conversations.muted_forever AND "+
+				"(user_conversations.muted_until IS NULL OR user_conversations.muted_until <= ?)", now)
+		}
+	}
+	if filter.Pinned != nil {
+		if *filter.Pinned {
+			query = query.Where("user_conversations.pinned_at IS NOT NULL")
+		} else {
+			query = query.Where("user_conversations.pinned_at IS NULL")
+		}
+	}
+	if filter.Unread != nil {
+		if *filter.Unread {
+			query = query.Where(unreadMessagesExist)
+		} else {
+			query = query.Where("NOT " + unreadMessagesExist)
+		}
+	}
+	if filter.Mentioned != nil {
+		if *filter.Mentioned {
+			query = query.Where(unreadMentionsExist)
+		} else {
+			query = query.Where("NOT " + unreadMentionsExist)
+		}
+	}
+	if len(filter.Kinds) > 0 {
+		query = query.Where("conversations.kind IN ?", filter.Kinds)
+	}
+	if filter.ConversationIDs != nil {
+		query = query.Where("conversations.id IN ?", filter.ConversationIDs)
+	}
+
+	return query
+}
+
+func (dao *ChatDAO) GetMembershipsForUser(userID string, conversationIDs []string) (map[string]*model.ConversationMember, error) {
+	var members []*model.ConversationMember
+	err := dao.DB.Find(&members, "user_id = ? AND conversation_id IN ?", userID, conversationIDs).Error
+	if err != nil {
+		return nil, err
+	}
+
+	memberships := make(map[string]*model.ConversationMember, len(members))
+	for _, member := range members {
+		memberships[member.ConversationID] = member
+	}
+	return memberships, nil
+}
+
+// GetUnreadCounts counts the unread messages of the user in each of the
+// conversations, conversations without any are left out.
+func (dao *ChatDAO) GetUnreadCounts(userID string, conversationIDs []string) (map[string]int64, error) {
+	var rows []struct {
+		ConversationID string
+		Count          int64
+	}
+	err := dao.DB.Table("user_conversations").
+		Select("user_conversations.conversation_id, COUNT(*) AS count").
+		Joins("JOIN messages ON"+unreadMessages).
+		Where("user_conversations.user_id = ? AND user_conversations.conversation_id IN ?", userID, conversationIDs).
+		Group("user_conversations.conversation_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.ConversationID] = row.Count
+	}
+	return counts, nil
+}
+
+func (dao *ChatDAO) UpdateMemberColumns(conversationID string, userID string, columns map[string]interface{}) error {
+	return dao.DB.Model(&model.ConversationMember{}).
+		Where("conversation_id = ? AND user_id = ?", conversationID, userID).
+		Updates(columns).Error
+}
+
+func (dao *ChatDAO) GetConversationMemberIDs(conversationID string) ([]string, error) {
+	var memberIDs []string
+	err := dao.DB.Table("user_conversations").
+		Where("conversation_id = ?", conversationID).
+		Pluck("user_id", &memberIDs).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return memberIDs, nil
+}
+
+// ForEachMemberIDBatch walks the member IDs of the conversation a batch at a
+// time, so fanning out to a large channel never holds every member at once.
+func (dao *ChatDAO) ForEachMemberIDBatch(conversationID string, batchSize int, fn func(memberIDs []string)) error {
+	var batch []*model.ConversationMember
+	return dao.DB.Select("conversation_id", "user_id").
+		Where("conversation_id = ?", conversationID).
+		FindInBatches(&batch, batchSize, func(tx *gorm.DB, _ int) error {
+			memberIDs := make([]string, len(batch))
+			for i, member := range batch {
+				memberIDs[i] = member.UserID
+			}
+			fn(memberIDs)
+			return nil
+		}).Error
+}
+
+func (dao *ChatDAO) GetConversationAdminIDs(conversationID string) ([]string, error) {
+	var adminIDs []string
+	err := dao.DB.Model(&model.ConversationMember{}).
+		Where("conversation_id = ? AND role = ?", conversationID, model.MemberRoleAdmin).
+		Pluck("user_id", &adminIDs).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return adminIDs, nil
+}
+
+func (dao *ChatDAO) IsConversationMember(conversationID string, userID string) (bool, error) {
+	var count int64
+	err := dao.DB.Table("user_conversations").
+		Where("conversation_id = ? AND user_id = ?", conversationID, userID).
+		Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+func (dao *ChatDAO) GetMember(conversationID string, userID string) (*model.ConversationMember, error) {
+	member := &model.ConversationMember{}
+	err := dao.DB.First(member, "conversation_id = ? AND user_id = ?", conversationID, userID).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return member, nil
+}
+
+func (dao *ChatDAO) SetMemberRole(conversationID string, userID string, role model.MemberRole) error {
+	return dao.DB.Model(&model.ConversationMember{}).
+		Where("conversation_id = ? AND user_id = ?", conversationID, userID).
+		Update("role", role).Error
+}
+
+func (dao *ChatDAO) CountMembers(conversationID string) (int64, error) {
+	var count int64
+	err := dao.DB.Model(&model.ConversationMember{}).
+		Where("conversation_id = ?", conversationID).
+		Count(&count).Error
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (dao *ChatDAO) UpdateConversationColumns(id string, columns map[string]interface{}) error {
+	return dao.DB.Model(&model.Conversation{}).Where("id = ?", id).Updates(columns).Error
+}
+
 func (dao *ChatDAO) UpdateConversation(conversation *model.Conversation) error {
 	return dao.DB.Save(conversation).Error
 }
@@ -62,10 +385,333 @@ func (dao *ChatDAO) CreateMessage(message *model.Message) error {
 		return err
 	}
 
+	// Bump the conversation in the lists and bring it back from the archive
+	// of every member who did not mute it
+	err := dao.DB.Model(&model.Conversation{}).
+		Where("id = ?", message.ConversationID).
+		UpdateColumn("last_message_at", message.CreatedAt).Error
+	if err != nil {
+		return err
+	}
+
+	err = dao.DB.Model(&model.ConversationMember{}).
+		Where("conversation_id = ? AND archived_at IS NOT NULL", message.ConversationID).
+		Where("NOT muted_forever AND (muted_until IS NULL OR muted_until <= ?)", message.CreatedAt).
+		Update("archived_at", nil).Error
+	if err != nil {
+		return err
+	}
+
 	// Preload the Sender (User)
-	if err := dao.DB.Preload("Sender").First(message, "id = ?", message.ID).Error; err != nil {
+	err = dao.DB.
+		Preload("Sender", publicUser).
+		Preload("Attachments").
+		Preload("Poll.Options", orderPollOptions).
+		Preload("LinkPreviews", orderLinkPreviews).
+		First(message, "id = ?", message.ID).Error
+	if err != nil {
 		return err
 	}
 
 	return nil
 }
+
+func (dao *ChatDAO) GetMessageByID(id string) (*model.Message, error) {
+	message := &model.Message{}
+	err := dao.DB.
+		Preload("Sender", publicUser).
+		Preload("Attachments").
+		Preload("Poll.Options", orderPollOptions).
+		Preload("LinkPreviews", orderLinkPreviews).
+		First(message, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return message, nil
+}
+
+// ClaimClientMessageID records that key.MessageID is created for the client
+// message ID. It reports false when the sender used the ID after retainedAfter,
+// the message created then is the one to return. Older keys are taken over.
+// Concurrent claims of the same ID wait on each other through the primary
+// key, so only one of them wins.
+func (dao *ChatDAO) ClaimClientMessageID(key *model.MessageIdempotencyKey, retainedAfter time.Time) (bool, error) {
+	result := dao.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "sender_id"}, {Name: "client_message_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"message_id", "created_at"}),
+		Where: clause.Where{Exprs: []clause.Expression{
+			clause.Lt{Column: clause.Column{Table: "message_idempotency_keys", Name: "created_at"}, Value: retainedAfter},
+		}},
+	}).Create(key)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+func (dao *ChatDAO) GetMessageByClientID(senderID string, clientMessageID string) (*model.Message, error) {
+	key := &model.MessageIdempotencyKey{}
+	err := dao.DB.First(key, "sender_id = ? AND client_message_id = ?", senderID, clientMessageID).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return dao.GetMessageByID(key.MessageID)
+}
+
+func (dao *ChatDAO) DeleteClientMessageIDsBefore(cutoff time.Time) error {
+	return dao.DB.Delete(&model.MessageIdempotencyKey{}, "created_at < ?", cutoff).Error
+}
+
+func (dao *ChatDAO) GetMessagesByID(ids []string) ([]*model.Message, error) {
+	var messages []*model.Message
+	err := dao.DB.Preload("Sender", publicUser).Preload("Attachments").Order("created_at").Find(&messages, "id IN ?", ids).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+// PinMessage reports false when the message was already pinned, and
+// ErrLimitReached when the conversation has limit pins already. The
+// conversation row is locked while counting, so concurrent pins cannot both
+// take the last place.
+func (dao *ChatDAO) PinMessage(pin *model.PinnedMessage, limit int) (bool, error) {
+	pinned := false
+	err := dao.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id").
+			First(&model.Conversation{}, "id = ?", pin.ConversationID).Error
+		if err != nil {
+			return err
+		}
+
+		var count int64
+		err = tx.Model(&model.PinnedMessage{}).Where("conversation_id = ?", pin.ConversationID).Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count >= int64(limit) {
+			return ErrLimitReached
+		}
+
+		result := tx.Omit("Message").Clauses(clause.OnConflict{DoNothing: true}).Create(pin)
+		if result.Error != nil {
+			return result.Error
+		}
+		pinned = result.RowsAffected == 1
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return pinned, nil
+}
+
+// UnpinMessage reports false when the message was not pinned.
+func (dao *ChatDAO) UnpinMessage(conversationID string, messageID string) (bool, error) {
+	result := dao.DB.Delete(&model.PinnedMessage{}, "conversation_id = ? AND message_id = ?", conversationID, messageID)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+// GetPinnedMessages lists the pins of the conversation, latest first.
+func (dao *ChatDAO) GetPinnedMessages(conversationID string) ([]*model.PinnedMessage, error) {
+	var pins []*model.PinnedMessage
+	err := dao.DB.
+		Preload("Message.Sender", publicUser).
+		Preload("Message.Attachments").
+		Preload("Message.Poll.Options", orderPollOptions).
+		Preload("Message.LinkPreviews", orderLinkPreviews).
+		Order("pinned_at DESC").
+		Find(&pins, "conversation_id = ?", conversationID).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return pins, nil
+}
+
+func orderPollOptions(db *gorm.DB) *gorm.DB {
+	return db.Order("position")
+}
+
+func orderLinkPreviews(db *gorm.DB) *gorm.DB {
+	return db.Order("position")
+}
+
+// SaveLinkPreviews stores the previews of a message unless it was deleted
+// meanwhile, which is reported with gorm.ErrRecordNotFound.
+func (dao *ChatDAO) SaveLinkPreviews(messageID string, previews []*model.LinkPreview) error {
+	return dao.DB.Transaction(func(tx *gorm.DB) error {
+		// holds off deleting the message until the previews are in
+		err := tx.Clauses(clause.Locking{Strength: "SHARE"}).Select("id").First(&model.Message{}, "id = ?", messageID).Error
+		if err != nil {
+			return err
+		}
+
+		for i, preview := range previews {
+			preview.MessageID = messageID
+			preview.Position = i
+		}
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&previews).Error
+	})
+}
+
+// DeletedMessages is what went away together with a batch of messages.
+type DeletedMessages struct {
+	// Messages only hold their ID and ConversationID
+	Messages []*model.Message
+	Unpinned []*model.PinnedMessage
+	// Orphans are the attachments no other message or avatar used, their
+	// rows are gone and their blobs are left to the caller
+	Orphans []*model.Attachment
+}
+
+// DeleteExpiredMessages deletes up to limit messages that expired at now.
+// Rows another instance is deleting are skipped.
+func (dao *ChatDAO) DeleteExpiredMessages(now time.Time, limit int) (*DeletedMessages, error) {
+	var deleted *DeletedMessages
+	err := dao.DB.Transaction(func(tx *gorm.DB) error {
+		var ids []string
+		err := tx.Model(&model.Message{}).
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("expires_at <= ?", now).
+			Order("expires_at").
+			Limit(limit).
+			Pluck("id", &ids).Error
+		if err != nil {
+			return err
+		}
+
+		deleted, err = deleteMessages(tx, ids)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return deleted, nil
+}
+
+func deleteMessages(tx *gorm.DB, ids []string) (*DeletedMessages, error) {
+	deleted := &DeletedMessages{}
+	if len(ids) == 0 {
+		return deleted, nil
+	}
+
+	err := tx.Select("id", "conversation_id").Find(&deleted.Messages, "id IN ?", ids).Error
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Find(&deleted.Unpinned, "message_id IN ?", ids).Error; err != nil {
+		return nil, err
+	}
+
+	var attachmentIDs []string
+	err = tx.Table("message_attachments").Where("message_id IN ?", ids).Distinct().Pluck("attachment_id", &attachmentIDs).Error
+	if err != nil {
+		return nil, err
+	}
+
+	tables := []interface{}{
+		&model.PinnedMessage{}, &model.MessageReaction{}, &model.MessageView{},
+		&model.PollVote{}, &model.PollOption{}, &model.Poll{}, &model.LinkPreview{},
+		// a retry must not find a key whose message is gone
+		&model.MessageIdempotencyKey{},
+	}
+	for _, table := range tables {
+		if err := tx.Delete(table, "message_id IN ?", ids).Error; err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Exec("DELETE FROM message_attachments WHERE message_id IN ?", ids).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Delete(&model.Message{}, "id IN ?", ids).Error; err != nil {
+		return nil, err
+	}
+
+	if len(attachmentIDs) == 0 {
+		return deleted, nil
+	}
+	err = tx.
+		Where("id IN ?", attachmentIDs).
+		Where("NOT EXISTS (SELECT 1 FROM message_attachments WHERE message_attachments.attachment_id = attachments.id)").
+		Where("NOT EXISTS (SELECT 1 FROM message_draft_attachments WHERE message_draft_attachments.attachment_id = attachments.id)").
+		Where("NOT EXISTS (SELECT 1 FROM conversations WHERE conversations.avatar_id = attachments.id)").
+		Where("NOT EXISTS (SELECT 1 FROM users WHERE users.profile_picture_id = attachments.id)").
+		Find(&deleted.Orphans).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, attachment := range deleted.Orphans {
+		if err := tx.Delete(attachment).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return deleted, nil
+}
+
+// AddReaction reports false when the user already reacted with the emoji.
+func (dao *ChatDAO) AddReaction(reaction *model.MessageReaction) (bool, error) {
+	result := dao.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(reaction)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+// RemoveReaction reports false when the user had not reacted with the emoji.
+func (dao *ChatDAO) RemoveReaction(messageID string, userID string, emoji string) (bool, error) {
+	result := dao.DB.Delete(&model.MessageReaction{}, "message_id = ? AND user_id = ? AND emoji = ?", messageID, userID, emoji)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+// GetReactionCounts totals the reactions of the message per emoji, most used
+// first. Reacted is set for the emojis userID reacted with.
+func (dao *ChatDAO) GetReactionCounts(messageID string, userID string) ([]*model.ReactionCount, error) {
+	counts := []*model.ReactionCount{}
+	err := dao.DB.Model(&model.MessageReaction{}).
+		Select("emoji, COUNT(*) AS count, BOOL_OR(user_id = ?) AS reacted", userID).
+		Where("message_id = ?", messageID).
+		Group("emoji").
+		Order("count DESC, emoji").
+		Scan(&counts).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+// RecordViews marks the messages of the conversation as seen by the user and
+// bumps the view count of the ones the user had not seen yet. The user's own
+// messages are not counted.
+func (dao *ChatDAO) RecordViews(conversationID string, userID string, messageIDs []string) error {
+	// one statement, so a view is only counted by the request that inserted it
+	return dao.DB.Exec(`
+		WITH inserted AS (
+			INSERT INTO message_views (message_id, user_id, created_at)
+			SELECT id, ?, NOW() FROM messages
+			WHERE conversation_id = ? AND id IN ? AND sender_id <> ?
+			ON CONFLICT DO NOTHING
+			RETURNING message_id
+		)
+		UPDATE messages SET view_count = view_count + 1
+		WHERE id IN (SELECT message_id FROM inserted)`,
+		userID, conversationID, messageIDs, userID).Error
+}
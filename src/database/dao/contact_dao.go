@@ -0,0 +1,155 @@
+package dao
+
+import (
+	"errors"
+
+	"github.com/badaccuracyid/softeng_backend/src/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ContactDAO struct {
+	DB *gorm.DB
+}
+
+func NewContactDAO(db *gorm.DB) *ContactDAO {
+	return &ContactDAO{
+		DB: db,
+	}
+}
+
+// CreateContactRequest stores the request unless the two users already have
+// one pending, sent by either of them, which is reported with false.
+func (dao *ContactDAO) CreateContactRequest(request *model.ContactRequest) (bool, error) {
+	result := dao.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(request)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+func (dao *ContactDAO) GetContactRequest(pairKey string) (*model.ContactRequest, error) {
+	request := &model.ContactRequest{}
+	err := dao.DB.First(request, "pair_key = ?", pairKey).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return request, nil
+}
+
+// DeleteContactRequest removes the pending request between the two users,
+// provided it was sent by senderID. It reports whether there was one, so a
+// decline or a cancel racing an accept only takes effect once.
+func (dao *ContactDAO) DeleteContactRequest(pairKey string, senderID string) (bool, error) {
+	result := dao.DB.Where("sender_id = ?", senderID).Delete(&model.ContactRequest{}, "pair_key = ?", pairKey)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+// AcceptContactRequest turns the request sent to recipientID into a contact
+// on both sides, and returns the request or nil when it was gone already.
+func (dao *ContactDAO) AcceptContactRequest(pairKey string, recipientID string) (*model.ContactRequest, error) {
+	var accepted *model.ContactRequest
+	err := dao.DB.Transaction(func(tx *gorm.DB) error {
+		request := &model.ContactRequest{}
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(request, "pair_key = ? AND recipient_id = ?", pairKey, recipientID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := tx.Delete(request).Error; err != nil {
+			return err
+		}
+		accepted = request
+
+		contacts := []*model.Contact{
+			{UserID: accepted.SenderID, ContactID: accepted.RecipientID},
+			{UserID: accepted.RecipientID, ContactID: accepted.SenderID},
+		}
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&contacts).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return accepted, nil
+}
+
+// RemoveContact removes the contact on both sides and reports whether they
+// were contacts.
+func (dao *ContactDAO) RemoveContact(userID string, contactID string) (bool, error) {
+	result := dao.DB.Delete(&model.Contact{},
+		"(user_id = ? AND contact_id = ?) OR (user_id = ? AND contact_id = ?)", userID, contactID, contactID, userID)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+func (dao *ContactDAO) IsContact(userID string, otherUserID string) (bool, error) {
+	var count int64
+	err := dao.DB.Model(&model.Contact{}).Where("user_id = ? AND contact_id = ?", userID, otherUserID).Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// GetContactIDsAmong returns which of the given users are contacts of the
+// user.
+func (dao *ContactDAO) GetContactIDsAmong(userID string, userIDs []string) ([]string, error) {
+	contactIDs := []string{}
+	err := dao.DB.Model(&model.Contact{}).
+		Where("user_id = ? AND contact_id IN ?", userID, userIDs).
+		Pluck("contact_id", &contactIDs).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return contactIDs, nil
+}
+
+// GetContacts lists the user's contacts, latest first.
+func (dao *ContactDAO) GetContacts(userID string) ([]*model.Contact, error) {
+	contacts := []*model.Contact{}
+	err := dao.DB.Where("user_id = ?", userID).Order("created_at DESC").Find(&contacts).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return contacts, nil
+}
+
+// GetIncomingContactRequests lists the requests waiting for the user to
+// answer, with their senders, latest first.
+func (dao *ContactDAO) GetIncomingContactRequests(userID string) ([]*model.ContactRequest, error) {
+	requests := []*model.ContactRequest{}
+	err := dao.DB.Preload("Sender", publicUser).Where("recipient_id = ?", userID).Order("created_at DESC").Find(&requests).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return requests, nil
+}
+
+// GetOutgoingContactRequests lists the requests the user sent that are still
+// pending, with their recipients, latest first.
+func (dao *ContactDAO) GetOutgoingContactRequests(userID string) ([]*model.ContactRequest, error) {
+	requests := []*model.ContactRequest{}
+	err := dao.DB.Preload("Recipient", publicUser).Where("sender_id = ?", userID).Order("created_at DESC").Find(&requests).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return requests, nil
+}
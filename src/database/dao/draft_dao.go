@@ -0,0 +1,191 @@
+package dao
+
+import (
+	"time"
+
+	"github.com/badaccuracyid/softeng_backend/src/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// nonEmptyDraft matches the drafts that were not cleared.
+const nonEmptyDraft = "(message_drafts.content <> '' OR message_drafts.reply_to_message_id IS NOT NULL OR " +
+	"EXISTS (SELECT 1 FROM message_draft_attachments WHERE message_draft_attachments.user_id = message_drafts.user_id " +
+	"AND message_draft_attachments.conversation_id = message_drafts.conversation_id))"
+
+type DraftDAO struct {
+	DB *gorm.DB
+}
+
+func NewDraftDAO(db *gorm.DB) *DraftDAO {
+	return &DraftDAO{
+		DB: db,
+	}
+}
+
+// SaveDraft stores the draft unless a newer one is stored already, which is
+// reported with false. Cleared drafts keep their row so that their time is
+// still compared against.
+func (dao *DraftDAO) SaveDraft(draft *model.MessageDraft) (bool, error) {
+	saved := false
+	err := dao.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "conversation_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"content", "reply_to_message_id", "updated_at"}),
+			Where: clause.Where{Exprs: []clause.Expression{
+				clause.Expr{SQL: "message_drafts.updated_at < excluded.updated_at"},
+			}},
+		}).Create(draft)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		saved = true
+
+		err := tx.Delete(&model.MessageDraftAttachment{}, "user_id = ? AND conversation_id = ?", draft.UserID, draft.ConversationID).Error
+		if err != nil {
+			return err
+		}
+		if len(draft.Attachments) == 0 {
+			return nil
+		}
+
+		rows := make([]*model.MessageDraftAttachment, len(draft.Attachments))
+		for i, attachment := range draft.Attachments {
+			rows[i] = &model.MessageDraftAttachment{
+				UserID:         draft.UserID,
+				ConversationID: draft.ConversationID,
+				AttachmentID:   attachment.ID,
+				Position:       i,
+			}
+		}
+		return tx.Create(&rows).Error
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return saved, nil
+}
+
+// ClearDraft empties the draft when it was last edited before the given
+// time, and reports whether it did.
+func (dao *DraftDAO) ClearDraft(userID string, conversationID string, before time.Time) (bool, error) {
+	cleared := false
+	err := dao.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.MessageDraft{}).
+			Where("user_id = ? AND conversation_id = ? AND updated_at < ?", userID, conversationID, before).
+			Where(nonEmptyDraft).
+			Updates(map[string]interface{}{"content": "", "reply_to_message_id": nil, "updated_at": before})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		cleared = true
+
+		return tx.Delete(&model.MessageDraftAttachment{}, "user_id = ? AND conversation_id = ?", userID, conversationID).Error
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return cleared, nil
+}
+
+func (dao *DraftDAO) GetDraft(userID string, conversationID string) (*model.MessageDraft, error) {
+	draft := &model.MessageDraft{}
+	err := dao.DB.First(draft, "user_id = ? AND conversation_id = ?", userID, conversationID).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if err := dao.loadAttachments(userID, []*model.MessageDraft{draft}); err != nil {
+		return nil, err
+	}
+	return draft, nil
+}
+
+// GetDraftsForUser lists the drafts that are not empty in the conversations
+// the user is still a member of, the latest first.
+func (dao *DraftDAO) GetDraftsForUser(userID string) ([]*model.MessageDraft, error) {
+	memberships := dao.DB.Model(&model.ConversationMember{}).Select("conversation_id").Where("user_id = ?", userID)
+
+	drafts := []*model.MessageDraft{}
+	err := dao.DB.
+		Where("user_id = ?", userID).
+		Where(nonEmptyDraft).
+		Where("conversation_id IN (?)", memberships).
+		Order("updated_at DESC").
+		Find(&drafts).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if err := dao.loadAttachments(userID, drafts); err != nil {
+		return nil, err
+	}
+	return drafts, nil
+}
+
+// GetDrafts returns the user's drafts that are not empty, keyed by
+// conversation.
+func (dao *DraftDAO) GetDrafts(userID string, conversationIDs []string) (map[string]*model.MessageDraft, error) {
+	drafts := make(map[string]*model.MessageDraft)
+	if len(conversationIDs) == 0 {
+		return drafts, nil
+	}
+
+	var rows []*model.MessageDraft
+	err := dao.DB.
+		Where("user_id = ? AND conversation_id IN ?", userID, conversationIDs).
+		Where(nonEmptyDraft).
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if err := dao.loadAttachments(userID, rows); err != nil {
+		return nil, err
+	}
+	for _, draft := range rows {
+		drafts[draft.ConversationID] = draft
+	}
+	return drafts, nil
+}
+
+func (dao *DraftDAO) loadAttachments(userID string, drafts []*model.MessageDraft) error {
+	if len(drafts) == 0 {
+		return nil
+	}
+
+	byConversation := make(map[string]*model.MessageDraft, len(drafts))
+	conversationIDs := make([]string, len(drafts))
+	for i, draft := range drafts {
+		byConversation[draft.ConversationID] = draft
+		conversationIDs[i] = draft.ConversationID
+		draft.Attachments = []*model.Attachment{}
+	}
+
+	var rows []struct {
+		ConversationID string
+		model.Attachment
+	}
+	err := dao.DB.Model(&model.MessageDraftAttachment{}).
+		Select("message_draft_attachments.conversation_id, attachments.*").
+		Joins("JOIN attachments ON attachments.id = message_draft_attachments.attachment_id").
+		Where("message_draft_attachments.user_id = ? AND message_draft_attachments.conversation_id IN ?", userID, conversationIDs).
+		Order("message_draft_attachments.position").
+		Scan(&rows).Error
+	if err != nil {
+		return err
+	}
+	for i := range rows {
+		draft := byConversation[rows[i].ConversationID]
+		draft.Attachments = append(draft.Attachments, &rows[i].Attachment)
+	}
+	return nil
+}
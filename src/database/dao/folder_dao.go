@@ -0,0 +1,96 @@
+package dao
+
+import (
+	"github.com/badaccuracyid/softeng_backend/src/model"
+	"gorm.io/gorm"
+)
+
+type FolderDAO struct {
+	DB *gorm.DB
+}
+
+func NewFolderDAO(db *gorm.DB) *FolderDAO {
+	return &FolderDAO{
+		DB: db,
+	}
+}
+
+// SaveFolder creates or replaces the folder together with its explicit
+// conversation list.
+func (dao *FolderDAO) SaveFolder(folder *model.ChatFolder) error {
+	return dao.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(folder).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Delete(&model.ChatFolderConversation{}, "folder_id = ?", folder.ID).Error; err != nil {
+			return err
+		}
+		if len(folder.ConversationIDs) == 0 {
+			return nil
+		}
+
+		rows := make([]*model.ChatFolderConversation, len(folder.ConversationIDs))
+		for i, conversationID := range folder.ConversationIDs {
+			rows[i] = &model.ChatFolderConversation{FolderID: folder.ID, ConversationID: conversationID}
+		}
+		return tx.Create(&rows).Error
+	})
+}
+
+func (dao *FolderDAO) GetFolderByID(id string) (*model.ChatFolder, error) {
+	folder := &model.ChatFolder{}
+	if err := dao.DB.First(folder, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+
+	if err := dao.loadConversationIDs([]*model.ChatFolder{folder}); err != nil {
+		return nil, err
+	}
+	return folder, nil
+}
+
+func (dao *FolderDAO) GetFoldersForUser(userID string) ([]*model.ChatFolder, error) {
+	folders := []*model.ChatFolder{}
+	err := dao.DB.Order("position, created_at").Find(&folders, "user_id = ?", userID).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if err := dao.loadConversationIDs(folders); err != nil {
+		return nil, err
+	}
+	return folders, nil
+}
+
+func (dao *FolderDAO) DeleteFolder(id string) error {
+	return dao.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&model.ChatFolderConversation{}, "folder_id = ?", id).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.ChatFolder{}, "id = ?", id).Error
+	})
+}
+
+func (dao *FolderDAO) loadConversationIDs(folders []*model.ChatFolder) error {
+	if len(folders) == 0 {
+		return nil
+	}
+
+	byID := make(map[string]*model.ChatFolder, len(folders))
+	ids := make([]string, len(folders))
+	for i, folder := range folders {
+		byID[folder.ID] = folder
+		ids[i] = folder.ID
+	}
+
+	var rows []*model.ChatFolderConversation
+	if err := dao.DB.Find(&rows, "folder_id IN ?", ids).Error; err != nil {
+		return err
+	}
+	for _, row := range rows {
+		folder := byID[row.FolderID]
+		folder.ConversationIDs = append(folder.ConversationIDs, row.ConversationID)
+	}
+	return nil
+}
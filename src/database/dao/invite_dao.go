@@ -0,0 +1,136 @@
+package dao
+
+import (
+	"time"
+
+	"github.com/badaccuracyid/softeng_backend/src/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type InviteDAO struct {
+	DB *gorm.DB
+}
+
+func NewInviteDAO(db *gorm.DB) *InviteDAO {
+	return &InviteDAO{
+		DB: db,
+	}
+}
+
+func (dao *InviteDAO) CreateInvite(invite *model.ConversationInvite) error {
+	return dao.DB.Create(invite).Error
+}
+
+func (dao *InviteDAO) GetInviteByID(id string) (*model.ConversationInvite, error) {
+	invite := &model.ConversationInvite{}
+	err := dao.DB.First(invite, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return invite, nil
+}
+
+func (dao *InviteDAO) GetInviteByToken(token string) (*model.ConversationInvite, error) {
+	invite := &model.ConversationInvite{}
+	err := dao.DB.First(invite, "token = ?", token).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return invite, nil
+}
+
+func (dao *InviteDAO) GetInvitesForConversation(conversationID string) ([]*model.ConversationInvite, error) {
+	var invites []*model.ConversationInvite
+	err := dao.DB.Order("created_at DESC").Find(&invites, "conversation_id = ?", conversationID).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return invites, nil
+}
+
+func (dao *InviteDAO) RevokeInvite(id string) error {
+	return dao.DB.Model(&model.ConversationInvite{}).Where("id = ?", id).Update("revoked", true).Error
+}
+
+// ConsumeInvite counts one use of the invite, in a single statement so two
+// users racing for the last use cannot both get it. It reports false when the
+// invite is revoked, expired or used up.
+func (dao *InviteDAO) ConsumeInvite(id string, now time.Time) (bool, error) {
+	result := dao.DB.Model(&model.ConversationInvite{}).
+		Where("id = ? AND revoked = ?", id, false).
+		Where("expires_at IS NULL OR expires_at > ?", now).
+		Where("max_uses IS NULL OR use_count < max_uses").
+		Update("use_count", gorm.Expr("use_count + 1"))
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+// CreateJoinRequest stores the request unless the user has one pending for
+// the conversation already, which is reported with false.
+func (dao *InviteDAO) CreateJoinRequest(request *model.JoinRequest) (bool, error) {
+	result := dao.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(request)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+func (dao *InviteDAO) GetJoinRequestByID(id string) (*model.JoinRequest, error) {
+	request := &model.JoinRequest{}
+	err := dao.DB.Preload("User", publicUser).First(request, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return request, nil
+}
+
+func (dao *InviteDAO) GetPendingJoinRequest(conversationID string, userID string) (*model.JoinRequest, error) {
+	request := &model.JoinRequest{}
+	err := dao.DB.
+		Where("conversation_id = ? AND user_id = ? AND status = ?", conversationID, userID, model.JoinRequestStatusPending).
+		First(request).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return request, nil
+}
+
+func (dao *InviteDAO) GetPendingJoinRequests(conversationID string) ([]*model.JoinRequest, error) {
+	var requests []*model.JoinRequest
+	err := dao.DB.Preload("User", publicUser).
+		Where("conversation_id = ? AND status = ?", conversationID, model.JoinRequestStatusPending).
+		Order("created_at").
+		Find(&requests).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return requests, nil
+}
+
+// ReviewJoinRequest moves a pending request to its final status, it reports
+// false when another admin reviewed it first.
+func (dao *InviteDAO) ReviewJoinRequest(id string, status model.JoinRequestStatus, reviewerID string, now time.Time) (bool, error) {
+	result := dao.DB.Model(&model.JoinRequest{}).
+		Where("id = ? AND status = ?", id, model.JoinRequestStatusPending).
+		Updates(map[string]interface{}{
+			"status":         status,
+			"reviewed_by_id": reviewerID,
+			"reviewed_at":    now,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected == 1, nil
+}
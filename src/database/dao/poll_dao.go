@@ -0,0 +1,123 @@
+package dao
+
+import (
+	"time"
+
+	"github.com/badaccuracyid/softeng_backend/src/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PollDAO struct {
+	DB *gorm.DB
+}
+
+func NewPollDAO(db *gorm.DB) *PollDAO {
+	return &PollDAO{
+		DB: db,
+	}
+}
+
+func (dao *PollDAO) GetPoll(messageID string) (*model.Poll, error) {
+	poll := &model.Poll{}
+	err := dao.DB.Preload("Options", orderPollOptions).First(poll, "message_id = ?", messageID).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return poll, nil
+}
+
+// ReplaceVote swaps the user's vote for optionIDs and hands the options the
+// user voted for before to onChanged, in the same transaction. The poll row
+// is locked meanwhile, so votes of a poll are counted one at a time and each
+// delta matches its totals.
+func (dao *PollDAO) ReplaceVote(messageID string, userID string, optionIDs []string, onChanged func(tx *PollDAO, previous []string) error) error {
+	return dao.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&model.Poll{}, "message_id = ?", messageID).Error
+		if err != nil {
+			return err
+		}
+
+		var previous []string
+		err = tx.Model(&model.PollVote{}).
+			Where("message_id = ? AND user_id = ?", messageID, userID).
+			Pluck("option_id", &previous).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Delete(&model.PollVote{}, "message_id = ? AND user_id = ?", messageID, userID).Error
+		if err != nil {
+			return err
+		}
+		if len(optionIDs) > 0 {
+			votes := make([]*model.PollVote, len(optionIDs))
+			for i, optionID := range optionIDs {
+				votes[i] = &model.PollVote{MessageID: messageID, OptionID: optionID, UserID: userID}
+			}
+			if err := tx.Create(&votes).Error; err != nil {
+				return err
+			}
+		}
+
+		return onChanged(&PollDAO{DB: tx}, previous)
+	})
+}
+
+// ClosePoll reports false when the poll was already closed.
+func (dao *PollDAO) ClosePoll(messageID string, now time.Time) (bool, error) {
+	result := dao.DB.Model(&model.Poll{}).
+		Where("message_id = ? AND closed_at IS NULL", messageID).
+		Update("closed_at", now)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+// CountVotes totals the votes of every option that has some.
+func (dao *PollDAO) CountVotes(messageID string) (map[string]int64, error) {
+	var rows []struct {
+		OptionID string
+		Votes    int64
+	}
+	err := dao.DB.Model(&model.PollVote{}).
+		Select("option_id, COUNT(*) AS votes").
+		Where("message_id = ?", messageID).
+		Group("option_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.OptionID] = row.Votes
+	}
+	return counts, nil
+}
+
+func (dao *PollDAO) CountVoters(messageID string) (int64, error) {
+	var count int64
+	err := dao.DB.Model(&model.PollVote{}).
+		Where("message_id = ?", messageID).
+		Distinct("user_id").
+		Count(&count).Error
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (dao *PollDAO) GetVotes(messageID string) ([]*model.PollVote, error) {
+	var votes []*model.PollVote
+	err := dao.DB.Order("created_at").Find(&votes, "message_id = ?", messageID).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return votes, nil
+}
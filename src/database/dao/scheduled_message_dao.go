@@ -0,0 +1,92 @@
+package dao
+
+import (
+	"time"
+
+	"github.com/badaccuracyid/softeng_backend/src/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ScheduledMessageDAO struct {
+	DB *gorm.DB
+}
+
+func NewScheduledMessageDAO(db *gorm.DB) *ScheduledMessageDAO {
+	return &ScheduledMessageDAO{
+		DB: db,
+	}
+}
+
+func (dao *ScheduledMessageDAO) CreateScheduledMessage(scheduled *model.ScheduledMessage) error {
+	return dao.DB.Create(scheduled).Error
+}
+
+func (dao *ScheduledMessageDAO) GetScheduledMessageByID(id string) (*model.ScheduledMessage, error) {
+	scheduled := &model.ScheduledMessage{}
+	err := dao.DB.First(scheduled, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return scheduled, nil
+}
+
+// GetPendingForUser lists the messages the user still has to be sent, the
+// next one first. An empty conversationID lists every conversation.
+func (dao *ScheduledMessageDAO) GetPendingForUser(userID string, conversationID string) ([]*model.ScheduledMessage, error) {
+	scheduled := []*model.ScheduledMessage{}
+	query := dao.DB.Where("sender_id = ? AND status = ?", userID, model.ScheduledMessageStatusPending)
+	if conversationID != "" {
+		query = query.Where("conversation_id = ?", conversationID)
+	}
+
+	err := query.Order("send_at").Find(&scheduled).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return scheduled, nil
+}
+
+func (dao *ScheduledMessageDAO) CountPendingForUser(userID string) (int64, error) {
+	var count int64
+	err := dao.DB.Model(&model.ScheduledMessage{}).
+		Where("sender_id = ? AND status = ?", userID, model.ScheduledMessageStatusPending).
+		Count(&count).Error
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// UpdatePending changes the message only while it is still pending, it
+// reports false once the scheduler got to it or it was cancelled.
+func (dao *ScheduledMessageDAO) UpdatePending(id string, columns map[string]interface{}) (bool, error) {
+	result := dao.DB.Model(&model.ScheduledMessage{}).
+		Where("id = ? AND status = ?", id, model.ScheduledMessageStatusPending).
+		Updates(columns)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+// LockNextDue picks the next message due at now and locks it until the
+// transaction of the DAO ends. Rows locked by another scheduler instance are
+// skipped, so every message is picked by a single instance.
+func (dao *ScheduledMessageDAO) LockNextDue(now time.Time) (*model.ScheduledMessage, error) {
+	scheduled := &model.ScheduledMessage{}
+	err := dao.DB.
+		Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("status = ? AND send_at <= ?", model.ScheduledMessageStatusPending, now).
+		Order("send_at").
+		First(scheduled).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return scheduled, nil
+}
@@ -1,10 +1,31 @@
 package dao
 
 import (
+	"errors"
+	"strings"
+	"time"
+
 	"github.com/badaccuracyid/softeng_backend/src/model"
+	"github.com/jackc/pgx/v5/pgconn"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// ErrTaken is returned when a value that has to be unique is already used.
+var ErrTaken = errors.New("value is already taken")
+
+// likeEscaper keeps the wildcards typed in a search from acting as such.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// uniqueViolation is the SQLSTATE postgres answers a duplicate key with.
+const uniqueViolation = "23505"
+
+// publicUser leaves the email out of the users loaded along with what other
+// users read, such as conversation members and message senders.
+func publicUser(db *gorm.DB) *gorm.DB {
+	return db.Omit("email")
+}
+
 type UserDAO struct {
 	DB *gorm.DB
 }
@@ -29,9 +50,21 @@ func (dao *UserDAO) GetUserByID(id string) (*model.User, error) {
 	return user, nil
 }
 
+// GetUserByEmail matches the email regardless of case.
 func (dao *UserDAO) GetUserByEmail(email string) (*model.User, error) {
 	user := &model.User{}
-	err := dao.DB.First(user, "email = ?", email).Error
+	err := dao.DB.First(user, "lower(email) = lower(?)", email).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// GetUserByUsername matches the username regardless of case.
+func (dao *UserDAO) GetUserByUsername(username string) (*model.User, error) {
+	user := &model.User{}
+	err := dao.DB.First(user, "lower(username) = lower(?) AND username <> ''", username).Error
 	if err != nil {
 		return nil, err
 	}
@@ -49,10 +82,226 @@ func (dao *UserDAO) GetUsersByID(ids []string) ([]*model.User, error) {
 	return users, nil
 }
 
-func (dao *UserDAO) UpdateUser(user *model.User) error {
-	return dao.DB.Save(user).Error
+// GetConversationPeerIDs returns every other user sharing at least one
+// conversation with the given user.
+func (dao *UserDAO) GetConversationPeerIDs(userID string) ([]string, error) {
+	var peerIDs []string
+	conversationIDs := dao.DB.Table("user_conversations").Select("conversation_id").Where("user_id = ?", userID)
+	err := dao.DB.Table("user_conversations").
+		Distinct("user_id").
+		Where("conversation_id IN (?)", conversationIDs).
+		Where("user_id <> ?", userID).
+		Pluck("user_id", &peerIDs).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return peerIDs, nil
+}
+
+// ReplaceAvatar sets the user's avatar, or removes it when nil, and returns
+// the one it replaced. The row is locked so that of two uploads racing, each
+// gets back the avatar it really replaced.
+func (dao *UserDAO) ReplaceAvatar(userID string, avatar *model.Avatar, attachmentID *string) (*model.Avatar, error) {
+	var previous *model.Avatar
+	err := dao.DB.Transaction(func(tx *gorm.DB) error {
+		user := &model.User{}
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "avatar").First(user, "id = ?", userID).Error
+		if err != nil {
+			return err
+		}
+		previous = user.Avatar
+
+		changes := &model.User{Avatar: avatar, ProfilePictureID: attachmentID}
+		if avatar != nil {
+			changes.ProfilePicture = &avatar.Variants[len(avatar.Variants)-1].URL
+		}
+		return tx.Model(&model.User{}).
+			Where("id = ?", userID).
+			Select("avatar", "profile_picture", "profile_picture_id").
+			Updates(changes).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return previous, nil
+}
+
+// userSearchRow is a user with the number of conversations they share with
+// the searching user.
+type userSearchRow struct {
+	model.User          `gorm:"embedded"`
+	SharedConversations int64
+	IsContact           bool
+}
+
+// SearchUsers finds the users the searcher may discover by a prefix or a
+// trigram match of their username or display name, or by their exact email
+// when byEmail is set. Handles matched exactly come first, then the
+// searcher's contacts, then users sharing the most conversations with the
+// searcher, then the closest names.
+func (dao *UserDAO) SearchUsers(searcherID string, query string, byEmail bool, limit int, offset int) ([]*model.UserSearchResult, error) {
+	query = strings.ToLower(query)
+	prefix := likeEscaper.Replace(query) + "%"
+
+	shared := dao.DB.Table("user_conversations AS mine").
+		Select("theirs.user_id, COUNT(*) AS shared").
+		Joins("JOIN user_conversations AS theirs ON theirs.conversation_id = mine.conversation_id").
+		Where("mine.user_id = ? AND theirs.user_id <> ?", searcherID, searcherID).
+		Group("theirs.user_id")
+
+	db := dao.DB.Table("users").
+		Select("users.*, COALESCE(shared.shared, 0) AS shared_conversations, contacts.user_id IS NOT NULL AS is_contact").
+		Joins("LEFT JOIN (?) AS shared ON shared.user_id = users.id", shared).
+		Joins("LEFT JOIN contacts ON contacts.user_id = ? AND contacts.contact_id = users.id", searcherID).
+		Where("users.id <> ?", searcherID).
+		Where("users.discoverability = ? OR (users.discoverability = ? AND (shared.shared IS NOT NULL OR contacts.user_id IS NOT NULL))",
+			model.UserDiscoverabilityEveryone, model.UserDiscoverabilityConversationPeers)
+
+	if byEmail {
+		db = db.Where("lower(users.email) = ?", query)
+	} else {
+		db = db.Where("lower(users.username) LIKE ? OR lower(users.display_name) LIKE ? OR lower(users.username) % ? OR lower(users.display_name) % ?",
+			prefix, prefix, query, query)
+	}
+
+	rows := []*userSearchRow{}
+	err := db.
+		Clauses(clause.OrderBy{Expression: clause.Expr{
+			SQL: "lower(users.username) = ? DESC, is_contact DESC, shared_conversations DESC, " +
+				"(lower(users.username) LIKE ? OR lower(users.display_name) LIKE ?) DESC, " +
+				"GREATEST(similarity(lower(users.username), ?), similarity(lower(users.display_name), ?)) DESC, " +
+				"users.username, users.id",
+			Vars:               []interface{}{query, prefix, prefix, query, query},
+			WithoutParentheses: true,
+		}}).
+		Limit(limit).
+		Offset(offset).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*model.UserSearchResult, 0, len(rows))
+	for _, row := range rows {
+		user := row.User
+		results = append(results, &model.UserSearchResult{
+			User:                &user,
+			SharedConversations: row.SharedConversations,
+			IsContact:           row.IsContact,
+		})
+	}
+	return results, nil
+}
+
+func (dao *UserDAO) UpdateUserColumns(id string, columns map[string]interface{}) error {
+	return dao.DB.Model(&model.User{}).Where("id = ?", id).Updates(columns).Error
+}
+
+// UpdateUserColumnsUnique is UpdateUserColumns for columns with a unique
+// index, a value another user holds already is reported with ErrTaken.
+func (dao *UserDAO) UpdateUserColumnsUnique(id string, columns map[string]interface{}) error {
+	err := dao.UpdateUserColumns(id, columns)
+	if isUniqueViolation(err) {
+		return ErrTaken
+	}
+	return err
+}
+
+// CreateUserUnique is CreateUser reporting a username another user holds
+// already with ErrTaken.
+func (dao *UserDAO) CreateUserUnique(user *model.User) error {
+	err := dao.CreateUser(user)
+	if isUniqueViolation(err) {
+		return ErrTaken
+	}
+	return err
+}
+
+// GetUserForUpdate reads the user and locks their row until the end of the
+// transaction.
+func (dao *UserDAO) GetUserForUpdate(userID string) (*model.User, error) {
+	user := &model.User{}
+	err := dao.DB.Clauses(clause.Locking{Strength: "UPDATE"}).First(user, "id = ?", userID).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// LockUsername locks the row of the user holding the username, if any, until
+// the end of the transaction.
+func (dao *UserDAO) LockUsername(username string) error {
+	var userIDs []string
+	return dao.DB.Model(&model.User{}).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("lower(username) = lower(?)", username).
+		Pluck("id", &userIDs).Error
+}
+
+// ChangeUsername sets the user's username and records the change, when
+// there is one to record, together.
+func (dao *UserDAO) ChangeUsername(userID string, username string, change *model.UsernameChange) error {
+	return dao.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&model.User{}).Where("id = ?", userID).Update("username", username).Error
+		if isUniqueViolation(err) {
+			return ErrTaken
+		}
+		if err != nil {
+			return err
+		}
+
+		if change == nil {
+			return nil
+		}
+		return tx.Create(change).Error
+	})
+}
+
+// GetUsernameRedirect returns the latest change away from the username whose
+// grace period is still running.
+func (dao *UserDAO) GetUsernameRedirect(username string, now time.Time) (*model.UsernameChange, error) {
+	change := &model.UsernameChange{}
+	err := dao.DB.
+		Where("lower(old_username) = lower(?)", username).
+		Where("redirect_until > ?", now).
+		Order("changed_at DESC").
+		First(change).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return change, nil
+}
+
+func (dao *UserDAO) GetLastUsernameChange(userID string) (*model.UsernameChange, error) {
+	change := &model.UsernameChange{}
+	err := dao.DB.Where("user_id = ?", userID).Order("changed_at DESC").First(change).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return change, nil
+}
+
+// GetUsernameHistory lists the user's username changes, latest first.
+func (dao *UserDAO) GetUsernameHistory(userID string) ([]*model.UsernameChange, error) {
+	changes := []*model.UsernameChange{}
+	err := dao.DB.Where("user_id = ?", userID).Order("changed_at DESC").Find(&changes).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return changes, nil
 }
 
 func (dao *UserDAO) DeleteUser(id string) error {
 	return dao.DB.Delete(&model.User{}, "id = ?", id).Error
 }
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation
+}
@@ -1,6 +1,7 @@
 package database
 
 import (
+	"fmt"
 	"github.com/badaccuracyid/softeng_backend/src/model"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -33,11 +34,103 @@ func MigrateTables() error {
 		return err
 	}
 
+	// the membership row carries its own columns, both sides of the
+	// many2many have to know about it before migrating
+	err = db.SetupJoinTable(&model.Conversation{}, "Members", &model.ConversationMember{})
+	if err != nil {
+		return err
+	}
+
+	err = db.SetupJoinTable(&model.User{}, "Conversations", &model.ConversationMember{})
+	if err != nil {
+		return err
+	}
+
+	// checked before anything migrates the join table, the admin backfill
+	// below only runs the one time the role column is added
+	addingRoles := db.Migrator().HasTable("user_conversations") && !db.Migrator().HasColumn("user_conversations", "role")
+
+	err = db.AutoMigrate(&model.Attachment{})
+	if err != nil {
+		return err
+	}
+
+	// the user search indexes names by trigram
+	err = db.Exec("CREATE EXTENSION IF NOT EXISTS pg_trgm").Error
+	if err != nil {
+		return err
+	}
+
+	// usernames are unique regardless of case, users who only differ by case
+	// from another one are renamed before the index is built, with a suffix
+	// from their ID
+	if db.Migrator().HasTable(&model.User{}) && !db.Migrator().HasIndex(&model.User{}, "idx_users_username_lower") {
+		err = db.Exec(`UPDATE users SET username = left(username, 24) || '_' || substr(md5(id), 1, 7)
+		WHERE username <> '' AND id NOT IN (
+			SELECT DISTINCT ON (lower(username)) id FROM users WHERE username <> ''
+			ORDER BY lower(username), username, id
+		)`).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	// emails are stored trimmed and in lower case, and unique regardless of
+	// case. The lookup index was not unique at first, it is rebuilt.
+	if db.Migrator().HasTable(&model.User{}) {
+		var conflicts int64
+		err = db.Raw(`SELECT COUNT(*) FROM (
+			SELECT lower(trim(email)) FROM users GROUP BY lower(trim(email)) HAVING COUNT(*) > 1
+		) AS conflicting`).Scan(&conflicts).Error
+		if err != nil {
+			return err
+		}
+		if conflicts > 0 {
+			return fmt.Errorf("%d emails are used by several accounts differing only by case, merge them before starting", conflicts)
+		}
+
+		err = db.Exec("UPDATE users SET email = lower(trim(email)) WHERE email <> lower(trim(email))").Error
+		if err != nil {
+			return err
+		}
+
+		var lookupIndexes int64
+		err = db.Raw(`SELECT COUNT(*) FROM pg_indexes
+			WHERE tablename = 'users' AND indexname = 'idx_users_email_lower' AND indexdef NOT LIKE 'CREATE UNIQUE%'`).
+			Scan(&lookupIndexes).Error
+		if err != nil {
+			return err
+		}
+		if lookupIndexes > 0 {
+			err = db.Migrator().DropIndex(&model.User{}, "idx_users_email_lower")
+			if err != nil {
+				return err
+			}
+		}
+		// the case sensitive unique index it replaces
+		if db.Migrator().HasIndex(&model.User{}, "idx_users_email") {
+			err = db.Migrator().DropIndex(&model.User{}, "idx_users_email")
+			if err != nil {
+				return err
+			}
+		}
+	}
+
 	err = db.AutoMigrate(&model.User{})
 	if err != nil {
 		return err
 	}
 
+	err = db.AutoMigrate(&model.UsernameChange{})
+	if err != nil {
+		return err
+	}
+
+	err = db.AutoMigrate(&model.Contact{}, &model.ContactRequest{})
+	if err != nil {
+		return err
+	}
+
 	err = db.AutoMigrate(&model.Message{})
 	if err != nil {
 		return err
@@ -48,6 +141,84 @@ func MigrateTables() error {
 		return err
 	}
 
+	// conversations from before member roles have no admin, the member who
+	// wrote first, most likely their creator, becomes one. Later conversations
+	// get their admin when they are created.
+	if addingRoles {
+		err = db.Exec(`UPDATE user_conversations SET role = ? FROM (
+			SELECT DISTINCT ON (m.conversation_id) m.conversation_id, m.user_id
+			FROM user_conversations m JOIN conversations c ON c.id = m.conversation_id
+			WHERE c.kind <> ?
+			ORDER BY m.conversation_id,
+				(SELECT MIN(created_at) FROM messages WHERE messages.conversation_id = m.conversation_id
+					AND messages.sender_id = m.user_id) NULLS LAST,
+				m.user_id
+		) AS earliest
+		WHERE user_conversations.conversation_id = earliest.conversation_id
+			AND user_conversations.user_id = earliest.user_id`,
+			model.MemberRoleAdmin, model.ConversationKindDirect).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	err = db.AutoMigrate(&model.MessageReaction{}, &model.MessageView{}, &model.MessageIdempotencyKey{})
+	if err != nil {
+		return err
+	}
+
+	err = db.AutoMigrate(&model.LinkPreview{})
+	if err != nil {
+		return err
+	}
+
+	err = db.AutoMigrate(&model.Poll{}, &model.PollOption{}, &model.PollVote{})
+	if err != nil {
+		return err
+	}
+
+	err = db.AutoMigrate(&model.MessageDraft{}, &model.MessageDraftAttachment{})
+	if err != nil {
+		return err
+	}
+
+	err = db.AutoMigrate(&model.PinnedMessage{})
+	if err != nil {
+		return err
+	}
+
+	err = db.AutoMigrate(&model.ChatFolder{}, &model.ChatFolderConversation{})
+	if err != nil {
+		return err
+	}
+
+	err = db.AutoMigrate(&model.ScheduledMessage{})
+	if err != nil {
+		return err
+	}
+
+	err = db.AutoMigrate(&model.ConversationInvite{})
+	if err != nil {
+		return err
+	}
+
+	// a user can only have one pending request per conversation, duplicates
+	// left by racing joins are rejected but the oldest
+	if db.Migrator().HasTable(&model.JoinRequest{}) {
+		err = db.Exec(`UPDATE join_requests SET status = ? WHERE status = ? AND id NOT IN (
+			SELECT DISTINCT ON (conversation_id, user_id) id FROM join_requests
+			WHERE status = ? ORDER BY conversation_id, user_id, created_at, id
+		)`, model.JoinRequestStatusRejected, model.JoinRequestStatusPending, model.JoinRequestStatusPending).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	err = db.AutoMigrate(&model.JoinRequest{})
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
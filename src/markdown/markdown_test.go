@@ -0,0 +1,220 @@
+package markdown
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/badaccuracyid/softeng_backend/src/utils"
+)
+
+func toHTML(t *testing.T, source string) string {
+	t.Helper()
+	document, err := Parse(source)
+	if err != nil {
+		t.Fatalf("Parse(%q): %v", source, err)
+	}
+	return RenderHTML(document)
+}
+
+func TestRenderHTMLEscapes(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{"raw html", `<script>alert(1)</script>`, `<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>`},
+		{"entities", `a & "b" 'c'`, `<p>a &amp; &#34;b&#34; &#39;c&#39;</p>`},
+		{"emphasis", `**bold** and _<i>_`, `<p><strong>bold</strong> and <em>&lt;i&gt;</em></p>`},
+		{"code span", "`<b>`", `<p><code>&lt;b&gt;</code></p>`},
+		{"link label", `[<img src=x>](https://example.com)`, `<p><a href="https://example.com" rel="nofollow noopener noreferrer" target="_blank">&lt;img src=x&gt;</a></p>`},
+		{"quote in address", `[x](https://example.com/"onmouseover=alert)`, `<p><a href="https://example.com/%22onmouseover=alert" rel="nofollow noopener noreferrer" target="_blank">x</a></p>`},
+		{"ampersand in address", `[x](https://example.com/?a=1&b=2)`, `<p><a href="https://example.com/?a=1&amp;b=2" rel="nofollow noopener noreferrer" target="_blank">x</a></p>`},
+		{"escaped markers", `\*not bold\*`, `<p>*not bold*</p>`},
+		{"control characters", "a\x00b\x1bc", `<p>abc</p>`},
+		{"line endings", "a\r\nb", `<p>a<br>b</p>`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := toHTML(t, test.source); got != test.want {
+				t.Errorf("RenderHTML(%q)\n got %s\nwant %s", test.source, got, test.want)
+			}
+		})
+	}
+}
+
+func TestLinkSchemes(t *testing.T) {
+	tests := []struct {
+		address string
+		// empty when the link is dropped and only its label is kept
+		want string
+	}{
+		{"javascript:alert(1)", ""},
+		{"JaVaScRiPt:alert(1)", ""},
+		{"data:text/html;base64,PHNjcmlwdD4=", ""},
+		{"vbscript:msgbox(1)", ""},
+		{"file:///etc/passwd", ""},
+		{"//example.com/path", ""},
+		{"/relative/path", ""},
+		{"https:///path", ""},
+		{"mailto:", ""},
+		{"https://" + strings.Repeat("a", maxURLLength), ""},
+		{"https://example.com/path", "https://example.com/path"},
+		{"HTTP://example.com", "http://example.com"},
+		{"www.example.com", "https://www.example.com"},
+		{"mailto:someone@example.com", "mailto:someone@example.com"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.address, func(t *testing.T) {
+			want := "<p>label</p>"
+			if test.want != "" {
+				want = `<p><a href="` + test.want + `" rel="nofollow noopener noreferrer" target="_blank">label</a></p>`
+			}
+			if got := toHTML(t, "[label]("+test.address+")"); got != want {
+				t.Errorf("got %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestNesting(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			"link at the deepest inline level",
+			"**a *b __c [d](https://example.com) c__ b* a**",
+			`<p><strong>a <em>b <strong>c <a href="https://example.com" rel="nofollow noopener noreferrer" target="_blank">d</a> c</strong> b</em> a</strong></p>`,
+		},
+		{
+			"link past the deepest inline level",
+			"**a *b __c _d [e](https://example.com) d_ c__ b* a**",
+			`<p><strong>a <em>b <strong>c <em>d [e](https://example.com) d</em> c</strong> b</em> a</strong></p>`,
+		},
+		{
+			"link inside a link label",
+			"[**[inner](https://a.example)**](https://b.example)",
+			`<p>[<strong><a href="https://a.example" rel="nofollow noopener noreferrer" target="_blank">inner</a></strong>](https://b.example)</p>`,
+		},
+		{
+			"escaped brackets inside a link label",
+			`[\[inner\](https://a.example)](https://b.example)`,
+			`<p><a href="https://b.example" rel="nofollow noopener noreferrer" target="_blank">[inner](https://a.example)</a></p>`,
+		},
+		{
+			"quote",
+			"> quoted\n> **text**",
+			`<blockquote><p>quoted<br><strong>text</strong></p></blockquote>`,
+		},
+		{
+			"quote inside a quote",
+			"> > inner",
+			`<blockquote><p>&gt; inner</p></blockquote>`,
+		},
+		{
+			"nested list flattened",
+			"- one\n  - two\n- three",
+			`<ul><li>one</li><li>two</li><li>three</li></ul>`,
+		},
+		{
+			"ordered list start",
+			"3. three\n4. four",
+			`<ol start="3"><li>three</li><li>four</li></ol>`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := toHTML(t, test.source); got != test.want {
+				t.Errorf("RenderHTML(%q)\n got %s\nwant %s", test.source, got, test.want)
+			}
+		})
+	}
+}
+
+func TestCodeBlocks(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{"language", "```Go\nfmt.Println(\"<x>\")\n```", `<pre><code class="language-go">fmt.Println(&#34;&lt;x&gt;&#34;)</code></pre>`},
+		{"no language", "```\n**not bold**\n```", `<pre><code>**not bold**</code></pre>`},
+		{"unsafe language", "```go\" onclick=\"alert(1)\nx\n```", `<pre><code>x</code></pre>`},
+		{"long language", "```" + strings.Repeat("a", maxLanguageLen+1) + "\nx\n```", `<pre><code>x</code></pre>`},
+		{"unclosed", "```\na\n\nb", "<pre><code>a\n\nb</code></pre>"},
+		{"text around", "before\n```\ncode\n```\nafter", `<p>before</p><pre><code>code</code></pre><p>after</p>`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := toHTML(t, test.source); got != test.want {
+				t.Errorf("RenderHTML(%q)\n got %s\nwant %s", test.source, got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		tooLong bool
+	}{
+		{"longest source", strings.Repeat("a", maxSourceLen), false},
+		{"source too long", strings.Repeat("a", maxSourceLen+1), true},
+		{"most nodes", strings.Repeat("`a`", maxNodes-1), false},
+		{"too many nodes", strings.Repeat("`a`", maxNodes+1), true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Parse(test.source)
+			if !test.tooLong {
+				if err != nil {
+					t.Errorf("Parse: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrTooComplex) || !errors.Is(err, utils.ErrInvalidInput) {
+				t.Errorf("Parse error = %v, want ErrTooComplex", err)
+			}
+		})
+	}
+}
+
+func TestRenderPlainText(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{"emphasis", "**bold** _italic_ `code`", "bold italic code"},
+		{"link", "[site](https://example.com)", "site (https://example.com)"},
+		{"bare link", "[https://example.com](https://example.com)", "https://example.com"},
+		{"mail link", "[someone@example.com](mailto:someone@example.com)", "someone@example.com"},
+		{"unsafe link", "[click](javascript:alert(1))", "click"},
+		{"html kept as text", "<b>hi</b> & bye", "<b>hi</b> & bye"},
+		{"paragraphs", "one\ntwo\n\nthree", "one\ntwo\n\nthree"},
+		{"quote", "> one\n> two", "> one\n> two"},
+		{"bullet list", "- one\n  more\n- two", "- one\n  more\n- two"},
+		{"ordered list", "3. three\n4. four", "3. three\n4. four"},
+		{"code block", "```\n  indented\n```", "indented"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			document, err := Parse(test.source)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if got := RenderPlainText(document); got != test.want {
+				t.Errorf("RenderPlainText(%q) = %q, want %q", test.source, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,483 @@
+// Package markdown parses the Markdown subset of formatted messages into
+// their canonical tree and renders that tree back as HTML or plain text.
+//
+// Anything outside the subset, raw HTML included, is kept as literal text,
+// and links are only kept when they point to http, https or mailto.
+package markdown
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode"
+
+	"github.com/badaccuracyid/softeng_backend/src/model"
+	"github.com/badaccuracyid/softeng_backend/src/utils"
+)
+
+const (
+	// quotes inside quotes are kept as text
+	maxQuoteDepth = 1
+	// bold inside italics inside a link and so on
+	maxInlineDepth = 4
+	maxSourceLen   = 8192
+	maxNodes       = 2000
+	maxURLLength   = 2048
+	maxLanguageLen = 32
+)
+
+var ErrTooComplex = fmt.Errorf("%w: formatted text is too complex", utils.ErrInvalidInput)
+
+var allowedSchemes = map[string]bool{
+	"http":   true,
+	"https":  true,
+	"mailto": true,
+}
+
+type parser struct {
+	nodes int
+}
+
+// Parse reads source into a DOCUMENT node. It only fails when the text is
+// longer or has more nodes than a message may carry.
+func Parse(source string) (*model.FormattedNode, error) {
+	if len(source) > maxSourceLen {
+		return nil, ErrTooComplex
+	}
+
+	p := &parser{}
+	lines := strings.Split(normalize(source), "\n")
+	document := &model.FormattedNode{
+		Type:     model.FormattedNodeTypeDocument,
+		Children: p.parseBlocks(lines, 0),
+	}
+
+	if p.nodes > maxNodes {
+		return nil, ErrTooComplex
+	}
+	return document, nil
+}
+
+// normalize unifies line endings and drops control characters, which have no
+// business in a chat message and could fool a renderer.
+func normalize(source string) string {
+	source = strings.ReplaceAll(source, "\r\n", "\n")
+	source = strings.ReplaceAll(source, "\r", "\n")
+	return strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\t' {
+			return r
+		}
+		if unicode.IsControl(r) || r == unicode.ReplacementChar {
+			return -1
+		}
+		return r
+	}, source)
+}
+
+func (p *parser) node(node *model.FormattedNode) *model.FormattedNode {
+	p.nodes++
+	return node
+}
+
+func (p *parser) parseBlocks(lines []string, depth int) []*model.FormattedNode {
+	blocks := []*model.FormattedNode{}
+	for i := 0; i < len(lines); {
+		trimmed := strings.TrimSpace(lines[i])
+		switch {
+		case trimmed == "":
+			i++
+
+		case isFence(trimmed):
+			language := codeLanguage(strings.TrimSpace(trimmed[3:]))
+			code := []string{}
+			for i++; i < len(lines) && !isFence(strings.TrimSpace(lines[i])); i++ {
+				code = append(code, lines[i])
+			}
+			// skip the closing fence, an unclosed block runs to the end
+			i++
+			blocks = append(blocks, p.node(&model.FormattedNode{
+				Type:     model.FormattedNodeTypeCodeBlock,
+				Text:     strings.Join(code, "\n"),
+				Language: language,
+			}))
+
+		case depth < maxQuoteDepth && isQuote(trimmed):
+			quoted := []string{}
+			for ; i < len(lines) && isQuote(strings.TrimSpace(lines[i])); i++ {
+				quoted = append(quoted, unquote(strings.TrimSpace(lines[i])))
+			}
+			if children := p.parseBlocks(quoted, depth+1); len(children) > 0 {
+				blocks = append(blocks, p.node(&model.FormattedNode{
+					Type:     model.FormattedNodeTypeQuote,
+					Children: children,
+				}))
+			}
+
+		case isListItem(trimmed):
+			var list *model.FormattedNode
+			list, i = p.parseList(lines, i)
+			blocks = append(blocks, list)
+
+		default:
+			paragraph := []string{}
+			for ; i < len(lines) && !startsBlock(lines[i], depth); i++ {
+				paragraph = append(paragraph, strings.TrimSpace(lines[i]))
+			}
+			if children := p.parseInline(strings.Join(paragraph, "\n"), 0, true); len(children) > 0 {
+				blocks = append(blocks, p.node(&model.FormattedNode{
+					Type:     model.FormattedNodeTypeParagraph,
+					Children: children,
+				}))
+			}
+		}
+	}
+	return blocks
+}
+
+// parseList reads the items of the list starting at lines[start]. Indented
+// lines that are not items continue the item above them, nested lists are
+// flattened into the outer one.
+func (p *parser) parseList(lines []string, start int) (*model.FormattedNode, int) {
+	first := strings.TrimSpace(lines[start])
+	ordered, number, _ := listMarker(first)
+
+	list := p.node(&model.FormattedNode{Type: model.FormattedNodeTypeBulletList})
+	if ordered {
+		list.Type = model.FormattedNodeTypeOrderedList
+		list.Start = number
+	}
+
+	items := [][]string{}
+	i := start
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			break
+		}
+
+		if isListItem(trimmed) {
+			itemOrdered, _, text := listMarker(trimmed)
+			if itemOrdered != ordered {
+				break
+			}
+			items = append(items, []string{text})
+			continue
+		}
+		if !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t") {
+			break
+		}
+		items[len(items)-1] = append(items[len(items)-1], trimmed)
+	}
+
+	for _, item := range items {
+		list.Children = append(list.Children, p.node(&model.FormattedNode{
+			Type:     model.FormattedNodeTypeListItem,
+			Children: p.parseInline(strings.Join(item, "\n"), 0, true),
+		}))
+	}
+	return list, i
+}
+
+// parseInline reads the spans of a paragraph or list item. Links are not
+// allowed inside the text of another link.
+func (p *parser) parseInline(text string, depth int, links bool) []*model.FormattedNode {
+	nodes := []*model.FormattedNode{}
+	var buffer strings.Builder
+	flush := func() {
+		if buffer.Len() > 0 {
+			nodes = appendText(nodes, buffer.String())
+			buffer.Reset()
+		}
+	}
+
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == '\\' && i+1 < len(text) && isEscapable(text[i+1]):
+			buffer.WriteByte(text[i+1])
+			i += 2
+			continue
+
+		case c == '\n':
+			flush()
+			nodes = append(nodes, p.node(&model.FormattedNode{Type: model.FormattedNodeTypeLineBreak}))
+			i++
+			continue
+
+		case c == '`':
+			if end := strings.IndexByte(text[i+1:], '`'); end > 0 {
+				flush()
+				nodes = append(nodes, p.node(&model.FormattedNode{
+					Type: model.FormattedNodeTypeCode,
+					Text: text[i+1 : i+1+end],
+				}))
+				i += end + 2
+				continue
+			}
+
+		case (c == '*' || c == '_') && depth < maxInlineDepth:
+			if node, end := p.parseEmphasis(text, i, depth, links); node != nil {
+				flush()
+				nodes = append(nodes, node)
+				i = end
+				continue
+			}
+
+		case c == '[' && links && depth < maxInlineDepth:
+			if children, end, ok := p.parseLink(text, i, depth); ok {
+				flush()
+				for _, child := range children {
+					if child.Type == model.FormattedNodeTypeText {
+						nodes = appendText(nodes, child.Text)
+					} else {
+						nodes = append(nodes, child)
+					}
+				}
+				i = end
+				continue
+			}
+		}
+
+		buffer.WriteByte(c)
+		i++
+	}
+	flush()
+
+	return trimLineBreaks(nodes)
+}
+
+// parseEmphasis reads the bold or italic span opened at text[start], it
+// returns nil when the marker is never closed and is then plain text.
+func (p *parser) parseEmphasis(text string, start int, depth int, links bool) (*model.FormattedNode, int) {
+	c := text[start]
+	marker := text[start : start+1]
+	nodeType := model.FormattedNodeTypeItalic
+	if start+1 < len(text) && text[start+1] == c {
+		marker = text[start : start+2]
+		nodeType = model.FormattedNodeTypeBold
+	}
+
+	open := start + len(marker)
+	if open >= len(text) || isSpace(text[open]) {
+		return nil, 0
+	}
+	// underscores inside words, like snake_case, are not emphasis
+	if c == '_' && start > 0 && isWordByte(text[start-1]) {
+		return nil, 0
+	}
+
+	for i := open + 1; i < len(text); i++ {
+		switch text[i] {
+		case '\\':
+			i++
+			continue
+		case '`':
+			if end := strings.IndexByte(text[i+1:], '`'); end > 0 {
+				i += end + 1
+			}
+			continue
+		case '\n':
+			return nil, 0
+		}
+
+		if !strings.HasPrefix(text[i:], marker) || isSpace(text[i-1]) {
+			continue
+		}
+		if len(marker) == 1 && ((i+1 < len(text) && text[i+1] == c) || text[i-1] == c) {
+			continue
+		}
+		end := i + len(marker)
+		if c == '_' && end < len(text) && isWordByte(text[end]) {
+			continue
+		}
+
+		children := p.parseInline(text[open:i], depth+1, links)
+		if len(children) == 0 {
+			return nil, 0
+		}
+		return p.node(&model.FormattedNode{Type: nodeType, Children: children}), end
+	}
+	return nil, 0
+}
+
+// parseLink reads [label](url) at text[start]. A link to an unsafe address
+// loses the link and only its label is kept.
+func (p *parser) parseLink(text string, start int, depth int) ([]*model.FormattedNode, int, bool) {
+	closeLabel := -1
+	for i := start + 1; i < len(text); i++ {
+		if text[i] == '\\' {
+			i++
+			continue
+		}
+		if text[i] == '[' || text[i] == '\n' {
+			return nil, 0, false
+		}
+		if text[i] == ']' {
+			closeLabel = i
+			break
+		}
+	}
+	if closeLabel <= start+1 || closeLabel+1 >= len(text) || text[closeLabel+1] != '(' {
+		return nil, 0, false
+	}
+
+	// parentheses inside the address have to be balanced
+	closeURL, open := -1, 0
+	for i := closeLabel + 2; i < len(text) && closeURL < 0; i++ {
+		switch text[i] {
+		case ' ', '\t', '\n':
+			return nil, 0, false
+		case '(':
+			open++
+		case ')':
+			if open == 0 {
+				closeURL = i
+			}
+			open--
+		}
+	}
+	if closeURL < 0 {
+		return nil, 0, false
+	}
+	address := text[closeLabel+2 : closeURL]
+
+	label := p.parseInline(text[start+1:closeLabel], depth+1, false)
+	safe, ok := safeURL(address)
+	if !ok {
+		return label, closeURL + 1, true
+	}
+	return []*model.FormattedNode{p.node(&model.FormattedNode{
+		Type:     model.FormattedNodeTypeLink,
+		URL:      safe,
+		Children: label,
+	})}, closeURL + 1, true
+}
+
+// safeURL only lets through absolute http, https and mailto addresses, an
+// address starting with www. is taken to be https.
+func safeURL(address string) (string, bool) {
+	if address == "" || len(address) > maxURLLength {
+		return "", false
+	}
+	if strings.HasPrefix(strings.ToLower(address), "www.") {
+		address = "https://" + address
+	}
+
+	parsed, err := url.Parse(address)
+	if err != nil || !allowedSchemes[strings.ToLower(parsed.Scheme)] {
+		return "", false
+	}
+	parsed.Scheme = strings.ToLower(parsed.Scheme)
+	if parsed.Scheme != "mailto" && parsed.Host == "" {
+		return "", false
+	}
+	if parsed.Scheme == "mailto" && parsed.Opaque == "" {
+		return "", false
+	}
+	return parsed.String(), true
+}
+
+func appendText(nodes []*model.FormattedNode, text string) []*model.FormattedNode {
+	if text == "" {
+		return nodes
+	}
+	if len(nodes) > 0 && nodes[len(nodes)-1].Type == model.FormattedNodeTypeText {
+		nodes[len(nodes)-1].Text += text
+		return nodes
+	}
+	return append(nodes, &model.FormattedNode{Type: model.FormattedNodeTypeText, Text: text})
+}
+
+func trimLineBreaks(nodes []*model.FormattedNode) []*model.FormattedNode {
+	for len(nodes) > 0 && nodes[0].Type == model.FormattedNodeTypeLineBreak {
+		nodes = nodes[1:]
+	}
+	for len(nodes) > 0 && nodes[len(nodes)-1].Type == model.FormattedNodeTypeLineBreak {
+		nodes = nodes[:len(nodes)-1]
+	}
+	return nodes
+}
+
+func startsBlock(line string, depth int) bool {
+	trimmed := strings.TrimSpace(line)
+	return trimmed == "" || isFence(trimmed) || isListItem(trimmed) ||
+		(depth < maxQuoteDepth && isQuote(trimmed))
+}
+
+func isFence(line string) bool {
+	return strings.HasPrefix(line, "```")
+}
+
+func isQuote(line string) bool {
+	return strings.HasPrefix(line, ">")
+}
+
+func unquote(line string) string {
+	line = strings.TrimPrefix(line, ">")
+	return strings.TrimPrefix(line, " ")
+}
+
+func isListItem(line string) bool {
+	_, number, text := listMarker(line)
+	return number > 0 || text != ""
+}
+
+// listMarker splits "- text", "* text", "+ text", "3. text" and "3) text"
+// into the kind of list, the number of ordered items and the item text. A
+// line that is not an item returns zero values.
+func listMarker(line string) (bool, int, string) {
+	if len(line) >= 2 && strings.ContainsRune("-*+", rune(line[0])) && line[1] == ' ' {
+		if text := strings.TrimSpace(line[2:]); text != "" {
+			return false, 0, text
+		}
+		return false, 0, ""
+	}
+
+	digits := 0
+	for digits < len(line) && digits < 9 && line[digits] >= '0' && line[digits] <= '9' {
+		digits++
+	}
+	if digits == 0 || digits+1 >= len(line) || (line[digits] != '.' && line[digits] != ')') || line[digits+1] != ' ' {
+		return false, 0, ""
+	}
+	text := strings.TrimSpace(line[digits+2:])
+	if text == "" {
+		return false, 0, ""
+	}
+
+	number := 0
+	for _, digit := range line[:digits] {
+		number = number*10 + int(digit-'0')
+	}
+	if number == 0 {
+		number = 1
+	}
+	return true, number, text
+}
+
+// codeLanguage keeps the language name of a code block when it looks like
+// one, since it ends up in a class attribute.
+func codeLanguage(language string) string {
+	if language == "" || len(language) > maxLanguageLen {
+		return ""
+	}
+	for _, r := range language {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("+#.-_", r)) {
+			return ""
+		}
+	}
+	return strings.ToLower(language)
+}
+
+func isEscapable(c byte) bool {
+	return strings.IndexByte("\\`*_[]()>#+-.!|~", c) >= 0
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n'
+}
+
+func isWordByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80
+}
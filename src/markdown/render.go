@@ -0,0 +1,130 @@
+package markdown
+
+import (
+	"html"
+	"strconv"
+	"strings"
+
+	"github.com/badaccuracyid/softeng_backend/src/model"
+)
+
+// RenderHTML writes the tree as HTML. Every text is escaped and only the tags
+// the node types map to are written, so the result is safe to embed.
+func RenderHTML(node *model.FormattedNode) string {
+	var builder strings.Builder
+	renderHTML(&builder, node)
+	return builder.String()
+}
+
+// RenderPlainText writes the tree without formatting, for notifications and
+// exports. Links keep their address next to their label.
+func RenderPlainText(node *model.FormattedNode) string {
+	return strings.TrimSpace(plainText(node))
+}
+
+func renderHTML(builder *strings.Builder, node *model.FormattedNode) {
+	switch node.Type {
+	case model.FormattedNodeTypeText:
+		builder.WriteString(html.EscapeString(node.Text))
+		return
+	case model.FormattedNodeTypeLineBreak:
+		builder.WriteString("<br>")
+		return
+	case model.FormattedNodeTypeCode:
+		builder.WriteString("<code>" + html.EscapeString(node.Text) + "</code>")
+		return
+	case model.FormattedNodeTypeCodeBlock:
+		builder.WriteString("<pre><code")
+		if node.Language != "" {
+			builder.WriteString(` class="language-` + html.EscapeString(node.Language) + `"`)
+		}
+		builder.WriteString(">" + html.EscapeString(node.Text) + "</code></pre>")
+		return
+	}
+
+	open, close := "", ""
+	switch node.Type {
+	case model.FormattedNodeTypeParagraph:
+		open, close = "<p>", "</p>"
+	case model.FormattedNodeTypeBold:
+		open, close = "<strong>", "</strong>"
+	case model.FormattedNodeTypeItalic:
+		open, close = "<em>", "</em>"
+	case model.FormattedNodeTypeLink:
+		open = `<a href="` + html.EscapeString(node.URL) + `" rel="nofollow noopener noreferrer" target="_blank">`
+		close = "</a>"
+	case model.FormattedNodeTypeQuote:
+		open, close = "<blockquote>", "</blockquote>"
+	case model.FormattedNodeTypeBulletList:
+		open, close = "<ul>", "</ul>"
+	case model.FormattedNodeTypeOrderedList:
+		open, close = "<ol>", "</ol>"
+		if node.Start > 1 {
+			open = `<ol start="` + strconv.Itoa(node.Start) + `">`
+		}
+	case model.FormattedNodeTypeListItem:
+		open, close = "<li>", "</li>"
+	}
+
+	builder.WriteString(open)
+	for _, child := range node.Children {
+		renderHTML(builder, child)
+	}
+	builder.WriteString(close)
+}
+
+func plainText(node *model.FormattedNode) string {
+	switch node.Type {
+	case model.FormattedNodeTypeText, model.FormattedNodeTypeCode, model.FormattedNodeTypeCodeBlock:
+		return node.Text
+	case model.FormattedNodeTypeLineBreak:
+		return "\n"
+
+	case model.FormattedNodeTypeLink:
+		label := inlinePlainText(node.Children)
+		if label == node.URL || strings.TrimPrefix(node.URL, "mailto:") == label {
+			return label
+		}
+		return label + " (" + node.URL + ")"
+
+	case model.FormattedNodeTypeDocument:
+		return blocksPlainText(node.Children)
+
+	case model.FormattedNodeTypeQuote:
+		lines := strings.Split(blocksPlainText(node.Children), "\n")
+		for i, line := range lines {
+			lines[i] = strings.TrimRight("> "+line, " ")
+		}
+		return strings.Join(lines, "\n")
+
+	case model.FormattedNodeTypeBulletList, model.FormattedNodeTypeOrderedList:
+		items := make([]string, len(node.Children))
+		for i, item := range node.Children {
+			marker := "- "
+			if node.Type == model.FormattedNodeTypeOrderedList {
+				marker = strconv.Itoa(node.Start+i) + ". "
+			}
+			text := inlinePlainText(item.Children)
+			items[i] = marker + strings.ReplaceAll(text, "\n", "\n"+strings.Repeat(" ", len(marker)))
+		}
+		return strings.Join(items, "\n")
+	}
+
+	return inlinePlainText(node.Children)
+}
+
+func inlinePlainText(nodes []*model.FormattedNode) string {
+	var builder strings.Builder
+	for _, node := range nodes {
+		builder.WriteString(plainText(node))
+	}
+	return builder.String()
+}
+
+func blocksPlainText(blocks []*model.FormattedNode) string {
+	texts := make([]string, len(blocks))
+	for i, block := range blocks {
+		texts[i] = plainText(block)
+	}
+	return strings.Join(texts, "\n\n")
+}
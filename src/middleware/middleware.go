@@ -7,6 +7,11 @@ import (
 func UserMiddleware() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		userId := ctx.GetHeader("X-User-Id")
+		if userId == "" {
+			// browsers cannot set headers on WebSocket and EventSource requests
+			userId = ctx.Query("userId")
+		}
+
 		if userId == "" {
 			ctx.Next()
 			return
@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+type Attachment struct {
+	ID          string    `json:"id" gorm:"primaryKey"`
+	UploaderID  string    `json:"uploaderId" gorm:"not null;index"`
+	FileName    string    `json:"fileName"`
+	ContentType string    `json:"contentType" gorm:"not null"`
+	Size        int64     `json:"size" gorm:"not null"`
+	StorageKey  string    `json:"-" gorm:"not null"`
+	URL         string    `json:"url" gorm:"not null"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// IsImage reports whether the attachment can be used where a picture is
+// expected, such as a group avatar.
+func (a *Attachment) IsImage() bool {
+	switch a.ContentType {
+	case "image/png", "image/jpeg", "image/gif", "image/webp":
+		return true
+	}
+	return false
+}
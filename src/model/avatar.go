@@ -0,0 +1,25 @@
+package model
+
+// Avatar is a profile picture cropped to a square and stored in several
+// sizes, smallest first. Pictures smaller than a size are not scaled up, so
+// the larger sizes may be missing.
+type Avatar struct {
+	ID       string           `json:"id"`
+	Variants []*AvatarVariant `json:"variants"`
+}
+
+type AvatarVariant struct {
+	Size int    `json:"size"`
+	URL  string `json:"url"`
+}
+
+// Variant is the smallest variant at least as large as the size, or the
+// largest one there is.
+func (a *Avatar) Variant(size int) *AvatarVariant {
+	for _, variant := range a.Variants {
+		if variant.Size >= size {
+			return variant
+		}
+	}
+	return a.Variants[len(a.Variants)-1]
+}
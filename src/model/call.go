@@ -0,0 +1,123 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// CallSession only lives in memory while the call is ringing or active, the
+// history is kept as a SYSTEM message holding a CallSummary.
+type CallSession struct {
+	ID             string                          `json:"id"`
+	ConversationID string                          `json:"conversationId"`
+	InitiatorID    string                          `json:"initiatorId"`
+	Media          CallMedia                       `json:"media"`
+	State          CallState                       `json:"state"`
+	Participants   map[string]CallParticipantState `json:"participants"`
+	StartedAt      time.Time                       `json:"startedAt"`
+	AnsweredAt     *time.Time                      `json:"answeredAt,omitempty"`
+	EndedAt        *time.Time                      `json:"endedAt,omitempty"`
+	Result         CallResult                      `json:"result,omitempty"`
+}
+
+type CallSummary struct {
+	CallID          string     `json:"callId"`
+	InitiatorID     string     `json:"initiatorId"`
+	Media           CallMedia  `json:"media"`
+	Result          CallResult `json:"result"`
+	DurationSeconds int64      `json:"durationSeconds"`
+	ParticipantIDs  []string   `json:"participantIds"`
+}
+
+// CallSignalPayload carries an SDP offer or answer, or an ICE candidate, from
+// one peer to another. The server never looks inside Data.
+type CallSignalPayload struct {
+	CallID     string          `json:"callId"`
+	FromUserID string          `json:"fromUserId"`
+	Data       json.RawMessage `json:"data"`
+}
+
+type CallMedia string
+
+const (
+	CallMediaAudio CallMedia = "AUDIO"
+	CallMediaVideo CallMedia = "VIDEO"
+)
+
+func (e CallMedia) IsValid() bool {
+	switch e {
+	case CallMediaAudio, CallMediaVideo:
+		return true
+	}
+	return false
+}
+
+func (e CallMedia) String() string {
+	return string(e)
+}
+
+type CallState string
+
+const (
+	CallStateRinging CallState = "RINGING"
+	CallStateActive  CallState = "ACTIVE"
+	CallStateEnded   CallState = "ENDED"
+)
+
+func (e CallState) IsValid() bool {
+	switch e {
+	case CallStateRinging, CallStateActive, CallStateEnded:
+		return true
+	}
+	return false
+}
+
+func (e CallState) String() string {
+	return string(e)
+}
+
+type CallParticipantState string
+
+const (
+	CallParticipantStateRinging  CallParticipantState = "RINGING"
+	CallParticipantStateJoined   CallParticipantState = "JOINED"
+	CallParticipantStateDeclined CallParticipantState = "DECLINED"
+	CallParticipantStateBusy     CallParticipantState = "BUSY"
+	CallParticipantStateMissed   CallParticipantState = "MISSED"
+	CallParticipantStateLeft     CallParticipantState = "LEFT"
+)
+
+func (e CallParticipantState) IsValid() bool {
+	switch e {
+	case CallParticipantStateRinging, CallParticipantStateJoined, CallParticipantStateDeclined,
+		CallParticipantStateBusy, CallParticipantStateMissed, CallParticipantStateLeft:
+		return true
+	}
+	return false
+}
+
+func (e CallParticipantState) String() string {
+	return string(e)
+}
+
+type CallResult string
+
+const (
+	CallResultCompleted CallResult = "COMPLETED"
+	CallResultMissed    CallResult = "MISSED"
+	CallResultDeclined  CallResult = "DECLINED"
+	CallResultBusy      CallResult = "BUSY"
+	CallResultCancelled CallResult = "CANCELLED"
+)
+
+func (e CallResult) IsValid() bool {
+	switch e {
+	case CallResultCompleted, CallResultMissed, CallResultDeclined, CallResultBusy, CallResultCancelled:
+		return true
+	}
+	return false
+}
+
+func (e CallResult) String() string {
+	return string(e)
+}
@@ -1,10 +1,36 @@
 package model
 
+import "time"
+
 type Conversation struct {
-	ID       string     `json:"id" gorm:"primaryKey"`
-	Title    string     `json:"title" gorm:"not null"`
-	Members  []*User    `json:"members" gorm:"many2many:user_conversations;"`
-	Messages []*Message `json:"messages" gorm:"foreignKey:ConversationID"`
+	ID          string           `json:"id" gorm:"primaryKey"`
+	Title       string           `json:"title" gorm:"not null"`
+	Kind        ConversationKind `json:"kind" gorm:"not null;default:GROUP"`
+	Description string           `json:"description"`
+	Topic       string           `json:"topic"`
+	AvatarID    *string          `json:"avatarId"`
+	Avatar      *Attachment      `json:"avatar" gorm:"foreignKey:AvatarID"`
+	CreatedAt   time.Time        `json:"createdAt"`
+	UpdatedAt   time.Time        `json:"updatedAt"`
+	// MessageTTL is the disappearing messages timer in seconds, messages sent
+	// while it is set expire that long after they were sent. 0 turns it off.
+	MessageTTL int64 `json:"messageTtl" gorm:"not null;default:0"`
+	// LastMessageAt orders the conversation lists
+	LastMessageAt *time.Time `json:"lastMessageAt" gorm:"index"`
+	// Settings holds the current user's membership, it is only set when
+	// listing the conversations of that user
+	Settings *ConversationMember `json:"settings,omitempty" gorm:"-"`
+	// UnreadCount is the number of unread messages of the current user, set
+	// together with Settings
+	UnreadCount int64 `json:"unreadCount" gorm:"-"`
+	// Draft is the current user's unsent message, set when listing
+	// conversations
+	Draft *MessageDraft `json:"draft,omitempty" gorm:"-"`
+	// DirectKey holds the sorted pair of member IDs of a DIRECT conversation,
+	// its unique index is what keeps a single DM per pair of users.
+	DirectKey *string    `json:"-" gorm:"uniqueIndex"`
+	Members   []*User    `json:"members" gorm:"many2many:user_conversations;"`
+	Messages  []*Message `json:"messages" gorm:"foreignKey:ConversationID"`
 }
 
 type Message struct {
@@ -15,22 +41,108 @@ type Message struct {
 	Conversation   Conversation       `json:"conversation" gorm:"foreignKey:ConversationID"`
 	Content        string             `json:"content"`
 	ContentType    MessageContentType `json:"contentType" gorm:"not null"`
-}
-
-type MessageSubscription struct {
-	MessageChannel chan *Message
-	DoneChannel    chan struct{}
+	// Format tells how Content is written, the tree parsed from MARKDOWN
+	// content is kept in Formatted
+	Format    MessageFormat  `json:"format" gorm:"not null;default:PLAIN"`
+	Formatted *FormattedNode `json:"formatted,omitempty" gorm:"type:jsonb;serializer:json"`
+	// ViewCount counts the distinct members who saw the message, it is kept
+	// for channels where listing every reader is not practical
+	ViewCount   int64         `json:"viewCount" gorm:"not null;default:0"`
+	Attachments []*Attachment `json:"attachments" gorm:"many2many:message_attachments;"`
+	Poll        *Poll         `json:"poll,omitempty" gorm:"foreignKey:MessageID"`
+	// LinkPreviews are added once the links of the message were fetched
+	LinkPreviews []*LinkPreview `json:"linkPreviews" gorm:"foreignKey:MessageID"`
+	// ClientMessageID is the idempotency key the sender's client picked
+	ClientMessageID *string `json:"clientMessageId,omitempty"`
+	// forwarding, the origin is the first message of a forwarding chain.
+	// ForwardedFromSenderID is left out when the original sender hides it.
+	ForwardedFromMessageID *string `json:"forwardedFromMessageId"`
+	ForwardedFromSenderID  *string `json:"forwardedFromSenderId"`
+	ForwardedFromName      string  `json:"forwardedFromName,omitempty"`
+	// ExpiresAt is set on messages sent while the disappearing messages timer
+	// of the conversation is on, the sweeper deletes them once it is past
+	ExpiresAt *time.Time `json:"expiresAt" gorm:"index"`
+	CreatedAt time.Time  `json:"createdAt"`
 }
 
 type CreateConversationInput struct {
 	Title     string   `json:"title"`
 	MemberIds []string `json:"memberIds"`
+	// Kind is GROUP when empty, DIRECT conversations have their own endpoint
+	// and a GROUP of two is turned into one
+	Kind ConversationKind `json:"kind"`
 }
+
+// UpdateConversationInput only changes the fields that are present, an empty
+// AvatarID removes the avatar.
+type UpdateConversationInput struct {
+	Title       *string `json:"title"`
+	Description *string `json:"description"`
+	Topic       *string `json:"topic"`
+	AvatarID    *string `json:"avatarId"`
+}
+
 type SendMessageInput struct {
+	// SenderID is always the current user, it may be left out
 	SenderID       string             `json:"senderId"`
 	ConversationID string             `json:"conversationId"`
 	Content        string             `json:"content"`
 	ContentType    MessageContentType `json:"contentType"`
+	// Format is PLAIN when empty, MARKDOWN is only allowed for TEXT messages
+	Format MessageFormat `json:"format"`
+	// AttachmentIDs are attachments the sender uploaded beforehand
+	AttachmentIDs []string `json:"attachmentIds"`
+	// ClientMessageID makes retries safe, sending again with the same ID
+	// returns the message created the first time
+	ClientMessageID string `json:"clientMessageId"`
+	// Poll is required for the POLL content type and ignored otherwise
+	Poll *CreatePollInput `json:"poll"`
+}
+
+// ForwardMessagesInput forwards every message to every target conversation,
+// in the order the messages were sent.
+type ForwardMessagesInput struct {
+	MessageIDs            []string `json:"messageIds"`
+	TargetConversationIDs []string `json:"targetConversationIds"`
+}
+
+type SetMessageTimerInput struct {
+	// MessageTTL in seconds, 0 turns disappearing messages off
+	MessageTTL int64 `json:"messageTtl"`
+}
+
+// MessagesDeletedPayload is published when messages are deleted for
+// everyone, for example once they disappeared.
+type MessagesDeletedPayload struct {
+	MessageIDs []string `json:"messageIds"`
+}
+
+type ConversationKind string
+
+const (
+	ConversationKindDirect ConversationKind = "DIRECT"
+	ConversationKindGroup  ConversationKind = "GROUP"
+	// ConversationKindChannel conversations are broadcast channels, only
+	// admins post and every other member reads.
+	ConversationKindChannel ConversationKind = "CHANNEL"
+)
+
+var AllConversationKind = []ConversationKind{
+	ConversationKindDirect,
+	ConversationKindGroup,
+	ConversationKindChannel,
+}
+
+func (e ConversationKind) IsValid() bool {
+	switch e {
+	case ConversationKindDirect, ConversationKindGroup, ConversationKindChannel:
+		return true
+	}
+	return false
+}
+
+func (e ConversationKind) String() string {
+	return string(e)
 }
 
 type MessageContentType string
@@ -38,16 +150,22 @@ type MessageContentType string
 const (
 	MessageContentTypeText  MessageContentType = "TEXT"
 	MessageContentTypeImage MessageContentType = "IMAGE"
+	MessageContentTypePoll  MessageContentType = "POLL"
+	// MessageContentTypeSystem messages are written by the server, such as
+	// call history entries, and cannot be sent by clients.
+	MessageContentTypeSystem MessageContentType = "SYSTEM"
 )
 
 var AllMessageContentType = []MessageContentType{
 	MessageContentTypeText,
 	MessageContentTypeImage,
+	MessageContentTypePoll,
+	MessageContentTypeSystem,
 }
 
 func (e MessageContentType) IsValid() bool {
 	switch e {
-	case MessageContentTypeText, MessageContentTypeImage:
+	case MessageContentTypeText, MessageContentTypeImage, MessageContentTypePoll, MessageContentTypeSystem:
 		return true
 	}
 	return false
@@ -0,0 +1,92 @@
+package model
+
+import "time"
+
+// Contact is one side of an accepted contact request, every pair of contacts
+// has a row for each of the two users.
+type Contact struct {
+	UserID    string    `json:"userId" gorm:"primaryKey"`
+	ContactID string    `json:"contactId" gorm:"primaryKey;index"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// ContactRequest is a request waiting for the recipient to accept or decline
+// it. PairKey allows a single request between two users, whoever sent it.
+type ContactRequest struct {
+	PairKey     string    `json:"-" gorm:"primaryKey"`
+	SenderID    string    `json:"senderId" gorm:"not null;index"`
+	RecipientID string    `json:"recipientId" gorm:"not null;index"`
+	CreatedAt   time.Time `json:"createdAt"`
+
+	Sender    *User `json:"sender,omitempty" gorm:"foreignKey:SenderID"`
+	Recipient *User `json:"recipient,omitempty" gorm:"foreignKey:RecipientID"`
+}
+
+// ContactEntry is a contact as listed to the user, with their presence.
+type ContactEntry struct {
+	User     *User     `json:"user"`
+	Presence *Presence `json:"presence"`
+	Since    time.Time `json:"since"`
+}
+
+// ContactRelation is where the current user stands with another user.
+type ContactRelation struct {
+	UserID string       `json:"userId"`
+	State  ContactState `json:"state"`
+}
+
+type ContactState string
+
+const (
+	ContactStateNone ContactState = "NONE"
+	// ContactStateOutgoing means the current user sent a request that is
+	// still pending, ContactStateIncoming that they received one
+	ContactStateOutgoing ContactState = "OUTGOING"
+	ContactStateIncoming ContactState = "INCOMING"
+	ContactStateContact  ContactState = "CONTACT"
+)
+
+var AllContactState = []ContactState{
+	ContactStateNone,
+	ContactStateOutgoing,
+	ContactStateIncoming,
+	ContactStateContact,
+}
+
+func (e ContactState) IsValid() bool {
+	switch e {
+	case ContactStateNone, ContactStateOutgoing, ContactStateIncoming, ContactStateContact:
+		return true
+	}
+	return false
+}
+
+func (e ContactState) String() string {
+	return string(e)
+}
+
+// DirectMessagePolicy decides who may start a conversation with the user,
+// a direct one or a group they are put in.
+type DirectMessagePolicy string
+
+const (
+	DirectMessagePolicyEveryone DirectMessagePolicy = "EVERYONE"
+	DirectMessagePolicyContacts DirectMessagePolicy = "CONTACTS"
+)
+
+var AllDirectMessagePolicy = []DirectMessagePolicy{
+	DirectMessagePolicyEveryone,
+	DirectMessagePolicyContacts,
+}
+
+func (e DirectMessagePolicy) IsValid() bool {
+	switch e {
+	case DirectMessagePolicyEveryone, DirectMessagePolicyContacts:
+		return true
+	}
+	return false
+}
+
+func (e DirectMessagePolicy) String() string {
+	return string(e)
+}
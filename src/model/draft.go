@@ -0,0 +1,38 @@
+package model
+
+import "time"
+
+// MessageDraft is the unsent message a user is composing in a conversation,
+// shared between the user's devices. The latest UpdatedAt wins, so a device
+// that was offline cannot overwrite a newer draft with an older one.
+type MessageDraft struct {
+	UserID           string  `json:"userId" gorm:"primaryKey"`
+	ConversationID   string  `json:"conversationId" gorm:"primaryKey"`
+	Content          string  `json:"content" gorm:"not null;default:''"`
+	ReplyToMessageID *string `json:"replyToMessageId"`
+
+	// Attachments are loaded from MessageDraftAttachment rows
+	Attachments []*Attachment `json:"attachments" gorm:"-"`
+
+	// UpdatedAt is the time the client edited the draft, it is not
+	// refreshed by the database
+	UpdatedAt time.Time `json:"updatedAt" gorm:"not null;autoUpdateTime:false"`
+}
+
+// MessageDraftAttachment is an attachment referenced by a draft.
+type MessageDraftAttachment struct {
+	UserID         string `gorm:"primaryKey"`
+	ConversationID string `gorm:"primaryKey"`
+	AttachmentID   string `gorm:"primaryKey;index"`
+	Position       int    `gorm:"not null;default:0"`
+}
+
+// SaveDraftInput replaces the draft of a conversation, a draft without
+// content, reply target or attachments clears it. UpdatedAt is when the
+// user made the change on the device.
+type SaveDraftInput struct {
+	Content          string    `json:"content"`
+	ReplyToMessageID *string   `json:"replyToMessageId"`
+	AttachmentIDs    []string  `json:"attachmentIds"`
+	UpdatedAt        time.Time `json:"updatedAt"`
+}
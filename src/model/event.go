@@ -0,0 +1,145 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Event is the envelope delivered over every realtime transport
+// (WebSocket, Server-Sent Events and long-polling).
+type Event struct {
+	ID             int64     `json:"id"`
+	Type           EventType `json:"type"`
+	ConversationID string    `json:"conversationId,omitempty"`
+	Payload        any       `json:"payload,omitempty"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
+
+type EventSubscription struct {
+	Stream       string
+	UserID       string
+	EventChannel chan *Event
+	DoneChannel  chan struct{}
+}
+
+// ClientEvent is a message sent by a client over the realtime channel. The
+// call fields are only used by the CALL_* events.
+type ClientEvent struct {
+	Type           EventType       `json:"type"`
+	ConversationID string          `json:"conversationId"`
+	CallID         string          `json:"callId,omitempty"`
+	TargetUserID   string          `json:"targetUserId,omitempty"`
+	Media          CallMedia       `json:"media,omitempty"`
+	Data           json.RawMessage `json:"data,omitempty" swaggertype:"object"`
+}
+
+type TypingPayload struct {
+	UserID string `json:"userId"`
+}
+
+type EventPollResult struct {
+	Events      []*Event `json:"events"`
+	LastEventID int64    `json:"lastEventId"`
+}
+
+type EventType string
+
+const (
+	EventTypeMessageCreated      EventType = "MESSAGE_CREATED"
+	EventTypeMessageUpdated      EventType = "MESSAGE_UPDATED"
+	EventTypeConversationCreated EventType = "CONVERSATION_CREATED"
+	EventTypeMetadataChanged     EventType = "METADATA_CHANGED"
+	EventTypeSettingsChanged     EventType = "SETTINGS_CHANGED"
+	EventTypeMessagePinned       EventType = "MESSAGE_PINNED"
+	EventTypeMessageUnpinned     EventType = "MESSAGE_UNPINNED"
+	EventTypeReactionChanged     EventType = "REACTION_CHANGED"
+	EventTypeScheduledFailed     EventType = "SCHEDULED_MESSAGE_FAILED"
+	EventTypeMessagesDeleted     EventType = "MESSAGES_DELETED"
+	EventTypePollUpdated         EventType = "POLL_UPDATED"
+	EventTypeDraftUpdated        EventType = "DRAFT_UPDATED"
+	EventTypeMemberAdded         EventType = "MEMBER_ADDED"
+	EventTypeMemberRemoved       EventType = "MEMBER_REMOVED"
+	EventTypeTypingStarted       EventType = "TYPING_STARTED"
+	EventTypeTypingStopped       EventType = "TYPING_STOPPED"
+	EventTypePresenceChanged     EventType = "PRESENCE_CHANGED"
+	EventTypeJoinRequested       EventType = "JOIN_REQUESTED"
+	EventTypeJoinRequestReviewed EventType = "JOIN_REQUEST_REVIEWED"
+	// contact events are sent to the users' own streams
+	EventTypeContactRequested     EventType = "CONTACT_REQUESTED"
+	EventTypeContactRequestClosed EventType = "CONTACT_REQUEST_CLOSED"
+	EventTypeContactAdded         EventType = "CONTACT_ADDED"
+	EventTypeContactRemoved       EventType = "CONTACT_REMOVED"
+
+	// call control, sent by clients and echoed to the other participants
+	EventTypeCallStart     EventType = "CALL_START"
+	EventTypeCallAccept    EventType = "CALL_ACCEPT"
+	EventTypeCallDecline   EventType = "CALL_DECLINE"
+	EventTypeCallHangUp    EventType = "CALL_HANG_UP"
+	EventTypeCallOffer     EventType = "CALL_OFFER"
+	EventTypeCallAnswer    EventType = "CALL_ANSWER"
+	EventTypeCallCandidate EventType = "CALL_ICE_CANDIDATE"
+	// call state, only sent by the server
+	EventTypeCallRinging EventType = "CALL_RINGING"
+	EventTypeCallUpdated EventType = "CALL_UPDATED"
+	EventTypeCallEnded   EventType = "CALL_ENDED"
+
+	// EventTypeResync tells the client that events were dropped from the
+	// replay buffer and it should refetch state instead of resuming.
+	EventTypeResync EventType = "RESYNC"
+)
+
+var AllEventType = []EventType{
+	EventTypeMessageCreated,
+	EventTypeMessageUpdated,
+	EventTypeConversationCreated,
+	EventTypeMetadataChanged,
+	EventTypeSettingsChanged,
+	EventTypeMessagePinned,
+	EventTypeMessageUnpinned,
+	EventTypeReactionChanged,
+	EventTypeScheduledFailed,
+	EventTypeMessagesDeleted,
+	EventTypePollUpdated,
+	EventTypeDraftUpdated,
+	EventTypeMemberAdded,
+	EventTypeMemberRemoved,
+	EventTypeTypingStarted,
+	EventTypeTypingStopped,
+	EventTypePresenceChanged,
+	EventTypeJoinRequested,
+	EventTypeJoinRequestReviewed,
+	EventTypeContactRequested,
+	EventTypeContactRequestClosed,
+	EventTypeContactAdded,
+	EventTypeContactRemoved,
+	EventTypeCallStart,
+	EventTypeCallAccept,
+	EventTypeCallDecline,
+	EventTypeCallHangUp,
+	EventTypeCallOffer,
+	EventTypeCallAnswer,
+	EventTypeCallCandidate,
+	EventTypeCallRinging,
+	EventTypeCallUpdated,
+	EventTypeCallEnded,
+	EventTypeResync,
+}
+
+func (e EventType) IsValid() bool {
+	switch e {
+	case EventTypeMessageCreated, EventTypeMessageUpdated, EventTypeConversationCreated, EventTypeMetadataChanged,
+		EventTypeSettingsChanged, EventTypeMessagePinned, EventTypeMessageUnpinned, EventTypeReactionChanged,
+		EventTypeScheduledFailed, EventTypeMessagesDeleted, EventTypePollUpdated, EventTypeDraftUpdated,
+		EventTypeMemberAdded, EventTypeMemberRemoved, EventTypeTypingStarted, EventTypeTypingStopped,
+		EventTypePresenceChanged, EventTypeJoinRequested, EventTypeJoinRequestReviewed,
+		EventTypeContactRequested, EventTypeContactRequestClosed, EventTypeContactAdded, EventTypeContactRemoved,
+		EventTypeCallStart, EventTypeCallAccept, EventTypeCallDecline, EventTypeCallHangUp, EventTypeCallOffer,
+		EventTypeCallAnswer, EventTypeCallCandidate, EventTypeCallRinging, EventTypeCallUpdated, EventTypeCallEnded, EventTypeResync:
+		return true
+	}
+	return false
+}
+
+func (e EventType) String() string {
+	return string(e)
+}
@@ -0,0 +1,81 @@
+package model
+
+import "time"
+
+// ChatFolder groups conversations of a user's inbox. A folder either lists
+// its conversations explicitly or matches them with rules, every rule that is
+// set has to match.
+type ChatFolder struct {
+	ID       string `json:"id" gorm:"primaryKey"`
+	UserID   string `json:"userId" gorm:"not null;index"`
+	Name     string `json:"name" gorm:"not null"`
+	Position int    `json:"position" gorm:"not null;default:0"`
+
+	// explicit list, when it is not empty the rules are ignored
+	ConversationIDs []string `json:"conversationIds" gorm:"-"`
+
+	// rules
+	Unread     bool `json:"unread" gorm:"not null;default:false"`
+	DirectOnly bool `json:"directOnly" gorm:"not null;default:false"`
+	GroupsOnly bool `json:"groupsOnly" gorm:"not null;default:false"`
+	Muted      bool `json:"muted" gorm:"not null;default:false"`
+	Mentions   bool `json:"mentions" gorm:"not null;default:false"`
+
+	// UnreadCount totals the unread messages of the folder's conversations,
+	// it is only set when listing folders
+	UnreadCount int64 `json:"unreadCount" gorm:"-"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// ChatFolderConversation is a row of the explicit list of a folder.
+type ChatFolderConversation struct {
+	FolderID       string `gorm:"primaryKey"`
+	ConversationID string `gorm:"primaryKey"`
+}
+
+// ChatFolderInput creates a folder, or replaces every field of one.
+type ChatFolderInput struct {
+	Name            string   `json:"name"`
+	Position        int      `json:"position"`
+	ConversationIDs []string `json:"conversationIds"`
+	Unread          bool     `json:"unread"`
+	DirectOnly      bool     `json:"directOnly"`
+	GroupsOnly      bool     `json:"groupsOnly"`
+	Muted           bool     `json:"muted"`
+	Mentions        bool     `json:"mentions"`
+}
+
+// HasRules tells whether the folder matches conversations with rules rather
+// than an explicit list.
+func (f *ChatFolder) HasRules() bool {
+	return f.Unread || f.DirectOnly || f.GroupsOnly || f.Muted || f.Mentions
+}
+
+// Filter turns the folder into a filter of the conversation list.
+func (f *ChatFolder) Filter() ConversationFilter {
+	filter := ConversationFilter{}
+	if len(f.ConversationIDs) > 0 {
+		filter.ConversationIDs = f.ConversationIDs
+		return filter
+	}
+
+	yes := true
+	if f.Unread {
+		filter.Unread = &yes
+	}
+	if f.Muted {
+		filter.Muted = &yes
+	}
+	if f.Mentions {
+		filter.Mentioned = &yes
+	}
+	switch {
+	case f.DirectOnly:
+		filter.Kinds = []ConversationKind{ConversationKindDirect}
+	case f.GroupsOnly:
+		filter.Kinds = []ConversationKind{ConversationKindGroup, ConversationKindChannel}
+	}
+	return filter
+}
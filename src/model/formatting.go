@@ -0,0 +1,102 @@
+package model
+
+// FormattedNode is a node of the canonical tree a MARKDOWN message is parsed
+// into. Only the fields that matter for the node type are set.
+type FormattedNode struct {
+	Type FormattedNodeType `json:"type"`
+	// Text of TEXT, CODE and CODE_BLOCK nodes
+	Text string `json:"text,omitempty"`
+	// URL of LINK nodes, always http, https or mailto
+	URL string `json:"url,omitempty"`
+	// Language of CODE_BLOCK nodes, when the sender named one
+	Language string `json:"language,omitempty"`
+	// Start is the first number of ORDERED_LIST nodes
+	Start int `json:"start,omitempty"`
+
+	Children []*FormattedNode `json:"children,omitempty"`
+}
+
+// RenderedMessage is a message turned into text for places that cannot show
+// the formatting tree, such as notifications and exports.
+type RenderedMessage struct {
+	MessageID string `json:"messageId"`
+	PlainText string `json:"plainText"`
+	// HTML only contains the tags the formatting maps to, with every text
+	// escaped
+	HTML string `json:"html"`
+}
+
+type MessageFormat string
+
+const (
+	MessageFormatPlain MessageFormat = "PLAIN"
+	// MessageFormatMarkdown TEXT messages are parsed from a safe subset of
+	// Markdown: bold, italics, code, code blocks, links, quotes and lists.
+	MessageFormatMarkdown MessageFormat = "MARKDOWN"
+)
+
+var AllMessageFormat = []MessageFormat{
+	MessageFormatPlain,
+	MessageFormatMarkdown,
+}
+
+func (e MessageFormat) IsValid() bool {
+	switch e {
+	case MessageFormatPlain, MessageFormatMarkdown:
+		return true
+	}
+	return false
+}
+
+func (e MessageFormat) String() string {
+	return string(e)
+}
+
+type FormattedNodeType string
+
+const (
+	FormattedNodeTypeDocument    FormattedNodeType = "DOCUMENT"
+	FormattedNodeTypeParagraph   FormattedNodeType = "PARAGRAPH"
+	FormattedNodeTypeText        FormattedNodeType = "TEXT"
+	FormattedNodeTypeLineBreak   FormattedNodeType = "LINE_BREAK"
+	FormattedNodeTypeBold        FormattedNodeType = "BOLD"
+	FormattedNodeTypeItalic      FormattedNodeType = "ITALIC"
+	FormattedNodeTypeCode        FormattedNodeType = "CODE"
+	FormattedNodeTypeCodeBlock   FormattedNodeType = "CODE_BLOCK"
+	FormattedNodeTypeLink        FormattedNodeType = "LINK"
+	FormattedNodeTypeQuote       FormattedNodeType = "QUOTE"
+	FormattedNodeTypeBulletList  FormattedNodeType = "BULLET_LIST"
+	FormattedNodeTypeOrderedList FormattedNodeType = "ORDERED_LIST"
+	FormattedNodeTypeListItem    FormattedNodeType = "LIST_ITEM"
+)
+
+var AllFormattedNodeType = []FormattedNodeType{
+	FormattedNodeTypeDocument,
+	FormattedNodeTypeParagraph,
+	FormattedNodeTypeText,
+	FormattedNodeTypeLineBreak,
+	FormattedNodeTypeBold,
+	FormattedNodeTypeItalic,
+	FormattedNodeTypeCode,
+	FormattedNodeTypeCodeBlock,
+	FormattedNodeTypeLink,
+	FormattedNodeTypeQuote,
+	FormattedNodeTypeBulletList,
+	FormattedNodeTypeOrderedList,
+	FormattedNodeTypeListItem,
+}
+
+func (e FormattedNodeType) IsValid() bool {
+	switch e {
+	case FormattedNodeTypeDocument, FormattedNodeTypeParagraph, FormattedNodeTypeText, FormattedNodeTypeLineBreak,
+		FormattedNodeTypeBold, FormattedNodeTypeItalic, FormattedNodeTypeCode, FormattedNodeTypeCodeBlock,
+		FormattedNodeTypeLink, FormattedNodeTypeQuote, FormattedNodeTypeBulletList, FormattedNodeTypeOrderedList,
+		FormattedNodeTypeListItem:
+		return true
+	}
+	return false
+}
+
+func (e FormattedNodeType) String() string {
+	return string(e)
+}
@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// MessageIdempotencyKey remembers which message a client message ID created,
+// so a retried send returns that message instead of a duplicate. Keys are
+// dropped once they are older than the retention window, or together with
+// their message.
+type MessageIdempotencyKey struct {
+	SenderID        string    `gorm:"primaryKey"`
+	ClientMessageID string    `gorm:"primaryKey"`
+	MessageID       string    `gorm:"not null;index"`
+	CreatedAt       time.Time `gorm:"not null;index"`
+}
@@ -0,0 +1,76 @@
+package model
+
+import "time"
+
+type ConversationInvite struct {
+	ID               string     `json:"id" gorm:"primaryKey"`
+	ConversationID   string     `json:"conversationId" gorm:"not null;index"`
+	Token            string     `json:"token" gorm:"not null;uniqueIndex"`
+	CreatedByID      string     `json:"createdById" gorm:"not null"`
+	ExpiresAt        *time.Time `json:"expiresAt"`
+	MaxUses          *int       `json:"maxUses"`
+	UseCount         int        `json:"useCount" gorm:"not null;default:0"`
+	Revoked          bool       `json:"revoked" gorm:"not null;default:false"`
+	RequiresApproval bool       `json:"requiresApproval" gorm:"not null;default:false"`
+	CreatedAt        time.Time  `json:"createdAt"`
+}
+
+type JoinRequest struct {
+	ID             string            `json:"id" gorm:"primaryKey"`
+	ConversationID string            `json:"conversationId" gorm:"not null;index;index:idx_join_requests_pending,unique,where:status = 'PENDING'"`
+	UserID         string            `json:"userId" gorm:"not null;index;index:idx_join_requests_pending,unique,where:status = 'PENDING'"`
+	User           User              `json:"user" gorm:"foreignKey:UserID"`
+	InviteID       string            `json:"inviteId" gorm:"not null"`
+	Status         JoinRequestStatus `json:"status" gorm:"not null;default:PENDING"`
+	ReviewedByID   *string           `json:"reviewedById"`
+	CreatedAt      time.Time         `json:"createdAt"`
+	ReviewedAt     *time.Time        `json:"reviewedAt"`
+}
+
+type CreateInviteInput struct {
+	ConversationID   string     `json:"conversationId"`
+	ExpiresAt        *time.Time `json:"expiresAt"`
+	MaxUses          *int       `json:"maxUses"`
+	RequiresApproval bool       `json:"requiresApproval"`
+}
+
+// InvitePreview is what anyone holding a link can see before joining.
+type InvitePreview struct {
+	ConversationID   string     `json:"conversationId"`
+	Title            string     `json:"title"`
+	MemberCount      int64      `json:"memberCount"`
+	RequiresApproval bool       `json:"requiresApproval"`
+	ExpiresAt        *time.Time `json:"expiresAt"`
+}
+
+type JoinResult struct {
+	Status       JoinRequestStatus `json:"status"`
+	Conversation *Conversation     `json:"conversation,omitempty"`
+	Request      *JoinRequest      `json:"request,omitempty"`
+}
+
+type JoinRequestStatus string
+
+const (
+	JoinRequestStatusPending  JoinRequestStatus = "PENDING"
+	JoinRequestStatusApproved JoinRequestStatus = "APPROVED"
+	JoinRequestStatusRejected JoinRequestStatus = "REJECTED"
+)
+
+var AllJoinRequestStatus = []JoinRequestStatus{
+	JoinRequestStatusPending,
+	JoinRequestStatusApproved,
+	JoinRequestStatusRejected,
+}
+
+func (e JoinRequestStatus) IsValid() bool {
+	switch e {
+	case JoinRequestStatusPending, JoinRequestStatusApproved, JoinRequestStatusRejected:
+		return true
+	}
+	return false
+}
+
+func (e JoinRequestStatus) String() string {
+	return string(e)
+}
@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// LinkPreview describes a page linked from a message, it is fetched after
+// the message was sent and pushed with a MESSAGE_UPDATED event.
+type LinkPreview struct {
+	MessageID string `json:"messageId" gorm:"primaryKey"`
+	URL       string `json:"url" gorm:"primaryKey"`
+	// Position orders the previews like the links in the message
+	Position    int       `json:"position" gorm:"not null;default:0"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	ImageURL    string    `json:"imageUrl"`
+	SiteName    string    `json:"siteName"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
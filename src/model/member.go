@@ -0,0 +1,78 @@
+package model
+
+import "time"
+
+// ConversationMember is the join table between users and conversations, it
+// holds everything that belongs to one user in one conversation.
+type ConversationMember struct {
+	ConversationID string     `json:"conversationId" gorm:"primaryKey"`
+	UserID         string     `json:"userId" gorm:"primaryKey"`
+	Role           MemberRole `json:"role" gorm:"not null;default:MEMBER"`
+	JoinedAt       time.Time  `json:"joinedAt" gorm:"autoCreateTime"`
+
+	// private settings of the member, nobody else sees them
+	MutedForever bool       `json:"mutedForever" gorm:"not null;default:false"`
+	MutedUntil   *time.Time `json:"mutedUntil"`
+	PinnedAt     *time.Time `json:"pinnedAt"`
+	ArchivedAt   *time.Time `json:"archivedAt"`
+	Nickname     string     `json:"nickname"`
+	// LastReadAt is the read marker, later messages of others are unread
+	LastReadAt *time.Time `json:"lastReadAt"`
+}
+
+// UpdateConversationSettingsInput only changes the settings that are present.
+// Muting without MutedUntil mutes forever.
+type UpdateConversationSettingsInput struct {
+	Muted      *bool      `json:"muted"`
+	MutedUntil *time.Time `json:"mutedUntil"`
+	Pinned     *bool      `json:"pinned"`
+	Archived   *bool      `json:"archived"`
+	Nickname   *string    `json:"nickname"`
+}
+
+// ConversationFilter narrows the conversation list of a user, nil fields do
+// not filter.
+type ConversationFilter struct {
+	Archived *bool
+	Muted    *bool
+	Pinned   *bool
+	// Unread keeps the conversations with unread messages
+	Unread *bool
+	// Mentioned keeps the conversations with unread messages mentioning the
+	// user by @username
+	Mentioned       *bool
+	Kinds           []ConversationKind
+	ConversationIDs []string
+}
+
+func (ConversationMember) TableName() string {
+	return "user_conversations"
+}
+
+type MemberRole string
+
+const (
+	MemberRoleAdmin  MemberRole = "ADMIN"
+	MemberRoleMember MemberRole = "MEMBER"
+)
+
+var AllMemberRole = []MemberRole{
+	MemberRoleAdmin,
+	MemberRoleMember,
+}
+
+func (m *ConversationMember) IsMuted(now time.Time) bool {
+	return m.MutedForever || (m.MutedUntil != nil && m.MutedUntil.After(now))
+}
+
+func (e MemberRole) IsValid() bool {
+	switch e {
+	case MemberRoleAdmin, MemberRoleMember:
+		return true
+	}
+	return false
+}
+
+func (e MemberRole) String() string {
+	return string(e)
+}
@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// PinnedMessage marks a message an admin pinned to the top of its
+// conversation. The row goes away together with the message.
+type PinnedMessage struct {
+	ConversationID string    `json:"conversationId" gorm:"primaryKey"`
+	MessageID      string    `json:"messageId" gorm:"primaryKey"`
+	Message        *Message  `json:"message,omitempty" gorm:"constraint:OnDelete:CASCADE"`
+	PinnedByID     string    `json:"pinnedById" gorm:"not null"`
+	PinnedAt       time.Time `json:"pinnedAt" gorm:"autoCreateTime;index"`
+}
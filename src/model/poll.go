@@ -0,0 +1,82 @@
+package model
+
+import "time"
+
+// Poll belongs to a message of the POLL content type, whose Content holds the
+// question as well so clients without poll support show something.
+type Poll struct {
+	MessageID      string        `json:"messageId" gorm:"primaryKey"`
+	Question       string        `json:"question" gorm:"not null"`
+	MultipleChoice bool          `json:"multipleChoice" gorm:"not null;default:false"`
+	Anonymous      bool          `json:"anonymous" gorm:"not null;default:false"`
+	ClosesAt       *time.Time    `json:"closesAt"`
+	ClosedAt       *time.Time    `json:"closedAt"`
+	Options        []*PollOption `json:"options" gorm:"foreignKey:MessageID;references:MessageID"`
+}
+
+type PollOption struct {
+	ID        string `json:"id" gorm:"primaryKey"`
+	MessageID string `json:"messageId" gorm:"not null;index"`
+	Position  int    `json:"position" gorm:"not null"`
+	Text      string `json:"text" gorm:"not null"`
+}
+
+type PollVote struct {
+	MessageID string    `json:"messageId" gorm:"primaryKey"`
+	OptionID  string    `json:"optionId" gorm:"primaryKey"`
+	UserID    string    `json:"userId" gorm:"primaryKey;index"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type CreatePollInput struct {
+	Question       string     `json:"question"`
+	Options        []string   `json:"options"`
+	MultipleChoice bool       `json:"multipleChoice"`
+	Anonymous      bool       `json:"anonymous"`
+	ClosesAt       *time.Time `json:"closesAt"`
+}
+
+// VotePollInput replaces the user's vote, no options takes the vote back.
+type VotePollInput struct {
+	OptionIDs []string `json:"optionIds"`
+}
+
+// PollResults are aggregated by the server. Voter IDs are only listed when
+// the poll is not anonymous.
+type PollResults struct {
+	MessageID   string               `json:"messageId"`
+	TotalVoters int64                `json:"totalVoters"`
+	Closed      bool                 `json:"closed"`
+	Options     []*PollOptionResults `json:"options"`
+	// MyOptionIDs are the options the current user voted for
+	MyOptionIDs []string `json:"myOptionIds"`
+}
+
+type PollOptionResults struct {
+	OptionID string   `json:"optionId"`
+	Votes    int64    `json:"votes"`
+	VoterIDs []string `json:"voterIds,omitempty"`
+}
+
+// PollDelta is published when a vote changes or the poll closes. Deltas
+// tells how each changed option moved along with its new total.
+type PollDelta struct {
+	MessageID   string             `json:"messageId"`
+	TotalVoters int64              `json:"totalVoters"`
+	Closed      bool               `json:"closed"`
+	Deltas      []*PollOptionDelta `json:"deltas"`
+	// VoterID and VoterOptionIDs are left out of anonymous polls
+	VoterID        string   `json:"voterId,omitempty"`
+	VoterOptionIDs []string `json:"voterOptionIds,omitempty"`
+}
+
+type PollOptionDelta struct {
+	OptionID string `json:"optionId"`
+	Delta    int64  `json:"delta"`
+	Votes    int64  `json:"votes"`
+}
+
+// IsClosed tells whether votes are no longer accepted at now.
+func (p *Poll) IsClosed(now time.Time) bool {
+	return p.ClosedAt != nil || (p.ClosesAt != nil && !p.ClosesAt.After(now))
+}
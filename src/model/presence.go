@@ -0,0 +1,51 @@
+package model
+
+import "time"
+
+type Presence struct {
+	UserID     string         `json:"userId"`
+	Status     PresenceStatus `json:"status"`
+	StatusText string         `json:"statusText,omitempty"`
+	LastSeenAt *time.Time     `json:"lastSeenAt,omitempty"`
+}
+
+type UpdateStatusInput struct {
+	Status     PresenceStatus `json:"status"`
+	StatusText string         `json:"statusText"`
+	ExpiresAt  *time.Time     `json:"expiresAt"`
+}
+
+type UpdatePrivacyInput struct {
+	HideLastSeen      *bool                `json:"hideLastSeen"`
+	HideForwardSender *bool                `json:"hideForwardSender"`
+	Discoverability   *UserDiscoverability `json:"discoverability"`
+	DirectMessages    *DirectMessagePolicy `json:"directMessages"`
+}
+
+type PresenceStatus string
+
+const (
+	PresenceStatusOnline       PresenceStatus = "ONLINE"
+	PresenceStatusOffline      PresenceStatus = "OFFLINE"
+	PresenceStatusAway         PresenceStatus = "AWAY"
+	PresenceStatusDoNotDisturb PresenceStatus = "DO_NOT_DISTURB"
+)
+
+var AllPresenceStatus = []PresenceStatus{
+	PresenceStatusOnline,
+	PresenceStatusOffline,
+	PresenceStatusAway,
+	PresenceStatusDoNotDisturb,
+}
+
+func (e PresenceStatus) IsValid() bool {
+	switch e {
+	case PresenceStatusOnline, PresenceStatusOffline, PresenceStatusAway, PresenceStatusDoNotDisturb:
+		return true
+	}
+	return false
+}
+
+func (e PresenceStatus) String() string {
+	return string(e)
+}
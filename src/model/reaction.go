@@ -0,0 +1,44 @@
+package model
+
+import "time"
+
+type MessageReaction struct {
+	MessageID string    `json:"messageId" gorm:"primaryKey"`
+	UserID    string    `json:"userId" gorm:"primaryKey"`
+	Emoji     string    `json:"emoji" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// MessageView records that a member saw a message, so each member only
+// counts once towards the ViewCount of the message.
+type MessageView struct {
+	MessageID string    `json:"messageId" gorm:"primaryKey"`
+	UserID    string    `json:"userId" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type ReactInput struct {
+	Emoji string `json:"emoji"`
+}
+
+type ViewMessagesInput struct {
+	MessageIDs []string `json:"messageIds"`
+}
+
+type ReactionCount struct {
+	Emoji string `json:"emoji"`
+	Count int64  `json:"count"`
+	// Reacted tells whether the current user is one of the Count, it is
+	// not set in events
+	Reacted bool `json:"reacted"`
+}
+
+// ReactionPayload is published when a reaction is added or removed, with the
+// new totals of the message so clients do not have to count themselves.
+type ReactionPayload struct {
+	MessageID string           `json:"messageId"`
+	UserID    string           `json:"userId"`
+	Emoji     string           `json:"emoji"`
+	Added     bool             `json:"added"`
+	Counts    []*ReactionCount `json:"counts"`
+}
@@ -0,0 +1,68 @@
+package model
+
+import "time"
+
+// ScheduledMessage is composed now and delivered at SendAt by the message
+// scheduler, through the same path as a message sent right away.
+type ScheduledMessage struct {
+	ID             string                 `json:"id" gorm:"primaryKey"`
+	SenderID       string                 `json:"senderId" gorm:"not null;index"`
+	ConversationID string                 `json:"conversationId" gorm:"not null"`
+	Content        string                 `json:"content"`
+	ContentType    MessageContentType     `json:"contentType" gorm:"not null"`
+	Format         MessageFormat          `json:"format" gorm:"not null;default:PLAIN"`
+	SendAt         time.Time              `json:"sendAt" gorm:"not null;index:idx_scheduled_messages_due,priority:2"`
+	Status         ScheduledMessageStatus `json:"status" gorm:"not null;default:PENDING;index:idx_scheduled_messages_due,priority:1"`
+	// MessageID is the delivered message once Status is SENT
+	MessageID *string `json:"messageId"`
+	// Error tells why delivery failed once Status is FAILED
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type ScheduleMessageInput struct {
+	ConversationID string             `json:"conversationId"`
+	Content        string             `json:"content"`
+	ContentType    MessageContentType `json:"contentType"`
+	// Format is PLAIN when empty
+	Format MessageFormat `json:"format"`
+	SendAt time.Time     `json:"sendAt"`
+}
+
+// UpdateScheduledMessageInput only changes the fields that are present.
+type UpdateScheduledMessageInput struct {
+	Content     *string             `json:"content"`
+	ContentType *MessageContentType `json:"contentType"`
+	Format      *MessageFormat      `json:"format"`
+	SendAt      *time.Time          `json:"sendAt"`
+}
+
+type ScheduledMessageStatus string
+
+const (
+	ScheduledMessageStatusPending   ScheduledMessageStatus = "PENDING"
+	ScheduledMessageStatusSent      ScheduledMessageStatus = "SENT"
+	ScheduledMessageStatusCancelled ScheduledMessageStatus = "CANCELLED"
+	ScheduledMessageStatusFailed    ScheduledMessageStatus = "FAILED"
+)
+
+var AllScheduledMessageStatus = []ScheduledMessageStatus{
+	ScheduledMessageStatusPending,
+	ScheduledMessageStatusSent,
+	ScheduledMessageStatusCancelled,
+	ScheduledMessageStatusFailed,
+}
+
+func (e ScheduledMessageStatus) IsValid() bool {
+	switch e {
+	case ScheduledMessageStatusPending, ScheduledMessageStatusSent, ScheduledMessageStatusCancelled,
+		ScheduledMessageStatusFailed:
+		return true
+	}
+	return false
+}
+
+func (e ScheduledMessageStatus) String() string {
+	return string(e)
+}
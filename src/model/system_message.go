@@ -0,0 +1,46 @@
+package model
+
+// SystemMessageContent is stored as JSON in the Content of SYSTEM messages.
+// Only the field matching Type is set.
+type SystemMessageContent struct {
+	Type    SystemMessageType `json:"type"`
+	ActorID string            `json:"actorId"`
+	Call    *CallSummary      `json:"call,omitempty"`
+	// Changes maps each changed field to its new value
+	Changes map[string]string `json:"changes,omitempty"`
+	// MessageID is the message a MESSAGE_PINNED or MESSAGE_UNPINNED entry is about
+	MessageID string `json:"messageId,omitempty"`
+	// MessageTTL is the new timer of a MESSAGE_TIMER_CHANGED entry
+	MessageTTL *int64 `json:"messageTtl,omitempty"`
+}
+
+type SystemMessageType string
+
+const (
+	SystemMessageTypeCall            SystemMessageType = "CALL"
+	SystemMessageTypeMetadataChanged SystemMessageType = "METADATA_CHANGED"
+	SystemMessageTypeMessagePinned   SystemMessageType = "MESSAGE_PINNED"
+	SystemMessageTypeMessageUnpinned SystemMessageType = "MESSAGE_UNPINNED"
+	SystemMessageTypeTimerChanged    SystemMessageType = "MESSAGE_TIMER_CHANGED"
+)
+
+var AllSystemMessageType = []SystemMessageType{
+	SystemMessageTypeCall,
+	SystemMessageTypeMetadataChanged,
+	SystemMessageTypeMessagePinned,
+	SystemMessageTypeMessageUnpinned,
+	SystemMessageTypeTimerChanged,
+}
+
+func (e SystemMessageType) IsValid() bool {
+	switch e {
+	case SystemMessageTypeCall, SystemMessageTypeMetadataChanged, SystemMessageTypeMessagePinned,
+		SystemMessageTypeMessageUnpinned, SystemMessageTypeTimerChanged:
+		return true
+	}
+	return false
+}
+
+func (e SystemMessageType) String() string {
+	return string(e)
+}
@@ -1,12 +1,37 @@
 package model
 
+import "time"
+
 type User struct {
 	ID             string  `json:"id" gorm:"primaryKey"`
-	Email          string  `json:"email" gorm:"uniqueIndex;not null"`
-	Password       string  `json:"password" gorm:"not null"`
-	Username       string  `json:"username"`
-	DisplayName    string  `json:"displayName"`
+	Email          string  `json:"email,omitempty" gorm:"not null;index:idx_users_email_lower,unique,expression:lower(email)"`
+	Password       string  `json:"-" gorm:"not null"`
+	Username       string  `json:"username" gorm:"index:idx_users_username_lower,unique,expression:lower(username),where:username <> '';index:idx_users_username_trgm,type:gin,expression:lower(username) gin_trgm_ops"`
+	DisplayName    string  `json:"displayName" gorm:"index:idx_users_display_name_trgm,type:gin,expression:lower(display_name) gin_trgm_ops"`
+	Bio            string  `json:"bio" gorm:"not null;default:''"`
 	ProfilePicture *string `json:"profilePicture"`
+	// ProfilePicture is the URL of the largest size of Avatar, and
+	// ProfilePictureID the attachment it was made from when it was not
+	// uploaded directly
+	ProfilePictureID *string `json:"profilePictureId"`
+	Avatar           *Avatar `json:"avatar" gorm:"type:jsonb;serializer:json"`
+
+	// presence, Status is the one picked by the user and only applies while
+	// they are connected, see Presence for what other users see. LastSeenAt
+	// is only sent as part of a Presence, which honours HideLastSeen.
+	Status          PresenceStatus `json:"status" gorm:"not null;default:ONLINE"`
+	StatusText      string         `json:"statusText"`
+	StatusExpiresAt *time.Time     `json:"statusExpiresAt"`
+	LastSeenAt      *time.Time     `json:"-"`
+
+	// privacy
+	HideLastSeen bool `json:"hideLastSeen" gorm:"not null;default:false"`
+	// HideForwardSender keeps the user's ID and name off what others forward
+	HideForwardSender bool `json:"hideForwardSender" gorm:"not null;default:false"`
+	// Discoverability decides who finds the user through the directory search
+	Discoverability UserDiscoverability `json:"discoverability" gorm:"not null;default:EVERYONE"`
+	// DirectMessages decides who may start a conversation with the user
+	DirectMessages DirectMessagePolicy `json:"directMessages" gorm:"not null;default:EVERYONE"`
 
 	// associations
 	Conversations []*Conversation `json:"conversations" gorm:"many2many:user_conversations;"`
@@ -18,3 +43,23 @@ type CreateUserInput struct {
 	DisplayName string `json:"displayName"`
 	Password    string `json:"password"`
 }
+
+// UpdateProfileInput only changes the fields that are present, an empty
+// ProfilePictureID removes the picture. Email and password have their own
+// flows since they need the current password.
+type UpdateProfileInput struct {
+	DisplayName      *string `json:"displayName"`
+	Username         *string `json:"username"`
+	Bio              *string `json:"bio"`
+	ProfilePictureID *string `json:"profilePictureId"`
+}
+
+type ChangeEmailInput struct {
+	NewEmail        string `json:"newEmail"`
+	CurrentPassword string `json:"currentPassword"`
+}
+
+type ChangePasswordInput struct {
+	CurrentPassword string `json:"currentPassword"`
+	NewPassword     string `json:"newPassword"`
+}
@@ -0,0 +1,47 @@
+package model
+
+// UserSearchResult is a user found by the directory search.
+// SharedConversations counts the conversations they have in common with
+// the searching user.
+type UserSearchResult struct {
+	User                *User `json:"user"`
+	SharedConversations int64 `json:"sharedConversations"`
+	IsContact           bool  `json:"isContact"`
+}
+
+// UserSearchPage is one page of search results, NextOffset is only set when
+// there are more.
+type UserSearchPage struct {
+	Results    []*UserSearchResult `json:"results"`
+	NextOffset *int                `json:"nextOffset"`
+}
+
+// UserDiscoverability decides who finds the user through the directory
+// search. Users can always be reached through conversations they share.
+type UserDiscoverability string
+
+const (
+	UserDiscoverabilityEveryone UserDiscoverability = "EVERYONE"
+	// UserDiscoverabilityConversationPeers users are only found by their
+	// contacts and the users they already share a conversation with
+	UserDiscoverabilityConversationPeers UserDiscoverability = "CONVERSATION_PEERS"
+	UserDiscoverabilityNobody            UserDiscoverability = "NOBODY"
+)
+
+var AllUserDiscoverability = []UserDiscoverability{
+	UserDiscoverabilityEveryone,
+	UserDiscoverabilityConversationPeers,
+	UserDiscoverabilityNobody,
+}
+
+func (e UserDiscoverability) IsValid() bool {
+	switch e {
+	case UserDiscoverabilityEveryone, UserDiscoverabilityConversationPeers, UserDiscoverabilityNobody:
+		return true
+	}
+	return false
+}
+
+func (e UserDiscoverability) String() string {
+	return string(e)
+}
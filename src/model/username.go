@@ -0,0 +1,59 @@
+package model
+
+import "time"
+
+// UsernameChange records a username a user gave up. Until RedirectUntil the
+// old handle still leads to the user and nobody else can take it.
+type UsernameChange struct {
+	ID            string    `json:"id" gorm:"primaryKey"`
+	UserID        string    `json:"userId" gorm:"not null;index"`
+	OldUsername   string    `json:"oldUsername" gorm:"not null;index:idx_username_changes_old_lower,expression:lower(old_username)"`
+	NewUsername   string    `json:"newUsername" gorm:"not null"`
+	ChangedAt     time.Time `json:"changedAt" gorm:"not null"`
+	RedirectUntil time.Time `json:"redirectUntil" gorm:"not null"`
+}
+
+type UsernameAvailability struct {
+	Username  string `json:"username"`
+	Available bool   `json:"available"`
+	// Reason tells why the username cannot be taken, it is empty when it is
+	// available
+	Reason UsernameUnavailableReason `json:"reason,omitempty"`
+}
+
+// HandleLookup is the user an @handle leads to. RedirectedFrom is set when
+// the handle was the user's previous username.
+type HandleLookup struct {
+	User           *User  `json:"user"`
+	RedirectedFrom string `json:"redirectedFrom,omitempty"`
+}
+
+type UsernameUnavailableReason string
+
+const (
+	// UsernameUnavailableReasonInvalid usernames break the character or
+	// length policy
+	UsernameUnavailableReasonInvalid  UsernameUnavailableReason = "INVALID"
+	UsernameUnavailableReasonReserved UsernameUnavailableReason = "RESERVED"
+	// UsernameUnavailableReasonTaken usernames belong to a user, now or
+	// during the grace period after they changed it
+	UsernameUnavailableReasonTaken UsernameUnavailableReason = "TAKEN"
+)
+
+var AllUsernameUnavailableReason = []UsernameUnavailableReason{
+	UsernameUnavailableReasonInvalid,
+	UsernameUnavailableReasonReserved,
+	UsernameUnavailableReasonTaken,
+}
+
+func (e UsernameUnavailableReason) IsValid() bool {
+	switch e {
+	case UsernameUnavailableReasonInvalid, UsernameUnavailableReasonReserved, UsernameUnavailableReasonTaken:
+		return true
+	}
+	return false
+}
+
+func (e UsernameUnavailableReason) String() string {
+	return string(e)
+}
@@ -0,0 +1,103 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/badaccuracyid/softeng_backend/src/controllers"
+	"github.com/badaccuracyid/softeng_backend/src/database"
+	"github.com/badaccuracyid/softeng_backend/src/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type AttachmentRoutes struct {
+	baseRouter *gin.RouterGroup
+	db         *gorm.DB
+}
+
+func NewAttachmentRoutes(router *gin.Engine) (*AttachmentRoutes, error) {
+	postgresDatabase, err := database.GetPostgresDatabase()
+	if err != nil {
+		panic(err)
+	}
+
+	baseRouter := router.Group("/api/v1/attachments")
+
+	return &AttachmentRoutes{
+		baseRouter: baseRouter,
+		db:         postgresDatabase,
+	}, nil
+}
+
+func InitializeAttachmentRoutes(router *gin.Engine) *gin.Engine {
+	attachmentRoutes, err := NewAttachmentRoutes(router)
+	if err != nil {
+		panic(err)
+	}
+
+	attachmentRoutes.registerRoutes()
+	return router
+}
+
+// attachmentController builds the controller of a request. Controllers keep
+// the request context, so one is never shared between requests.
+func (a *AttachmentRoutes) attachmentController(ctx *gin.Context) controllers.AttachmentController {
+	attachmentController := controllers.NewAttachmentController(a.db)
+	attachmentController.SetContext(ctx)
+	return attachmentController
+}
+
+func (a *AttachmentRoutes) registerRoutes() {
+	a.baseRouter.POST("/upload", a.uploadAttachment)
+	a.baseRouter.GET("/get/:id", a.getAttachment)
+}
+
+// uploadAttachment handles the POST /api/v1/attachments/upload request
+// @Summary Upload an attachment
+// @Description Upload a file that can then be referenced by messages or used as a chat avatar
+// @Tags attachments
+// @Accept  multipart/form-data
+// @Produce  json
+// @Param file formData file true "File"
+// @Success 201 {object} model.Attachment
+// @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Failure 500 {string} string
+// @Router /attachments/upload [post]
+func (a *AttachmentRoutes) uploadAttachment(ctx *gin.Context) {
+	attachmentController := a.attachmentController(ctx)
+	file, err := ctx.FormFile("file")
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	attachment, err := attachmentController.UploadAttachment(file)
+	if err != nil {
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, attachment)
+}
+
+// getAttachment handles the GET /api/v1/attachments/get/:id request
+// @Summary Get an attachment
+// @Description Get the metadata and URL of an attachment
+// @Tags attachments
+// @Produce  json
+// @Param id path string true "Attachment ID"
+// @Success 200 {object} model.Attachment
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /attachments/get/{id} [get]
+func (a *AttachmentRoutes) getAttachment(ctx *gin.Context) {
+	attachmentController := a.attachmentController(ctx)
+	attachment, err := attachmentController.GetAttachment(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, attachment)
+}
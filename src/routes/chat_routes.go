@@ -1,27 +1,33 @@
 package routes
 
 import (
+	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/badaccuracyid/softeng_backend/src/controllers"
 	"github.com/badaccuracyid/softeng_backend/src/database"
 	"github.com/badaccuracyid/softeng_backend/src/model"
+	"github.com/badaccuracyid/softeng_backend/src/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
+	"gorm.io/gorm"
 )
 
 type ChatRoutes struct {
-	baseRouter     *gin.RouterGroup
-	chatController controllers.ChatController
+	baseRouter *gin.RouterGroup
+	db         *gorm.DB
+	websocket  *WebsocketTransport
 }
 
-var upgrader = websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
-	CheckOrigin: func(r *http.Request) bool {
-		return true
-	},
-}
+const (
+	sseRetry             = 3 * time.Second
+	sseKeepAliveInterval = 15 * time.Second
+	longPollTimeout      = 25 * time.Second
+)
 
 func NewChatRoutes(router *gin.Engine) (*ChatRoutes, error) {
 	postgresDatabase, err := database.GetPostgresDatabase()
@@ -29,12 +35,12 @@ func NewChatRoutes(router *gin.Engine) (*ChatRoutes, error) {
 		panic(err)
 	}
 
-	chatService := controllers.NewChatController(postgresDatabase)
 	baseRouter := router.Group("/api/v1/chats")
 
 	return &ChatRoutes{
-		baseRouter:     baseRouter,
-		chatController: chatService,
+		baseRouter: baseRouter,
+		db:         postgresDatabase,
+		websocket:  NewWebsocketTransport(loadWebsocketConfig()),
 	}, nil
 }
 
@@ -48,27 +54,64 @@ func InitializeChatRoutes(router *gin.Engine) *gin.Engine {
 	return router
 }
 
+// chatController builds the controller of a request. Controllers keep the
+// request context, so one is never shared between requests.
+func (c *ChatRoutes) chatController(ctx *gin.Context) controllers.ChatController {
+	chatController := controllers.NewChatController(c.db)
+	chatController.SetContext(ctx)
+	return chatController
+}
+
+func (c *ChatRoutes) userController(ctx *gin.Context) controllers.UserController {
+	userController := controllers.NewUserService(c.db)
+	userController.SetContext(ctx)
+	return userController
+}
+
 func (c *ChatRoutes) registerRoutes() {
 	c.baseRouter.POST("/create", c.createConversation)
+	c.baseRouter.POST("/direct/:userId", c.getOrCreateDirectConversation)
 	c.baseRouter.POST("/message", c.sendMessage)
+	c.baseRouter.POST("/forward", c.forwardMessages)
+	c.baseRouter.POST("/pin/:messageId", c.pinMessage)
+	c.baseRouter.POST("/unpin/:messageId", c.unpinMessage)
+	c.baseRouter.GET("/pinned/:id", c.getPinnedMessages)
+	c.baseRouter.POST("/react/:messageId", c.react)
+	c.baseRouter.POST("/unreact/:messageId", c.unreact)
+	c.baseRouter.GET("/reactions/:messageId", c.getReactions)
+	c.baseRouter.GET("/rendered/:messageId", c.getRenderedMessage)
+	c.baseRouter.POST("/view/:id", c.viewMessages)
 	c.baseRouter.GET("/getForUser", c.getConversationForUser)
 	c.baseRouter.GET("/get/:id", c.getConversation)
+	c.baseRouter.PATCH("/update/:id", c.updateConversation)
+	c.baseRouter.PATCH("/timer/:id", c.setMessageTimer)
+	c.baseRouter.PATCH("/settings/:id", c.updateConversationSettings)
+	c.baseRouter.POST("/read/:id", c.markConversationRead)
+	c.baseRouter.GET("/ws", c.handleWebSocket)
 	c.baseRouter.GET("/ws/:id", c.handleWebSocket)
+	c.baseRouter.GET("/sse", c.handleEventStream)
+	c.baseRouter.GET("/sse/:id", c.handleEventStream)
+	c.baseRouter.GET("/poll", c.pollEvents)
+	c.baseRouter.GET("/poll/:id", c.pollEvents)
+	c.baseRouter.POST("/signal", c.sendSignal)
+	c.baseRouter.POST("/call", c.sendCallEvent)
+	c.baseRouter.GET("/call/:id", c.getCall)
 }
 
 // createConversation handles the POST /api/v1/chats/create request
 // @Summary Create a new chat
-// @Description Create a new chat with the input payload
+// @Description Create a new group chat or broadcast channel with the input payload, the creator becomes its admin. A group with a single other member is the direct chat with them, which is returned instead. Members who only let their contacts start a conversation with them must have the creator as a contact
 // @Tags chats
 // @Accept  json
 // @Produce  json
 // @Param chat body model.CreateConversationInput true "Chat"
 // @Success 201 {object} model.Conversation
 // @Failure 400 {string} string
+// @Failure 403 {string} string
 // @Failure 500 {string} string
 // @Router /chats/create [post]
 func (c *ChatRoutes) createConversation(ctx *gin.Context) {
-	c.chatController.SetContext(ctx)
+	chatController := c.chatController(ctx)
 	payload := model.CreateConversationInput{}
 	err := ctx.BindJSON(&payload)
 	if err != nil {
@@ -76,28 +119,61 @@ func (c *ChatRoutes) createConversation(ctx *gin.Context) {
 		return
 	}
 
-	conversation, err := c.chatController.CreateConversation(payload)
+	conversation, err := chatController.CreateConversation(payload)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, err.Error())
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
 		return
 	}
 
 	ctx.JSON(http.StatusCreated, conversation)
 }
 
+// getOrCreateDirectConversation handles the POST /api/v1/chats/direct/:userId request
+// @Summary Get or create a direct chat
+// @Description Return the direct chat between the current user and the given user, creating it if it does not exist yet. A user who only lets their contacts start a conversation with them must have the current user as a contact
+// @Tags chats
+// @Accept  json
+// @Produce  json
+// @Param userId path string true "User ID"
+// @Success 200 {object} model.Conversation
+// @Success 201 {object} model.Conversation
+// @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Failure 403 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /chats/direct/{userId} [post]
+func (c *ChatRoutes) getOrCreateDirectConversation(ctx *gin.Context) {
+	chatController := c.chatController(ctx)
+	conversation, created, err := chatController.GetOrCreateDirectConversation(ctx.Param("userId"))
+	if err != nil {
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
+		return
+	}
+
+	if created {
+		ctx.JSON(http.StatusCreated, conversation)
+		return
+	}
+	ctx.JSON(http.StatusOK, conversation)
+}
+
 // sendMessage handles the POST /api/v1/chats/message request
 // @Summary Send a message
-// @Description Send a message with the input payload
+// @Description Send a message with the input payload, only admins can post in a channel
 // @Tags chats
 // @Accept  json
 // @Produce  json
 // @Param message body model.SendMessageInput true "Message"
 // @Success 201 {object} model.Message
 // @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Failure 403 {string} string
+// @Failure 404 {string} string
 // @Failure 500 {string} string
 // @Router /chats/message [post]
 func (c *ChatRoutes) sendMessage(ctx *gin.Context) {
-	c.chatController.SetContext(ctx)
+	chatController := c.chatController(ctx)
 	payload := model.SendMessageInput{}
 	err := ctx.BindJSON(&payload)
 	if err != nil {
@@ -105,57 +181,431 @@ func (c *ChatRoutes) sendMessage(ctx *gin.Context) {
 		return
 	}
 
-	message, err := c.chatController.SendMessage(payload)
+	message, err := chatController.SendMessage(payload)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, err.Error())
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
 		return
 	}
 
 	ctx.JSON(http.StatusCreated, message)
 }
 
+// forwardMessages handles the POST /api/v1/chats/forward request
+// @Summary Forward messages
+// @Description Forward messages to other chats in the order they were sent, keeping their content type and attachments and a reference to where they came from
+// @Tags chats
+// @Accept  json
+// @Produce  json
+// @Param forward body model.ForwardMessagesInput true "Messages and target chats"
+// @Success 201 {array} model.Message
+// @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Failure 403 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /chats/forward [post]
+func (c *ChatRoutes) forwardMessages(ctx *gin.Context) {
+	chatController := c.chatController(ctx)
+	payload := model.ForwardMessagesInput{}
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	messages, err := chatController.ForwardMessages(payload)
+	if err != nil {
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, messages)
+}
+
+// pinMessage handles the POST /api/v1/chats/pin/:messageId request
+// @Summary Pin a message
+// @Description Pin a message to the top of its chat, only admins can pin messages
+// @Tags chats
+// @Produce  json
+// @Param messageId path string true "Message ID"
+// @Success 201 {object} model.PinnedMessage
+// @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Failure 403 {string} string
+// @Failure 404 {string} string
+// @Failure 409 {string} string
+// @Failure 500 {string} string
+// @Router /chats/pin/{messageId} [post]
+func (c *ChatRoutes) pinMessage(ctx *gin.Context) {
+	chatController := c.chatController(ctx)
+	pin, err := chatController.PinMessage(ctx.Param("messageId"))
+	if err != nil {
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, pin)
+}
+
+// unpinMessage handles the POST /api/v1/chats/unpin/:messageId request
+// @Summary Unpin a message
+// @Description Unpin a pinned message, only admins can unpin messages
+// @Tags chats
+// @Produce  json
+// @Param messageId path string true "Message ID"
+// @Success 204
+// @Failure 401 {string} string
+// @Failure 403 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /chats/unpin/{messageId} [post]
+func (c *ChatRoutes) unpinMessage(ctx *gin.Context) {
+	chatController := c.chatController(ctx)
+	if err := chatController.UnpinMessage(ctx.Param("messageId")); err != nil {
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// getPinnedMessages handles the GET /api/v1/chats/pinned/:id request
+// @Summary List pinned messages
+// @Description List the pinned messages of a chat, the latest pin first
+// @Tags chats
+// @Produce  json
+// @Param id path string true "Chat ID"
+// @Success 200 {array} model.PinnedMessage
+// @Failure 401 {string} string
+// @Failure 403 {string} string
+// @Failure 500 {string} string
+// @Router /chats/pinned/{id} [get]
+func (c *ChatRoutes) getPinnedMessages(ctx *gin.Context) {
+	chatController := c.chatController(ctx)
+	pins, err := chatController.GetPinnedMessages(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, pins)
+}
+
+// react handles the POST /api/v1/chats/react/:messageId request
+// @Summary React to a message
+// @Description Add the current user's emoji reaction to a message, every member can react, including channel readers
+// @Tags chats
+// @Accept  json
+// @Produce  json
+// @Param messageId path string true "Message ID"
+// @Param reaction body model.ReactInput true "Reaction"
+// @Success 200 {array} model.ReactionCount
+// @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Failure 403 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /chats/react/{messageId} [post]
+func (c *ChatRoutes) react(ctx *gin.Context) {
+	chatController := c.chatController(ctx)
+	payload := model.ReactInput{}
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	counts, err := chatController.React(ctx.Param("messageId"), payload)
+	if err != nil {
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, counts)
+}
+
+// unreact handles the POST /api/v1/chats/unreact/:messageId request
+// @Summary Remove a reaction
+// @Description Remove the current user's emoji reaction from a message
+// @Tags chats
+// @Accept  json
+// @Produce  json
+// @Param messageId path string true "Message ID"
+// @Param reaction body model.ReactInput true "Reaction"
+// @Success 200 {array} model.ReactionCount
+// @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Failure 403 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /chats/unreact/{messageId} [post]
+func (c *ChatRoutes) unreact(ctx *gin.Context) {
+	chatController := c.chatController(ctx)
+	payload := model.ReactInput{}
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	counts, err := chatController.Unreact(ctx.Param("messageId"), payload)
+	if err != nil {
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, counts)
+}
+
+// getReactions handles the GET /api/v1/chats/reactions/:messageId request
+// @Summary Get the reactions of a message
+// @Description Get the reaction totals of a message per emoji, most used first
+// @Tags chats
+// @Produce  json
+// @Param messageId path string true "Message ID"
+// @Success 200 {array} model.ReactionCount
+// @Failure 401 {string} string
+// @Failure 403 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /chats/reactions/{messageId} [get]
+func (c *ChatRoutes) getReactions(ctx *gin.Context) {
+	chatController := c.chatController(ctx)
+	counts, err := chatController.GetReactions(ctx.Param("messageId"))
+	if err != nil {
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, counts)
+}
+
+// getRenderedMessage handles the GET /api/v1/chats/rendered/:messageId request
+// @Summary Render a message as text
+// @Description Get the plain text and sanitized HTML of a message, for notifications and exports
+// @Tags chats
+// @Produce  json
+// @Param messageId path string true "Message ID"
+// @Success 200 {object} model.RenderedMessage
+// @Failure 401 {string} string
+// @Failure 403 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /chats/rendered/{messageId} [get]
+func (c *ChatRoutes) getRenderedMessage(ctx *gin.Context) {
+	chatController := c.chatController(ctx)
+	rendered, err := chatController.GetRenderedMessage(ctx.Param("messageId"))
+	if err != nil {
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, rendered)
+}
+
+// viewMessages handles the POST /api/v1/chats/view/:id request
+// @Summary Mark messages as viewed
+// @Description Count the current user once towards the view count of each message, the user's own messages are not counted
+// @Tags chats
+// @Accept  json
+// @Param id path string true "Chat ID"
+// @Param views body model.ViewMessagesInput true "Viewed messages"
+// @Success 204
+// @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Failure 403 {string} string
+// @Failure 500 {string} string
+// @Router /chats/view/{id} [post]
+func (c *ChatRoutes) viewMessages(ctx *gin.Context) {
+	chatController := c.chatController(ctx)
+	payload := model.ViewMessagesInput{}
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := chatController.ViewMessages(ctx.Param("id"), payload); err != nil {
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
 // getConversation handles the GET /api/v1/chats/get/:id request
 // @Summary Get a chat by ID
-// @Description Get a chat by ID
+// @Description Get a chat by ID with its messages, members only
 // @Tags chats
 // @Accept  json
 // @Produce  json
 // @Param id path string true "Chat ID"
 // @Success 200 {object} model.Conversation
 // @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Failure 403 {string} string
 // @Failure 404 {string} string
 // @Failure 500 {string} string
 // @Router /chats/get/{id} [get]
 func (c *ChatRoutes) getConversation(ctx *gin.Context) {
-	c.chatController.SetContext(ctx)
+	chatController := c.chatController(ctx)
 	id := ctx.Param("id")
-	conversation, err := c.chatController.GetConversation(id)
+	conversation, err := chatController.GetConversation(id)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, err.Error())
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, conversation)
+}
+
+// updateConversation handles the PATCH /api/v1/chats/update/:id request
+// @Summary Update a chat
+// @Description Update the title, description, topic or avatar of a group chat, only the fields present are changed
+// @Tags chats
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Chat ID"
+// @Param chat body model.UpdateConversationInput true "Chat metadata"
+// @Success 200 {object} model.Conversation
+// @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Failure 403 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /chats/update/{id} [patch]
+func (c *ChatRoutes) updateConversation(ctx *gin.Context) {
+	chatController := c.chatController(ctx)
+	payload := model.UpdateConversationInput{}
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.JSON(http.StatusBadRequest, err.Error())
 		return
 	}
 
-	if conversation == nil {
-		ctx.JSON(http.StatusNotFound, "Conversation not found")
+	conversation, err := chatController.UpdateConversation(ctx.Param("id"), payload)
+	if err != nil {
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
 		return
 	}
 
 	ctx.JSON(http.StatusOK, conversation)
 }
 
+// setMessageTimer handles the PATCH /api/v1/chats/timer/:id request
+// @Summary Set the disappearing messages timer
+// @Description Turn disappearing messages on with a timer of 24 hours, 7 days or 90 days in seconds, or off with 0. Admins set it in groups and channels, either side in a direct chat
+// @Tags chats
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Chat ID"
+// @Param timer body model.SetMessageTimerInput true "Timer"
+// @Success 200 {object} model.Conversation
+// @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Failure 403 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /chats/timer/{id} [patch]
+func (c *ChatRoutes) setMessageTimer(ctx *gin.Context) {
+	chatController := c.chatController(ctx)
+	payload := model.SetMessageTimerInput{}
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	conversation, err := chatController.SetMessageTimer(ctx.Param("id"), payload)
+	if err != nil {
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, conversation)
+}
+
+// updateConversationSettings handles the PATCH /api/v1/chats/settings/:id request
+// @Summary Update my settings of a chat
+// @Description Mute, pin, archive or nickname a chat for the current user only, only the fields present are changed. Muting without mutedUntil mutes forever
+// @Tags chats
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Chat ID"
+// @Param settings body model.UpdateConversationSettingsInput true "Chat settings"
+// @Success 200 {object} model.ConversationMember
+// @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Failure 403 {string} string
+// @Failure 500 {string} string
+// @Router /chats/settings/{id} [patch]
+func (c *ChatRoutes) updateConversationSettings(ctx *gin.Context) {
+	chatController := c.chatController(ctx)
+	payload := model.UpdateConversationSettingsInput{}
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	settings, err := chatController.UpdateConversationSettings(ctx.Param("id"), payload)
+	if err != nil {
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, settings)
+}
+
+// markConversationRead handles the POST /api/v1/chats/read/:id request
+// @Summary Mark a chat as read
+// @Description Move the current user's read marker of a chat to now
+// @Tags chats
+// @Produce  json
+// @Param id path string true "Chat ID"
+// @Success 200 {object} model.ConversationMember
+// @Failure 401 {string} string
+// @Failure 403 {string} string
+// @Failure 500 {string} string
+// @Router /chats/read/{id} [post]
+func (c *ChatRoutes) markConversationRead(ctx *gin.Context) {
+	chatController := c.chatController(ctx)
+	settings, err := chatController.MarkConversationRead(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, settings)
+}
+
 // getConversationForUser handles the GET /api/v1/chats/getForUser request
 // @Summary Get all chats for a user
-// @Description Get all chats for a user
+// @Description Get the chats of the current user, pinned chats first and then by latest message. Archived chats are left out unless archived=true
 // @Tags chats
 // @Accept  json
 // @Produce  json
+// @Param archived query bool false "Only archived (true) or only unarchived (false, default) chats"
+// @Param muted query bool false "Only muted or only unmuted chats"
+// @Param pinned query bool false "Only pinned or only unpinned chats"
 // @Success 200 {array} model.Conversation
 // @Failure 400 {string} string
 // @Failure 500 {string} string
 // @Router /chats/getForUser [get]
 func (c *ChatRoutes) getConversationForUser(ctx *gin.Context) {
-	c.chatController.SetContext(ctx)
-	conversations, err := c.chatController.GetConversationsForUser()
+	chatController := c.chatController(ctx)
+	filter := model.ConversationFilter{}
+	for key, target := range map[string]**bool{
+		"archived": &filter.Archived,
+		"muted":    &filter.Muted,
+		"pinned":   &filter.Pinned,
+	} {
+		value, err := queryBool(ctx, key)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, err.Error())
+			return
+		}
+		*target = value
+	}
+	if filter.Archived == nil {
+		archived := false
+		filter.Archived = &archived
+	}
+
+	conversations, err := chatController.GetConversationsForUser(filter)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, err.Error())
 		return
@@ -164,62 +614,279 @@ func (c *ChatRoutes) getConversationForUser(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, conversations)
 }
 
+// queryBool reads an optional boolean query parameter.
+func queryBool(ctx *gin.Context, key string) (*bool, error) {
+	raw, found := ctx.GetQuery(key)
+	if !found {
+		return nil, nil
+	}
+
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		return nil, err
+	}
+	return &value, nil
+}
+
 // handleWebSocket handles the GET /api/v1/chats/ws/:id request
 // @Summary Handle a websocket connection
-// @Description Handle a websocket connection
+// @Description Stream the events of a chat, or of the current user when no chat ID is given, over a websocket. Clients send model.ClientEvent typing signals on the same socket.
 // @Tags chats
 // @Accept  json
 // @Produce  json
-// @Param id path string true "Chat ID"
-// @Success 200 {string} string
-// @Failure 400 {string} string
-// @Failure 404 {string} string
+// @Param id path string false "Chat ID"
+// @Param lastEventId query int false "Resume after this event ID"
+// @Success 200 {object} model.Event
+// @Failure 401 {string} string
+// @Failure 403 {string} string
 // @Failure 500 {string} string
 // @Router /chats/ws/{id} [get]
 func (c *ChatRoutes) handleWebSocket(ctx *gin.Context) {
-	conversationID := ctx.Param("id")
-	conn, err := upgrader.Upgrade(ctx.Writer, ctx.Request, nil)
+	chatController := c.chatController(ctx)
+	stream, err := chatController.AuthorizeEventStream(ctx.Param("id"))
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, "Failed to set websocket upgrade")
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
 		return
 	}
 
-	messageChannel, doneChannel, err := c.chatController.NewMessageSubscription(conversationID)
+	conn, err := c.websocket.Upgrade(ctx.Writer, ctx.Request)
 	if err != nil {
-		err := conn.Close()
-		if err != nil {
+		return
+	}
+
+	userID := utils.GetCurrentUserID(ctx)
+	userController := c.userController(ctx)
+	if !c.websocket.Acquire(userID) {
+		c.websocket.Close(conn, websocket.CloseTryAgainLater, "too many connections")
+		return
+	}
+	defer c.websocket.Release(userID)
+
+	subscription, missed := chatController.SubscribeEvents(stream, utils.GetLastEventID(ctx))
+	defer chatController.UnsubscribeEvents(subscription)
+
+	// deferred first so it runs after the presence has been released
+	defer chatController.LeaveCallsOnDisconnect(userID)
+	userController.ConnectPresence(userID)
+	defer userController.DisconnectPresence(userID)
+
+	conversationID := ctx.Param("id")
+	if conversationID != "" {
+		defer chatController.SendTypingSignal(userID, conversationID, model.EventTypeTypingStopped)
+	}
+
+	c.websocket.Serve(conn, subscription, missed, func(data []byte) {
+		var clientEvent model.ClientEvent
+		if err := json.Unmarshal(data, &clientEvent); err != nil {
 			return
 		}
-		ctx.JSON(http.StatusInternalServerError, "Failed to subscribe to messages")
+		if clientEvent.ConversationID == "" {
+			clientEvent.ConversationID = conversationID
+		}
+
+		// invalid or rate limited events are dropped, the connection stays open
+		_ = chatController.HandleClientEvent(userID, clientEvent)
+	})
+}
+
+// handleEventStream handles the GET /api/v1/chats/sse/:id request
+// @Summary Stream events with Server-Sent Events
+// @Description Stream the events of a chat, or of the current user when no chat ID is given, as Server-Sent Events
+// @Tags chats
+// @Produce  text/event-stream
+// @Param id path string false "Chat ID"
+// @Param Last-Event-ID header int false "Resume after this event ID"
+// @Success 200 {object} model.Event
+// @Failure 401 {string} string
+// @Failure 403 {string} string
+// @Failure 500 {string} string
+// @Router /chats/sse/{id} [get]
+func (c *ChatRoutes) handleEventStream(ctx *gin.Context) {
+	chatController := c.chatController(ctx)
+	stream, err := chatController.AuthorizeEventStream(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
+		return
+	}
+
+	subscription, missed := chatController.SubscribeEvents(stream, utils.GetLastEventID(ctx))
+	defer chatController.UnsubscribeEvents(subscription)
+
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	ctx.Header("X-Accel-Buffering", "no")
+	ctx.Status(http.StatusOK)
+
+	if _, err := fmt.Fprintf(ctx.Writer, "retry: %d\n\n", sseRetry.Milliseconds()); err != nil {
 		return
 	}
+	for _, event := range missed {
+		if err := writeServerSentEvent(ctx.Writer, event); err != nil {
+			return
+		}
+	}
+	ctx.Writer.Flush()
 
-	defer close(doneChannel)
+	userID := utils.GetCurrentUserID(ctx)
+	userController := c.userController(ctx)
+	defer chatController.LeaveCallsOnDisconnect(userID)
+	userController.ConnectPresence(userID)
+	defer userController.DisconnectPresence(userID)
 
-	go func() {
-		for {
-			message, ok := <-messageChannel
+	keepAlive := time.NewTicker(sseKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-ctx.Request.Context().Done():
+			return
+		case event, ok := <-subscription.EventChannel:
 			if !ok {
-				err := conn.Close()
-				if err != nil {
-					return
-				}
 				return
 			}
-			err := conn.WriteJSON(message)
-			if err != nil {
+			if err := writeServerSentEvent(ctx.Writer, event); err != nil {
+				return
+			}
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(ctx.Writer, ": keep-alive\n\n"); err != nil {
 				return
 			}
 		}
-	}()
+		ctx.Writer.Flush()
+	}
+}
 
-	for {
-		_, _, err := conn.ReadMessage()
-		if err != nil {
-			doneChannel <- struct{}{}
-			conn.Close()
-			return
-		}
+// pollEvents handles the GET /api/v1/chats/poll/:id request
+// @Summary Long-poll for events
+// @Description Wait for the events of a chat, or of the current user when no chat ID is given, after the given event ID
+// @Tags chats
+// @Produce  json
+// @Param id path string false "Chat ID"
+// @Param lastEventId query int false "Return events after this event ID"
+// @Param timeout query int false "Seconds to wait for an event"
+// @Success 200 {object} model.EventPollResult
+// @Failure 401 {string} string
+// @Failure 403 {string} string
+// @Failure 500 {string} string
+// @Router /chats/poll/{id} [get]
+func (c *ChatRoutes) pollEvents(ctx *gin.Context) {
+	chatController := c.chatController(ctx)
+	stream, err := chatController.AuthorizeEventStream(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
+		return
+	}
+
+	timeout := longPollTimeout
+	if seconds, err := strconv.Atoi(ctx.Query("timeout")); err == nil && seconds >= 0 {
+		timeout = min(time.Duration(seconds)*time.Second, longPollTimeout)
+	}
+
+	result := chatController.PollEvents(stream, utils.GetLastEventID(ctx), timeout)
+	ctx.JSON(http.StatusOK, result)
+}
+
+// sendSignal handles the POST /api/v1/chats/signal request
+// @Summary Send an ephemeral signal
+// @Description Send a typing or call signal for clients that receive events over Server-Sent Events or long-polling
+// @Tags chats
+// @Accept  json
+// @Produce  json
+// @Param signal body model.ClientEvent true "Signal"
+// @Success 204 {string} string
+// @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Failure 403 {string} string
+// @Failure 429 {string} string
+// @Failure 500 {string} string
+// @Router /chats/signal [post]
+func (c *ChatRoutes) sendSignal(ctx *gin.Context) {
+	chatController := c.chatController(ctx)
+	payload := model.ClientEvent{}
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.JSON(http.StatusBadRequest, err.Error())
+		return
 	}
 
+	err := chatController.HandleClientEvent(utils.GetCurrentUserID(ctx), payload)
+	if err != nil {
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// sendCallEvent handles the POST /api/v1/chats/call request
+// @Summary Control a call
+// @Description Start, accept, decline or hang up a call, or relay an SDP offer, answer or ICE candidate to a participant. Channels have no calls
+// @Tags chats
+// @Accept  json
+// @Produce  json
+// @Param event body model.ClientEvent true "Call event"
+// @Success 200 {object} model.CallSession
+// @Success 204 {string} string
+// @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Failure 403 {string} string
+// @Failure 404 {string} string
+// @Failure 409 {string} string
+// @Failure 500 {string} string
+// @Router /chats/call [post]
+func (c *ChatRoutes) sendCallEvent(ctx *gin.Context) {
+	chatController := c.chatController(ctx)
+	payload := model.ClientEvent{}
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	call, err := chatController.HandleCallEvent(utils.GetCurrentUserID(ctx), payload)
+	if err != nil {
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
+		return
+	}
+
+	if call == nil {
+		ctx.Status(http.StatusNoContent)
+		return
+	}
+	ctx.JSON(http.StatusOK, call)
+}
+
+// getCall handles the GET /api/v1/chats/call/:id request
+// @Summary Get a call
+// @Description Get the state of a ringing or active call the current user takes part in
+// @Tags chats
+// @Produce  json
+// @Param id path string true "Call ID"
+// @Success 200 {object} model.CallSession
+// @Failure 403 {string} string
+// @Failure 404 {string} string
+// @Router /chats/call/{id} [get]
+func (c *ChatRoutes) getCall(ctx *gin.Context) {
+	chatController := c.chatController(ctx)
+	call, err := chatController.GetCall(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, call)
+}
+
+func writeServerSentEvent(writer io.Writer, event *model.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	if event.ID > 0 {
+		if _, err := fmt.Fprintf(writer, "id: %d\n", event.ID); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(writer, "event: %s\ndata: %s\n\n", event.Type, data)
+	return err
 }
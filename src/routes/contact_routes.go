@@ -0,0 +1,260 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/badaccuracyid/softeng_backend/src/controllers"
+	"github.com/badaccuracyid/softeng_backend/src/database"
+	"github.com/badaccuracyid/softeng_backend/src/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ContactRoutes struct {
+	baseRouter *gin.RouterGroup
+	db         *gorm.DB
+}
+
+func NewContactRoutes(router *gin.Engine) (*ContactRoutes, error) {
+	postgresDatabase, err := database.GetPostgresDatabase()
+	if err != nil {
+		panic(err)
+	}
+
+	baseRouter := router.Group("/api/v1/users/contacts")
+
+	return &ContactRoutes{
+		baseRouter: baseRouter,
+		db:         postgresDatabase,
+	}, nil
+}
+
+func InitializeContactRoutes(router *gin.Engine) *gin.Engine {
+	contactRoutes, err := NewContactRoutes(router)
+	if err != nil {
+		panic(err)
+	}
+
+	contactRoutes.registerRoutes()
+	return router
+}
+
+// contactController builds the controller of a request. Controllers keep the
+// request context, so one is never shared between requests.
+func (s *ContactRoutes) contactController(ctx *gin.Context) controllers.ContactController {
+	contactController := controllers.NewContactController(s.db)
+	contactController.SetContext(ctx)
+	return contactController
+}
+
+func (s *ContactRoutes) registerRoutes() {
+	s.baseRouter.GET("/list", s.getContacts)
+	s.baseRouter.GET("/status/:userId", s.getRelation)
+	s.baseRouter.POST("/remove/:userId", s.removeContact)
+
+	s.baseRouter.GET("/requests/incoming", s.getIncomingRequests)
+	s.baseRouter.GET("/requests/outgoing", s.getOutgoingRequests)
+	s.baseRouter.POST("/requests/send/:userId", s.sendRequest)
+	s.baseRouter.POST("/requests/accept/:userId", s.acceptRequest)
+	s.baseRouter.POST("/requests/decline/:userId", s.declineRequest)
+	s.baseRouter.POST("/requests/cancel/:userId", s.cancelRequest)
+}
+
+// sendRequest handles the POST /api/v1/users/contacts/requests/send/:userId request
+// @Summary Send a contact request
+// @Description Ask the user to become a contact of the current user. When they already asked the current user, their request is accepted instead
+// @Tags contacts
+// @Produce  json
+// @Param userId path string true "User ID"
+// @Success 200 {object} model.ContactRelation
+// @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Failure 404 {string} string
+// @Failure 409 {string} string
+// @Failure 500 {string} string
+// @Router /users/contacts/requests/send/{userId} [post]
+func (s *ContactRoutes) sendRequest(ctx *gin.Context) {
+	contactController := s.contactController(ctx)
+	relation, err := contactController.SendRequest(ctx.Param("userId"))
+	if err != nil {
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, relation)
+}
+
+// acceptRequest handles the POST /api/v1/users/contacts/requests/accept/:userId request
+// @Summary Accept a contact request
+// @Description Accept the contact request the user sent to the current user
+// @Tags contacts
+// @Produce  json
+// @Param userId path string true "User ID"
+// @Success 200 {object} model.ContactRelation
+// @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /users/contacts/requests/accept/{userId} [post]
+func (s *ContactRoutes) acceptRequest(ctx *gin.Context) {
+	contactController := s.contactController(ctx)
+	relation, err := contactController.AcceptRequest(ctx.Param("userId"))
+	if err != nil {
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, relation)
+}
+
+// declineRequest handles the POST /api/v1/users/contacts/requests/decline/:userId request
+// @Summary Decline a contact request
+// @Description Turn down the contact request the user sent to the current user
+// @Tags contacts
+// @Produce  json
+// @Param userId path string true "User ID"
+// @Success 200 {object} model.ContactRelation
+// @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /users/contacts/requests/decline/{userId} [post]
+func (s *ContactRoutes) declineRequest(ctx *gin.Context) {
+	contactController := s.contactController(ctx)
+	relation, err := contactController.DeclineRequest(ctx.Param("userId"))
+	if err != nil {
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, relation)
+}
+
+// cancelRequest handles the POST /api/v1/users/contacts/requests/cancel/:userId request
+// @Summary Cancel a contact request
+// @Description Take back the contact request the current user sent to the user
+// @Tags contacts
+// @Produce  json
+// @Param userId path string true "User ID"
+// @Success 200 {object} model.ContactRelation
+// @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /users/contacts/requests/cancel/{userId} [post]
+func (s *ContactRoutes) cancelRequest(ctx *gin.Context) {
+	contactController := s.contactController(ctx)
+	relation, err := contactController.CancelRequest(ctx.Param("userId"))
+	if err != nil {
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, relation)
+}
+
+// removeContact handles the POST /api/v1/users/contacts/remove/:userId request
+// @Summary Remove a contact
+// @Description Remove the user from the current user's contacts, and the current user from theirs
+// @Tags contacts
+// @Produce  json
+// @Param userId path string true "User ID"
+// @Success 200 {object} model.ContactRelation
+// @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /users/contacts/remove/{userId} [post]
+func (s *ContactRoutes) removeContact(ctx *gin.Context) {
+	contactController := s.contactController(ctx)
+	relation, err := contactController.RemoveContact(ctx.Param("userId"))
+	if err != nil {
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, relation)
+}
+
+// getRelation handles the GET /api/v1/users/contacts/status/:userId request
+// @Summary Get the contact status with a user
+// @Description Tell whether the user is a contact of the current user, or whether a request between them is pending
+// @Tags contacts
+// @Produce  json
+// @Param userId path string true "User ID"
+// @Success 200 {object} model.ContactRelation
+// @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /users/contacts/status/{userId} [get]
+func (s *ContactRoutes) getRelation(ctx *gin.Context) {
+	contactController := s.contactController(ctx)
+	relation, err := contactController.GetRelation(ctx.Param("userId"))
+	if err != nil {
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, relation)
+}
+
+// getContacts handles the GET /api/v1/users/contacts/list request
+// @Summary Get the current user's contacts
+// @Description List the current user's contacts with their presence, the latest added first
+// @Tags contacts
+// @Produce  json
+// @Success 200 {array} model.ContactEntry
+// @Failure 401 {string} string
+// @Failure 500 {string} string
+// @Router /users/contacts/list [get]
+func (s *ContactRoutes) getContacts(ctx *gin.Context) {
+	contactController := s.contactController(ctx)
+	contacts, err := contactController.GetContacts()
+	if err != nil {
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, contacts)
+}
+
+// getIncomingRequests handles the GET /api/v1/users/contacts/requests/incoming request
+// @Summary Get the contact requests sent to the current user
+// @Description List the pending contact requests sent to the current user with their senders, latest first
+// @Tags contacts
+// @Produce  json
+// @Success 200 {array} model.ContactRequest
+// @Failure 401 {string} string
+// @Failure 500 {string} string
+// @Router /users/contacts/requests/incoming [get]
+func (s *ContactRoutes) getIncomingRequests(ctx *gin.Context) {
+	contactController := s.contactController(ctx)
+	requests, err := contactController.GetIncomingRequests()
+	if err != nil {
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, requests)
+}
+
+// getOutgoingRequests handles the GET /api/v1/users/contacts/requests/outgoing request
+// @Summary Get the contact requests the current user sent
+// @Description List the pending contact requests the current user sent with their recipients, latest first
+// @Tags contacts
+// @Produce  json
+// @Success 200 {array} model.ContactRequest
+// @Failure 401 {string} string
+// @Failure 500 {string} string
+// @Router /users/contacts/requests/outgoing [get]
+func (s *ContactRoutes) getOutgoingRequests(ctx *gin.Context) {
+	contactController := s.contactController(ctx)
+	requests, err := contactController.GetOutgoingRequests()
+	if err != nil {
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, requests)
+}
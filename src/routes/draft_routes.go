@@ -0,0 +1,105 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/badaccuracyid/softeng_backend/src/controllers"
+	"github.com/badaccuracyid/softeng_backend/src/database"
+	"github.com/badaccuracyid/softeng_backend/src/model"
+	"github.com/badaccuracyid/softeng_backend/src/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type DraftRoutes struct {
+	baseRouter *gin.RouterGroup
+	db         *gorm.DB
+}
+
+func NewDraftRoutes(router *gin.Engine) (*DraftRoutes, error) {
+	postgresDatabase, err := database.GetPostgresDatabase()
+	if err != nil {
+		panic(err)
+	}
+
+	baseRouter := router.Group("/api/v1/chats/drafts")
+
+	return &DraftRoutes{
+		baseRouter: baseRouter,
+		db:         postgresDatabase,
+	}, nil
+}
+
+func InitializeDraftRoutes(router *gin.Engine) *gin.Engine {
+	draftRoutes, err := NewDraftRoutes(router)
+	if err != nil {
+		panic(err)
+	}
+
+	draftRoutes.registerRoutes()
+	return router
+}
+
+// draftController builds the controller of a request. Controllers keep the
+// request context, so one is never shared between requests.
+func (s *DraftRoutes) draftController(ctx *gin.Context) controllers.DraftController {
+	draftController := controllers.NewDraftController(s.db)
+	draftController.SetContext(ctx)
+	return draftController
+}
+
+func (s *DraftRoutes) registerRoutes() {
+	s.baseRouter.PUT("/save/:id", s.saveDraft)
+	s.baseRouter.GET("/list", s.getDrafts)
+}
+
+// saveDraft handles the PUT /api/v1/chats/drafts/save/:id request
+// @Summary Save a draft
+// @Description Replace the current user's draft of a chat, an empty draft clears it. The draft with the latest updatedAt wins, when a newer one is stored it is returned instead
+// @Tags drafts
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Conversation ID"
+// @Param draft body model.SaveDraftInput true "Draft"
+// @Success 200 {object} model.MessageDraft
+// @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Failure 403 {string} string
+// @Failure 500 {string} string
+// @Router /chats/drafts/save/{id} [put]
+func (s *DraftRoutes) saveDraft(ctx *gin.Context) {
+	draftController := s.draftController(ctx)
+	payload := model.SaveDraftInput{}
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	draft, err := draftController.SaveDraft(ctx.Param("id"), payload)
+	if err != nil {
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, draft)
+}
+
+// getDrafts handles the GET /api/v1/chats/drafts/list request
+// @Summary List drafts
+// @Description List the current user's drafts that are not empty, the latest first
+// @Tags drafts
+// @Produce  json
+// @Success 200 {array} model.MessageDraft
+// @Failure 401 {string} string
+// @Failure 500 {string} string
+// @Router /chats/drafts/list [get]
+func (s *DraftRoutes) getDrafts(ctx *gin.Context) {
+	draftController := s.draftController(ctx)
+	drafts, err := draftController.GetDrafts()
+	if err != nil {
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, drafts)
+}
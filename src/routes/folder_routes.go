@@ -0,0 +1,182 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/badaccuracyid/softeng_backend/src/controllers"
+	"github.com/badaccuracyid/softeng_backend/src/database"
+	"github.com/badaccuracyid/softeng_backend/src/model"
+	"github.com/badaccuracyid/softeng_backend/src/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type FolderRoutes struct {
+	baseRouter *gin.RouterGroup
+	db         *gorm.DB
+}
+
+func NewFolderRoutes(router *gin.Engine) (*FolderRoutes, error) {
+	postgresDatabase, err := database.GetPostgresDatabase()
+	if err != nil {
+		panic(err)
+	}
+
+	baseRouter := router.Group("/api/v1/chats/folders")
+
+	return &FolderRoutes{
+		baseRouter: baseRouter,
+		db:         postgresDatabase,
+	}, nil
+}
+
+func InitializeFolderRoutes(router *gin.Engine) *gin.Engine {
+	folderRoutes, err := NewFolderRoutes(router)
+	if err != nil {
+		panic(err)
+	}
+
+	folderRoutes.registerRoutes()
+	return router
+}
+
+// folderController builds the controller of a request. Controllers keep the
+// request context, so one is never shared between requests.
+func (f *FolderRoutes) folderController(ctx *gin.Context) controllers.FolderController {
+	folderController := controllers.NewFolderController(f.db)
+	folderController.SetContext(ctx)
+	return folderController
+}
+
+func (f *FolderRoutes) registerRoutes() {
+	f.baseRouter.POST("/create", f.createFolder)
+	f.baseRouter.GET("/list", f.getFolders)
+	f.baseRouter.PUT("/update/:id", f.updateFolder)
+	f.baseRouter.POST("/delete/:id", f.deleteFolder)
+	f.baseRouter.GET("/chats/:id", f.getFolderConversations)
+}
+
+// createFolder handles the POST /api/v1/chats/folders/create request
+// @Summary Create a chat folder
+// @Description Create a folder of the current user's inbox, with either an explicit list of chats or rules matching them
+// @Tags folders
+// @Accept  json
+// @Produce  json
+// @Param folder body model.ChatFolderInput true "Folder"
+// @Success 201 {object} model.ChatFolder
+// @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Failure 403 {string} string
+// @Failure 409 {string} string
+// @Failure 500 {string} string
+// @Router /chats/folders/create [post]
+func (f *FolderRoutes) createFolder(ctx *gin.Context) {
+	folderController := f.folderController(ctx)
+	payload := model.ChatFolderInput{}
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	folder, err := folderController.CreateFolder(payload)
+	if err != nil {
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, folder)
+}
+
+// getFolders handles the GET /api/v1/chats/folders/list request
+// @Summary List chat folders
+// @Description List the current user's folders in order, each with the total of unread messages in its chats
+// @Tags folders
+// @Produce  json
+// @Success 200 {array} model.ChatFolder
+// @Failure 401 {string} string
+// @Failure 500 {string} string
+// @Router /chats/folders/list [get]
+func (f *FolderRoutes) getFolders(ctx *gin.Context) {
+	folderController := f.folderController(ctx)
+	folders, err := folderController.GetFolders()
+	if err != nil {
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, folders)
+}
+
+// updateFolder handles the PUT /api/v1/chats/folders/update/:id request
+// @Summary Update a chat folder
+// @Description Replace the name, position, chat list and rules of a folder
+// @Tags folders
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Folder ID"
+// @Param folder body model.ChatFolderInput true "Folder"
+// @Success 200 {object} model.ChatFolder
+// @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Failure 403 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /chats/folders/update/{id} [put]
+func (f *FolderRoutes) updateFolder(ctx *gin.Context) {
+	folderController := f.folderController(ctx)
+	payload := model.ChatFolderInput{}
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	folder, err := folderController.UpdateFolder(ctx.Param("id"), payload)
+	if err != nil {
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, folder)
+}
+
+// deleteFolder handles the POST /api/v1/chats/folders/delete/:id request
+// @Summary Delete a chat folder
+// @Description Delete a folder, its chats are left untouched
+// @Tags folders
+// @Param id path string true "Folder ID"
+// @Success 204
+// @Failure 401 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /chats/folders/delete/{id} [post]
+func (f *FolderRoutes) deleteFolder(ctx *gin.Context) {
+	folderController := f.folderController(ctx)
+	if err := folderController.DeleteFolder(ctx.Param("id")); err != nil {
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// getFolderConversations handles the GET /api/v1/chats/folders/chats/:id request
+// @Summary List the chats of a folder
+// @Description List the chats in a folder like the inbox, pinned chats first and then by latest message
+// @Tags folders
+// @Produce  json
+// @Param id path string true "Folder ID"
+// @Success 200 {array} model.Conversation
+// @Failure 401 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /chats/folders/chats/{id} [get]
+func (f *FolderRoutes) getFolderConversations(ctx *gin.Context) {
+	folderController := f.folderController(ctx)
+	conversations, err := folderController.GetFolderConversations(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, conversations)
+}
@@ -0,0 +1,252 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/badaccuracyid/softeng_backend/src/controllers"
+	"github.com/badaccuracyid/softeng_backend/src/database"
+	"github.com/badaccuracyid/softeng_backend/src/model"
+	"github.com/badaccuracyid/softeng_backend/src/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type InviteRoutes struct {
+	baseRouter *gin.RouterGroup
+	db         *gorm.DB
+}
+
+func NewInviteRoutes(router *gin.Engine) (*InviteRoutes, error) {
+	postgresDatabase, err := database.GetPostgresDatabase()
+	if err != nil {
+		panic(err)
+	}
+
+	baseRouter := router.Group("/api/v1/chats/invites")
+
+	return &InviteRoutes{
+		baseRouter: baseRouter,
+		db:         postgresDatabase,
+	}, nil
+}
+
+func InitializeInviteRoutes(router *gin.Engine) *gin.Engine {
+	inviteRoutes, err := NewInviteRoutes(router)
+	if err != nil {
+		panic(err)
+	}
+
+	inviteRoutes.registerRoutes()
+	return router
+}
+
+// inviteController builds the controller of a request. Controllers keep the
+// request context, so one is never shared between requests.
+func (i *InviteRoutes) inviteController(ctx *gin.Context) controllers.InviteController {
+	inviteController := controllers.NewInviteController(i.db)
+	inviteController.SetContext(ctx)
+	return inviteController
+}
+
+func (i *InviteRoutes) registerRoutes() {
+	i.baseRouter.POST("/create", i.createInvite)
+	i.baseRouter.GET("/list/:conversationId", i.getInvites)
+	i.baseRouter.POST("/revoke/:id", i.revokeInvite)
+
+	i.baseRouter.GET("/preview/:token", i.previewInvite)
+	i.baseRouter.POST("/join/:token", i.joinWithInvite)
+
+	i.baseRouter.GET("/requests/:conversationId", i.getJoinRequests)
+	i.baseRouter.POST("/requests/approve/:id", i.approveJoinRequest)
+	i.baseRouter.POST("/requests/reject/:id", i.rejectJoinRequest)
+}
+
+// createInvite handles the POST /api/v1/chats/invites/create request
+// @Summary Create an invite link
+// @Description Create an invite link for a group chat, only admins can create links
+// @Tags invites
+// @Accept  json
+// @Produce  json
+// @Param invite body model.CreateInviteInput true "Invite"
+// @Success 201 {object} model.ConversationInvite
+// @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Failure 403 {string} string
+// @Failure 500 {string} string
+// @Router /chats/invites/create [post]
+func (i *InviteRoutes) createInvite(ctx *gin.Context) {
+	inviteController := i.inviteController(ctx)
+	payload := model.CreateInviteInput{}
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	invite, err := inviteController.CreateInvite(payload)
+	if err != nil {
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, invite)
+}
+
+// getInvites handles the GET /api/v1/chats/invites/list/:conversationId request
+// @Summary List the invite links of a chat
+// @Description List every invite link of a chat, including revoked and expired ones
+// @Tags invites
+// @Produce  json
+// @Param conversationId path string true "Chat ID"
+// @Success 200 {array} model.ConversationInvite
+// @Failure 401 {string} string
+// @Failure 403 {string} string
+// @Failure 500 {string} string
+// @Router /chats/invites/list/{conversationId} [get]
+func (i *InviteRoutes) getInvites(ctx *gin.Context) {
+	inviteController := i.inviteController(ctx)
+	invites, err := inviteController.GetInvites(ctx.Param("conversationId"))
+	if err != nil {
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, invites)
+}
+
+// revokeInvite handles the POST /api/v1/chats/invites/revoke/:id request
+// @Summary Revoke an invite link
+// @Description Revoke an invite link so it cannot be used anymore
+// @Tags invites
+// @Produce  json
+// @Param id path string true "Invite ID"
+// @Success 200 {object} model.ConversationInvite
+// @Failure 401 {string} string
+// @Failure 403 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /chats/invites/revoke/{id} [post]
+func (i *InviteRoutes) revokeInvite(ctx *gin.Context) {
+	inviteController := i.inviteController(ctx)
+	invite, err := inviteController.RevokeInvite(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, invite)
+}
+
+// previewInvite handles the GET /api/v1/chats/invites/preview/:token request
+// @Summary Preview a chat from an invite link
+// @Description Show the title and member count of the chat an invite link leads to
+// @Tags invites
+// @Produce  json
+// @Param token path string true "Invite token"
+// @Success 200 {object} model.InvitePreview
+// @Failure 403 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /chats/invites/preview/{token} [get]
+func (i *InviteRoutes) previewInvite(ctx *gin.Context) {
+	inviteController := i.inviteController(ctx)
+	preview, err := inviteController.PreviewInvite(ctx.Param("token"))
+	if err != nil {
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, preview)
+}
+
+// joinWithInvite handles the POST /api/v1/chats/invites/join/:token request
+// @Summary Join a chat with an invite link
+// @Description Join the chat of an invite link, or request to join it when the link needs an admin's approval
+// @Tags invites
+// @Produce  json
+// @Param token path string true "Invite token"
+// @Success 200 {object} model.JoinResult
+// @Failure 401 {string} string
+// @Failure 403 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /chats/invites/join/{token} [post]
+func (i *InviteRoutes) joinWithInvite(ctx *gin.Context) {
+	inviteController := i.inviteController(ctx)
+	result, err := inviteController.JoinWithInvite(ctx.Param("token"))
+	if err != nil {
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// getJoinRequests handles the GET /api/v1/chats/invites/requests/:conversationId request
+// @Summary List pending join requests
+// @Description List the join requests of a chat waiting for an admin's review
+// @Tags invites
+// @Produce  json
+// @Param conversationId path string true "Chat ID"
+// @Success 200 {array} model.JoinRequest
+// @Failure 401 {string} string
+// @Failure 403 {string} string
+// @Failure 500 {string} string
+// @Router /chats/invites/requests/{conversationId} [get]
+func (i *InviteRoutes) getJoinRequests(ctx *gin.Context) {
+	inviteController := i.inviteController(ctx)
+	requests, err := inviteController.GetJoinRequests(ctx.Param("conversationId"))
+	if err != nil {
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, requests)
+}
+
+// approveJoinRequest handles the POST /api/v1/chats/invites/requests/approve/:id request
+// @Summary Approve a join request
+// @Description Approve a pending join request and add the user to the chat
+// @Tags invites
+// @Produce  json
+// @Param id path string true "Join request ID"
+// @Success 200 {object} model.JoinRequest
+// @Failure 401 {string} string
+// @Failure 403 {string} string
+// @Failure 404 {string} string
+// @Failure 409 {string} string
+// @Failure 500 {string} string
+// @Router /chats/invites/requests/approve/{id} [post]
+func (i *InviteRoutes) approveJoinRequest(ctx *gin.Context) {
+	inviteController := i.inviteController(ctx)
+	request, err := inviteController.ApproveJoinRequest(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, request)
+}
+
+// rejectJoinRequest handles the POST /api/v1/chats/invites/requests/reject/:id request
+// @Summary Reject a join request
+// @Description Reject a pending join request
+// @Tags invites
+// @Produce  json
+// @Param id path string true "Join request ID"
+// @Success 200 {object} model.JoinRequest
+// @Failure 401 {string} string
+// @Failure 403 {string} string
+// @Failure 404 {string} string
+// @Failure 409 {string} string
+// @Failure 500 {string} string
+// @Router /chats/invites/requests/reject/{id} [post]
+func (i *InviteRoutes) rejectJoinRequest(ctx *gin.Context) {
+	inviteController := i.inviteController(ctx)
+	request, err := inviteController.RejectJoinRequest(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, request)
+}
@@ -0,0 +1,135 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/badaccuracyid/softeng_backend/src/controllers"
+	"github.com/badaccuracyid/softeng_backend/src/database"
+	"github.com/badaccuracyid/softeng_backend/src/model"
+	"github.com/badaccuracyid/softeng_backend/src/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type PollRoutes struct {
+	baseRouter *gin.RouterGroup
+	db         *gorm.DB
+}
+
+func NewPollRoutes(router *gin.Engine) (*PollRoutes, error) {
+	postgresDatabase, err := database.GetPostgresDatabase()
+	if err != nil {
+		panic(err)
+	}
+
+	baseRouter := router.Group("/api/v1/chats/polls")
+
+	return &PollRoutes{
+		baseRouter: baseRouter,
+		db:         postgresDatabase,
+	}, nil
+}
+
+func InitializePollRoutes(router *gin.Engine) *gin.Engine {
+	pollRoutes, err := NewPollRoutes(router)
+	if err != nil {
+		panic(err)
+	}
+
+	pollRoutes.registerRoutes()
+	return router
+}
+
+// pollController builds the controller of a request. Controllers keep the
+// request context, so one is never shared between requests.
+func (s *PollRoutes) pollController(ctx *gin.Context) controllers.PollController {
+	pollController := controllers.NewPollController(s.db)
+	pollController.SetContext(ctx)
+	return pollController
+}
+
+func (s *PollRoutes) registerRoutes() {
+	s.baseRouter.POST("/vote/:messageId", s.vote)
+	s.baseRouter.GET("/results/:messageId", s.getResults)
+	s.baseRouter.POST("/close/:messageId", s.closePoll)
+}
+
+// vote handles the POST /api/v1/chats/polls/vote/:messageId request
+// @Summary Vote in a poll
+// @Description Replace the current user's vote, an empty list retracts it
+// @Tags polls
+// @Accept  json
+// @Produce  json
+// @Param messageId path string true "Poll message ID"
+// @Param vote body model.VotePollInput true "Chosen options"
+// @Success 200 {object} model.PollResults
+// @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Failure 403 {string} string
+// @Failure 404 {string} string
+// @Failure 409 {string} string
+// @Failure 500 {string} string
+// @Router /chats/polls/vote/{messageId} [post]
+func (s *PollRoutes) vote(ctx *gin.Context) {
+	pollController := s.pollController(ctx)
+	payload := model.VotePollInput{}
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	results, err := pollController.Vote(ctx.Param("messageId"), payload)
+	if err != nil {
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, results)
+}
+
+// getResults handles the GET /api/v1/chats/polls/results/:messageId request
+// @Summary Get poll results
+// @Description Get the vote counts of a poll, with the voters unless the poll is anonymous
+// @Tags polls
+// @Produce  json
+// @Param messageId path string true "Poll message ID"
+// @Success 200 {object} model.PollResults
+// @Failure 401 {string} string
+// @Failure 403 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /chats/polls/results/{messageId} [get]
+func (s *PollRoutes) getResults(ctx *gin.Context) {
+	pollController := s.pollController(ctx)
+	results, err := pollController.GetResults(ctx.Param("messageId"))
+	if err != nil {
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, results)
+}
+
+// closePoll handles the POST /api/v1/chats/polls/close/:messageId request
+// @Summary Close a poll
+// @Description Stop the voting early, only the author of the poll or a chat admin can close it
+// @Tags polls
+// @Produce  json
+// @Param messageId path string true "Poll message ID"
+// @Success 200 {object} model.PollResults
+// @Failure 401 {string} string
+// @Failure 403 {string} string
+// @Failure 404 {string} string
+// @Failure 409 {string} string
+// @Failure 500 {string} string
+// @Router /chats/polls/close/{messageId} [post]
+func (s *PollRoutes) closePoll(ctx *gin.Context) {
+	pollController := s.pollController(ctx)
+	results, err := pollController.ClosePoll(ctx.Param("messageId"))
+	if err != nil {
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, results)
+}
@@ -0,0 +1,164 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/badaccuracyid/softeng_backend/src/controllers"
+	"github.com/badaccuracyid/softeng_backend/src/database"
+	"github.com/badaccuracyid/softeng_backend/src/model"
+	"github.com/badaccuracyid/softeng_backend/src/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ScheduledMessageRoutes struct {
+	baseRouter *gin.RouterGroup
+	db         *gorm.DB
+}
+
+func NewScheduledMessageRoutes(router *gin.Engine) (*ScheduledMessageRoutes, error) {
+	postgresDatabase, err := database.GetPostgresDatabase()
+	if err != nil {
+		panic(err)
+	}
+
+	baseRouter := router.Group("/api/v1/chats/scheduled")
+
+	return &ScheduledMessageRoutes{
+		baseRouter: baseRouter,
+		db:         postgresDatabase,
+	}, nil
+}
+
+func InitializeScheduledMessageRoutes(router *gin.Engine) *gin.Engine {
+	scheduledMessageRoutes, err := NewScheduledMessageRoutes(router)
+	if err != nil {
+		panic(err)
+	}
+
+	scheduledMessageRoutes.registerRoutes()
+	return router
+}
+
+// scheduledMessageController builds the controller of a request. Controllers
+// keep the request context, so one is never shared between requests.
+func (s *ScheduledMessageRoutes) scheduledMessageController(ctx *gin.Context) controllers.ScheduledMessageController {
+	scheduledMessageController := controllers.NewScheduledMessageController(s.db)
+	scheduledMessageController.SetContext(ctx)
+	return scheduledMessageController
+}
+
+func (s *ScheduledMessageRoutes) registerRoutes() {
+	s.baseRouter.POST("/create", s.scheduleMessage)
+	s.baseRouter.GET("/list", s.getScheduledMessages)
+	s.baseRouter.PATCH("/update/:id", s.updateScheduledMessage)
+	s.baseRouter.POST("/cancel/:id", s.cancelScheduledMessage)
+}
+
+// scheduleMessage handles the POST /api/v1/chats/scheduled/create request
+// @Summary Schedule a message
+// @Description Compose a message now and have it sent to a chat at sendAt
+// @Tags scheduled
+// @Accept  json
+// @Produce  json
+// @Param message body model.ScheduleMessageInput true "Scheduled message"
+// @Success 201 {object} model.ScheduledMessage
+// @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Failure 403 {string} string
+// @Failure 409 {string} string
+// @Failure 500 {string} string
+// @Router /chats/scheduled/create [post]
+func (s *ScheduledMessageRoutes) scheduleMessage(ctx *gin.Context) {
+	scheduledMessageController := s.scheduledMessageController(ctx)
+	payload := model.ScheduleMessageInput{}
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	scheduled, err := scheduledMessageController.ScheduleMessage(payload)
+	if err != nil {
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, scheduled)
+}
+
+// getScheduledMessages handles the GET /api/v1/chats/scheduled/list request
+// @Summary List scheduled messages
+// @Description List the current user's messages waiting to be sent, the next one first
+// @Tags scheduled
+// @Produce  json
+// @Param conversationId query string false "Only the messages of this chat"
+// @Success 200 {array} model.ScheduledMessage
+// @Failure 401 {string} string
+// @Failure 500 {string} string
+// @Router /chats/scheduled/list [get]
+func (s *ScheduledMessageRoutes) getScheduledMessages(ctx *gin.Context) {
+	scheduledMessageController := s.scheduledMessageController(ctx)
+	scheduled, err := scheduledMessageController.GetScheduledMessages(ctx.Query("conversationId"))
+	if err != nil {
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, scheduled)
+}
+
+// updateScheduledMessage handles the PATCH /api/v1/chats/scheduled/update/:id request
+// @Summary Edit a scheduled message
+// @Description Change the content or send time of a message that was not sent yet, only the fields present are changed
+// @Tags scheduled
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Scheduled message ID"
+// @Param message body model.UpdateScheduledMessageInput true "Changes"
+// @Success 200 {object} model.ScheduledMessage
+// @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Failure 403 {string} string
+// @Failure 404 {string} string
+// @Failure 409 {string} string
+// @Failure 500 {string} string
+// @Router /chats/scheduled/update/{id} [patch]
+func (s *ScheduledMessageRoutes) updateScheduledMessage(ctx *gin.Context) {
+	scheduledMessageController := s.scheduledMessageController(ctx)
+	payload := model.UpdateScheduledMessageInput{}
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	scheduled, err := scheduledMessageController.UpdateScheduledMessage(ctx.Param("id"), payload)
+	if err != nil {
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, scheduled)
+}
+
+// cancelScheduledMessage handles the POST /api/v1/chats/scheduled/cancel/:id request
+// @Summary Cancel a scheduled message
+// @Description Cancel a message that was not sent yet
+// @Tags scheduled
+// @Produce  json
+// @Param id path string true "Scheduled message ID"
+// @Success 200 {object} model.ScheduledMessage
+// @Failure 401 {string} string
+// @Failure 404 {string} string
+// @Failure 409 {string} string
+// @Failure 500 {string} string
+// @Router /chats/scheduled/cancel/{id} [post]
+func (s *ScheduledMessageRoutes) cancelScheduledMessage(ctx *gin.Context) {
+	scheduledMessageController := s.scheduledMessageController(ctx)
+	scheduled, err := scheduledMessageController.CancelScheduledMessage(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, scheduled)
+}
@@ -0,0 +1,88 @@
+package routes
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/badaccuracyid/softeng_backend/src/controllers"
+	"github.com/badaccuracyid/softeng_backend/src/database"
+	"github.com/badaccuracyid/softeng_backend/src/storage"
+	"github.com/badaccuracyid/softeng_backend/src/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// UploadRoutes serve the stored files under storage.PublicPath. They are
+// served from the API origin, so nothing a user uploaded may be rendered by
+// the browser as a page.
+type UploadRoutes struct {
+	baseRouter *gin.RouterGroup
+	db         *gorm.DB
+}
+
+func NewUploadRoutes(router *gin.Engine) (*UploadRoutes, error) {
+	postgresDatabase, err := database.GetPostgresDatabase()
+	if err != nil {
+		panic(err)
+	}
+
+	baseRouter := router.Group(storage.PublicPath)
+
+	return &UploadRoutes{
+		baseRouter: baseRouter,
+		db:         postgresDatabase,
+	}, nil
+}
+
+func InitializeUploadRoutes(router *gin.Engine) *gin.Engine {
+	uploadRoutes, err := NewUploadRoutes(router)
+	if err != nil {
+		panic(err)
+	}
+
+	uploadRoutes.registerRoutes()
+	return router
+}
+
+// attachmentController builds the controller of a request. Controllers keep
+// the request context, so one is never shared between requests.
+func (u *UploadRoutes) attachmentController(ctx *gin.Context) controllers.AttachmentController {
+	attachmentController := controllers.NewAttachmentController(u.db)
+	attachmentController.SetContext(ctx)
+	return attachmentController
+}
+
+func (u *UploadRoutes) registerRoutes() {
+	u.baseRouter.GET("/*key", u.getUpload)
+}
+
+// getUpload handles the GET /uploads/*key request
+// @Summary Download an uploaded file
+// @Description Serve an uploaded file with the content type it was stored with. Only images are shown inline, anything else is downloaded
+// @Tags attachments
+// @Produce  octet-stream
+// @Param key path string true "Storage key"
+// @Success 200 {file} file
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /uploads/{key} [get]
+func (u *UploadRoutes) getUpload(ctx *gin.Context) {
+	attachmentController := u.attachmentController(ctx)
+	attachment, reader, err := attachmentController.OpenUpload(strings.TrimPrefix(ctx.Param("key"), "/"))
+	if err != nil {
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
+		return
+	}
+	defer reader.Close()
+
+	disposition := "attachment"
+	if attachment.IsImage() {
+		disposition = "inline"
+	}
+	ctx.DataFromReader(http.StatusOK, attachment.Size, attachment.ContentType, reader, map[string]string{
+		"Content-Disposition":     mime.FormatMediaType(disposition, map[string]string{"filename": attachment.FileName}),
+		"X-Content-Type-Options":  "nosniff",
+		"Content-Security-Policy": "default-src 'none'; sandbox",
+	})
+}
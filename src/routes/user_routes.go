@@ -2,17 +2,25 @@ package routes
 
 import (
 	"net/http"
+	"strconv"
 
 	"github.com/badaccuracyid/softeng_backend/src/controllers"
 	"github.com/badaccuracyid/softeng_backend/src/database"
 	"github.com/badaccuracyid/softeng_backend/src/model"
+	"github.com/badaccuracyid/softeng_backend/src/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultAvatarSize = 128
+	maxAvatarSize     = 1024
 )
 
 type UserRoutes struct {
-	baseRouter     *gin.RouterGroup
-	userController controllers.UserController
+	baseRouter *gin.RouterGroup
+	db         *gorm.DB
 }
 
 func NewUserRoutes(router *gin.Engine) (*UserRoutes, error) {
@@ -21,12 +29,11 @@ func NewUserRoutes(router *gin.Engine) (*UserRoutes, error) {
 		panic(err)
 	}
 
-	userService := controllers.NewUserService(postgresDatabase)
 	baseRouter := router.Group("/api/v1/users")
 
 	return &UserRoutes{
-		baseRouter:     baseRouter,
-		userController: userService,
+		baseRouter: baseRouter,
+		db:         postgresDatabase,
 	}, nil
 }
 func InitializeUserRoutes(router *gin.Engine) *gin.Engine {
@@ -39,14 +46,35 @@ func InitializeUserRoutes(router *gin.Engine) *gin.Engine {
 	return router
 }
 
+// userController builds the controller of a request. Controllers keep the
+// request context, so one is never shared between requests.
+func (u *UserRoutes) userController(ctx *gin.Context) controllers.UserController {
+	userController := controllers.NewUserService(u.db)
+	userController.SetContext(ctx)
+	return userController
+}
+
 func (u *UserRoutes) registerRoutes() {
 	u.baseRouter.POST("/create", u.createUser)
-	u.baseRouter.PATCH("/update", u.createUser)
+	u.baseRouter.PATCH("/update", u.updateProfile)
+	u.baseRouter.POST("/avatar", u.uploadAvatar)
+	u.baseRouter.GET("/avatar/:id", u.getAvatar)
+	u.baseRouter.PATCH("/email", u.changeEmail)
+	u.baseRouter.PATCH("/password", u.changePassword)
 
 	u.baseRouter.GET("/get/:id", u.getUserByID)
 	u.baseRouter.GET("/get", u.getUsersByID)
+	u.baseRouter.GET("/search", u.searchUsers)
+
+	u.baseRouter.GET("/username/available", u.checkUsername)
+	u.baseRouter.GET("/username/history", u.getUsernameHistory)
+	u.baseRouter.GET("/handle/:handle", u.lookupHandle)
 
 	u.baseRouter.GET("/auth/login", u.login)
+
+	u.baseRouter.GET("/presence", u.getPresences)
+	u.baseRouter.PATCH("/status", u.updateStatus)
+	u.baseRouter.PATCH("/privacy", u.updatePrivacy)
 }
 
 // createUser handles the POST /api/v1/users/create request
@@ -58,10 +86,11 @@ func (u *UserRoutes) registerRoutes() {
 // @Param user body model.CreateUserInput true "User"
 // @Success 201 {object} model.User
 // @Failure 400 {string} string
+// @Failure 409 {string} string
 // @Failure 500 {string} string
 // @Router /users/create [post]
 func (u *UserRoutes) createUser(ctx *gin.Context) {
-	u.userController.SetContext(ctx)
+	userController := u.userController(ctx)
 	var userInput model.CreateUserInput
 	if err := ctx.ShouldBindJSON(&userInput); err != nil {
 		ctx.JSON(http.StatusBadRequest, err.Error())
@@ -76,9 +105,9 @@ func (u *UserRoutes) createUser(ctx *gin.Context) {
 		Password:    userInput.Password,
 	}
 
-	createdUser, err := u.userController.CreateUser(newUser)
+	createdUser, err := userController.CreateUser(newUser)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, err.Error())
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
 		return
 	}
 	ctx.JSON(http.StatusCreated, createdUser)
@@ -97,9 +126,9 @@ func (u *UserRoutes) createUser(ctx *gin.Context) {
 // @Failure 500 {string} string
 // @Router /users/get/{id} [get]
 func (u *UserRoutes) getUserByID(ctx *gin.Context) {
-	u.userController.SetContext(ctx)
+	userController := u.userController(ctx)
 	id := ctx.Param("id")
-	user, err := u.userController.GetUserByID(id)
+	user, err := userController.GetUserByID(id)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, err.Error())
 		return
@@ -124,9 +153,9 @@ func (u *UserRoutes) getUserByID(ctx *gin.Context) {
 // @Failure 500 {string} string
 // @Router /users/get [get]
 func (u *UserRoutes) getUsersByID(ctx *gin.Context) {
-	u.userController.SetContext(ctx)
+	userController := u.userController(ctx)
 	ids := ctx.QueryArray("ids")
-	users, err := u.userController.GetUsersByID(ids)
+	users, err := userController.GetUsersByID(ids)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, err.Error())
 		return
@@ -138,31 +167,255 @@ func (u *UserRoutes) getUsersByID(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, users)
 }
 
-// updateUser handles the PATCH /api/v1/users/update request
-// @Summary Update a user
-// @Description Update a user with the input payload
+// searchUsers handles the GET /api/v1/users/search request
+// @Summary Search the user directory
+// @Description Find users by a prefix or a fuzzy match of their username or display name, or by their exact email. Contacts of the current user and users sharing conversations with them rank first, users who are not discoverable by them are left out
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Param q query string true "Username, display name or email"
+// @Param limit query int false "Results per page, 20 by default and 50 at most"
+// @Param offset query int false "The nextOffset of the previous page"
+// @Success 200 {object} model.UserSearchPage
+// @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Failure 500 {string} string
+// @Router /users/search [get]
+func (u *UserRoutes) searchUsers(ctx *gin.Context) {
+	userController := u.userController(ctx)
+	limit, offset := 0, 0
+	for key, target := range map[string]*int{"limit": &limit, "offset": &offset} {
+		raw, found := ctx.GetQuery(key)
+		if !found {
+			continue
+		}
+		value, err := strconv.Atoi(raw)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, err.Error())
+			return
+		}
+		*target = value
+	}
+
+	page, err := userController.SearchUsers(ctx.Query("q"), limit, offset)
+	if err != nil {
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
+		return
+	}
+	ctx.JSON(http.StatusOK, page)
+}
+
+// updateProfile handles the PATCH /api/v1/users/update request
+// @Summary Update the current user's profile
+// @Description Update the display name, username, bio or profile picture of the current user, only the fields present are changed. The profile picture is made from an uploaded image attachment, an empty profilePictureId removes it
 // @Tags users
 // @Accept  json
 // @Produce  json
-// @Param user body model.User true "User"
+// @Param profile body model.UpdateProfileInput true "Profile changes"
 // @Success 200 {object} model.User
 // @Failure 400 {string} string
-// @Failure 404 {string} string
+// @Failure 401 {string} string
+// @Failure 403 {string} string
+// @Failure 409 {string} string
+// @Failure 429 {string} string
 // @Failure 500 {string} string
 // @Router /users/update [patch]
-func (u *UserRoutes) updateUser(ctx *gin.Context) {
-	u.userController.SetContext(ctx)
-	var user model.User
-	if err := ctx.ShouldBindJSON(&user); err != nil {
+func (u *UserRoutes) updateProfile(ctx *gin.Context) {
+	userController := u.userController(ctx)
+	var input model.UpdateProfileInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
 		ctx.JSON(http.StatusBadRequest, err.Error())
 		return
 	}
-	updatedUser, err := u.userController.UpdateUser(user)
+	user, err := userController.UpdateProfile(input)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, err.Error())
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
+		return
+	}
+	ctx.JSON(http.StatusOK, user)
+}
+
+// uploadAvatar handles the POST /api/v1/users/avatar request
+// @Summary Upload the current user's avatar
+// @Description Replace the current user's avatar with a PNG, JPEG or GIF picture. It is cropped to a square around its center and stored in several sizes
+// @Tags users
+// @Accept  multipart/form-data
+// @Produce  json
+// @Param file formData file true "Picture"
+// @Success 200 {object} model.User
+// @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Failure 500 {string} string
+// @Router /users/avatar [post]
+func (u *UserRoutes) uploadAvatar(ctx *gin.Context) {
+	userController := u.userController(ctx)
+	file, err := ctx.FormFile("file")
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	user, err := userController.UploadAvatar(file)
+	if err != nil {
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
+		return
+	}
+	ctx.JSON(http.StatusOK, user)
+}
+
+// getAvatar handles the GET /api/v1/users/avatar/:id request
+// @Summary Get a user's avatar
+// @Description Redirect to the user's avatar in the size closest to the one asked for, or draw their initials as SVG when they have no picture
+// @Tags users
+// @Produce  image/svg+xml
+// @Param id path string true "User ID"
+// @Param size query int false "Size in pixels, 128 by default"
+// @Success 200 {file} file
+// @Success 302
+// @Failure 400 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /users/avatar/{id} [get]
+func (u *UserRoutes) getAvatar(ctx *gin.Context) {
+	userController := u.userController(ctx)
+	size := defaultAvatarSize
+	if raw, found := ctx.GetQuery("size"); found {
+		value, err := strconv.Atoi(raw)
+		if err != nil || value <= 0 || value > maxAvatarSize {
+			ctx.JSON(http.StatusBadRequest, "invalid size")
+			return
+		}
+		size = value
+	}
+
+	url, svg, err := userController.GetAvatar(ctx.Param("id"), size)
+	if err != nil {
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
+		return
+	}
+	if url != "" {
+		ctx.Redirect(http.StatusFound, url)
+		return
+	}
+	ctx.Header("Cache-Control", "public, max-age=300")
+	ctx.Data(http.StatusOK, "image/svg+xml", svg)
+}
+
+// changeEmail handles the PATCH /api/v1/users/email request
+// @Summary Change the current user's email
+// @Description Change the email the current user logs in with, the current password confirms it
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Param email body model.ChangeEmailInput true "New email"
+// @Success 200 {object} model.User
+// @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Failure 403 {string} string
+// @Failure 409 {string} string
+// @Failure 500 {string} string
+// @Router /users/email [patch]
+func (u *UserRoutes) changeEmail(ctx *gin.Context) {
+	userController := u.userController(ctx)
+	var input model.ChangeEmailInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+	user, err := userController.ChangeEmail(input)
+	if err != nil {
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
+		return
+	}
+	ctx.JSON(http.StatusOK, user)
+}
+
+// changePassword handles the PATCH /api/v1/users/password request
+// @Summary Change the current user's password
+// @Description Replace the current user's password, the current password confirms it
+// @Tags users
+// @Accept  json
+// @Param password body model.ChangePasswordInput true "Passwords"
+// @Success 204
+// @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Failure 403 {string} string
+// @Failure 500 {string} string
+// @Router /users/password [patch]
+func (u *UserRoutes) changePassword(ctx *gin.Context) {
+	userController := u.userController(ctx)
+	var input model.ChangePasswordInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := userController.ChangePassword(input); err != nil {
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
 		return
 	}
-	ctx.JSON(http.StatusOK, updatedUser)
+	ctx.Status(http.StatusNoContent)
+}
+
+// checkUsername handles the GET /api/v1/users/username/available request
+// @Summary Check whether a username is available
+// @Description Tell whether the current user could take the username, and why not when they could not
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Param username query string true "Username"
+// @Success 200 {object} model.UsernameAvailability
+// @Failure 500 {string} string
+// @Router /users/username/available [get]
+func (u *UserRoutes) checkUsername(ctx *gin.Context) {
+	userController := u.userController(ctx)
+	availability, err := userController.CheckUsername(ctx.Query("username"))
+	if err != nil {
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
+		return
+	}
+	ctx.JSON(http.StatusOK, availability)
+}
+
+// getUsernameHistory handles the GET /api/v1/users/username/history request
+// @Summary Get the current user's username history
+// @Description List the current user's username changes, latest first
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Success 200 {array} model.UsernameChange
+// @Failure 401 {string} string
+// @Failure 500 {string} string
+// @Router /users/username/history [get]
+func (u *UserRoutes) getUsernameHistory(ctx *gin.Context) {
+	userController := u.userController(ctx)
+	changes, err := userController.GetUsernameHistory()
+	if err != nil {
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
+		return
+	}
+	ctx.JSON(http.StatusOK, changes)
+}
+
+// lookupHandle handles the GET /api/v1/users/handle/:handle request
+// @Summary Find a user by handle
+// @Description Find the user with the username, with or without a leading @. A username changed recently still leads to its previous owner
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Param handle path string true "Handle"
+// @Success 200 {object} model.HandleLookup
+// @Failure 400 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /users/handle/{handle} [get]
+func (u *UserRoutes) lookupHandle(ctx *gin.Context) {
+	userController := u.userController(ctx)
+	lookup, err := userController.LookupHandle(ctx.Param("handle"))
+	if err != nil {
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
+		return
+	}
+	ctx.JSON(http.StatusOK, lookup)
 }
 
 // login handles the GET /api/v1/users/auth/login request
@@ -179,10 +432,10 @@ func (u *UserRoutes) updateUser(ctx *gin.Context) {
 // @Failure 500 {string} string
 // @Router /users/auth/login [get]
 func (u *UserRoutes) login(ctx *gin.Context) {
-	u.userController.SetContext(ctx)
+	userController := u.userController(ctx)
 	email := ctx.Query("email")
 	password := ctx.Query("password")
-	user, err := u.userController.Login(email, password)
+	user, err := userController.Login(email, password)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, err.Error())
 		return
@@ -193,3 +446,78 @@ func (u *UserRoutes) login(ctx *gin.Context) {
 	}
 	ctx.JSON(http.StatusOK, user)
 }
+
+// getPresences handles the GET /api/v1/users/presence request
+// @Summary Get the presence of users
+// @Description Get the online status, custom status and last-seen time of a list of users
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Param ids query []string true "User IDs"
+// @Success 200 {array} model.Presence
+// @Failure 500 {string} string
+// @Router /users/presence [get]
+func (u *UserRoutes) getPresences(ctx *gin.Context) {
+	userController := u.userController(ctx)
+	ids := ctx.QueryArray("ids")
+	presences, err := userController.GetPresences(ids)
+	if err != nil {
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
+		return
+	}
+	ctx.JSON(http.StatusOK, presences)
+}
+
+// updateStatus handles the PATCH /api/v1/users/status request
+// @Summary Update the current user's status
+// @Description Set an away or do-not-disturb status with an optional text and expiry, ONLINE clears it
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Param status body model.UpdateStatusInput true "Status"
+// @Success 200 {object} model.Presence
+// @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Failure 500 {string} string
+// @Router /users/status [patch]
+func (u *UserRoutes) updateStatus(ctx *gin.Context) {
+	userController := u.userController(ctx)
+	var input model.UpdateStatusInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+	presence, err := userController.UpdateStatus(input)
+	if err != nil {
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
+		return
+	}
+	ctx.JSON(http.StatusOK, presence)
+}
+
+// updatePrivacy handles the PATCH /api/v1/users/privacy request
+// @Summary Update the current user's privacy settings
+// @Description Update the privacy settings that are present in the payload
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Param privacy body model.UpdatePrivacyInput true "Privacy settings"
+// @Success 200 {object} model.User
+// @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Failure 500 {string} string
+// @Router /users/privacy [patch]
+func (u *UserRoutes) updatePrivacy(ctx *gin.Context) {
+	userController := u.userController(ctx)
+	var input model.UpdatePrivacyInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+	user, err := userController.UpdatePrivacy(input)
+	if err != nil {
+		ctx.JSON(utils.GetErrorStatusCode(err), err.Error())
+		return
+	}
+	ctx.JSON(http.StatusOK, user)
+}
@@ -0,0 +1,217 @@
+package routes
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/badaccuracyid/softeng_backend/src/model"
+	"github.com/badaccuracyid/softeng_backend/src/utils"
+	"github.com/gorilla/websocket"
+)
+
+// WebsocketConfig is read from the environment, see loadWebsocketConfig for
+// the variable names and defaults.
+type WebsocketConfig struct {
+	PingInterval          time.Duration
+	IdleTimeout           time.Duration
+	WriteTimeout          time.Duration
+	MaxMessageSize        int64
+	AllowedOrigins        []string
+	MaxConnections        int
+	MaxConnectionsPerUser int
+}
+
+// WebsocketTransport owns the upgrader and the connection limits shared by
+// every websocket endpoint.
+type WebsocketTransport struct {
+	config   WebsocketConfig
+	upgrader websocket.Upgrader
+
+	mutex        sync.Mutex
+	connections  int
+	userSessions map[string]int
+}
+
+func loadWebsocketConfig() WebsocketConfig {
+	return WebsocketConfig{
+		PingInterval:          utils.GetEnvDuration("WS_PING_INTERVAL", 25*time.Second),
+		IdleTimeout:           utils.GetEnvDuration("WS_IDLE_TIMEOUT", 60*time.Second),
+		WriteTimeout:          utils.GetEnvDuration("WS_WRITE_TIMEOUT", 10*time.Second),
+		MaxMessageSize:        int64(utils.GetEnvInt("WS_MAX_MESSAGE_SIZE", 4096)),
+		AllowedOrigins:        utils.GetEnvList("WS_ALLOWED_ORIGINS"),
+		MaxConnections:        utils.GetEnvInt("WS_MAX_CONNECTIONS", 10000),
+		MaxConnectionsPerUser: utils.GetEnvInt("WS_MAX_CONNECTIONS_PER_USER", 10),
+	}
+}
+
+func NewWebsocketTransport(config WebsocketConfig) *WebsocketTransport {
+	transport := &WebsocketTransport{
+		config:       config,
+		userSessions: make(map[string]int),
+	}
+
+	transport.upgrader = websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		CheckOrigin:     transport.checkOrigin,
+	}
+
+	return transport
+}
+
+// checkOrigin accepts requests without an Origin header (non-browser
+// clients), same-host requests, and the origins in WS_ALLOWED_ORIGINS. A "*"
+// entry allows every origin.
+func (t *WebsocketTransport) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	for _, allowed := range t.config.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+
+	originURL, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(originURL.Host, r.Host)
+}
+
+// Upgrade switches the request to the websocket protocol. On failure the
+// upgrader has already written the HTTP error response.
+func (t *WebsocketTransport) Upgrade(w http.ResponseWriter, r *http.Request) (*websocket.Conn, error) {
+	return t.upgrader.Upgrade(w, r, nil)
+}
+
+// Acquire reserves a connection slot for the user, it must be paired with
+// Release when it succeeds.
+func (t *WebsocketTransport) Acquire(userID string) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.connections >= t.config.MaxConnections || t.userSessions[userID] >= t.config.MaxConnectionsPerUser {
+		return false
+	}
+
+	t.connections++
+	t.userSessions[userID]++
+	return true
+}
+
+func (t *WebsocketTransport) Release(userID string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.connections--
+	t.userSessions[userID]--
+	if t.userSessions[userID] <= 0 {
+		delete(t.userSessions, userID)
+	}
+}
+
+// Close sends a close frame with the given code before closing the socket.
+func (t *WebsocketTransport) Close(conn *websocket.Conn, code int, reason string) {
+	message := websocket.FormatCloseMessage(code, reason)
+	_ = conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(t.config.WriteTimeout))
+	_ = conn.Close()
+}
+
+// Serve pumps the subscription to the socket and hands every frame read from
+// the client to onMessage until either side goes away. Pings keep the
+// connection alive, and a peer that stops answering them is dropped once the
+// idle timeout expires, which releases its subscription.
+func (t *WebsocketTransport) Serve(conn *websocket.Conn, subscription *model.EventSubscription, missed []*model.Event, onMessage func(data []byte)) {
+	readerDone := make(chan struct{})
+	writerDone := make(chan struct{})
+
+	go func() {
+		defer close(writerDone)
+		t.writePump(conn, subscription, missed, readerDone)
+	}()
+
+	code, reason := t.readPump(conn, onMessage)
+	close(readerDone)
+	<-writerDone
+
+	t.Close(conn, code, reason)
+}
+
+func (t *WebsocketTransport) readPump(conn *websocket.Conn, onMessage func(data []byte)) (int, string) {
+	conn.SetReadLimit(t.config.MaxMessageSize)
+	extendDeadline := func() {
+		_ = conn.SetReadDeadline(time.Now().Add(t.config.IdleTimeout))
+	}
+
+	extendDeadline()
+	conn.SetPongHandler(func(string) error {
+		extendDeadline()
+		return nil
+	})
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			var netErr net.Error
+			switch {
+			case errors.Is(err, websocket.ErrReadLimit):
+				return websocket.CloseMessageTooBig, "message too big"
+			case errors.As(err, &netErr) && netErr.Timeout():
+				return websocket.CloseGoingAway, "idle timeout"
+			}
+			return websocket.CloseNormalClosure, ""
+		}
+
+		extendDeadline()
+		onMessage(data)
+	}
+}
+
+func (t *WebsocketTransport) writePump(conn *websocket.Conn, subscription *model.EventSubscription, missed []*model.Event, readerDone <-chan struct{}) {
+	ping := time.NewTicker(t.config.PingInterval)
+	defer ping.Stop()
+
+	write := func(event *model.Event) bool {
+		_ = conn.SetWriteDeadline(time.Now().Add(t.config.WriteTimeout))
+		return conn.WriteJSON(event) == nil
+	}
+
+	for _, event := range missed {
+		if !write(event) {
+			_ = conn.Close()
+			return
+		}
+	}
+
+	for {
+		select {
+		case <-readerDone:
+			return
+		case event, ok := <-subscription.EventChannel:
+			if !ok {
+				// the hub dropped this subscription, the client should
+				// reconnect and resume from its last event
+				t.Close(conn, websocket.CloseTryAgainLater, "subscription dropped")
+				return
+			}
+			if !write(event) {
+				_ = conn.Close()
+				return
+			}
+		case <-ping.C:
+			deadline := time.Now().Add(t.config.WriteTimeout)
+			if err := conn.WriteControl(websocket.PingMessage, nil, deadline); err != nil {
+				_ = conn.Close()
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/badaccuracyid/softeng_backend/src/utils"
+)
+
+// BlobStorage stores uploaded files under opaque keys. Keys use forward
+// slashes, for example "attachments/<id>".
+type BlobStorage interface {
+	Put(key string, reader io.Reader) error
+	Open(key string) (io.ReadCloser, error)
+	Delete(key string) error
+	URL(key string) string
+}
+
+// LocalBlobStorage keeps blobs on the local disk and serves them through the
+// upload routes registered by the router under PublicPath.
+type LocalBlobStorage struct {
+	Directory  string
+	PublicPath string
+}
+
+const PublicPath = "/uploads"
+
+var (
+	blobStorage BlobStorage
+	once        sync.Once
+)
+
+func GetBlobStorage() BlobStorage {
+	once.Do(func() {
+		blobStorage = NewLocalBlobStorage(GetStorageDirectory(), PublicPath)
+	})
+
+	return blobStorage
+}
+
+// GetStorageDirectory returns the directory set by STORAGE_DIR, defaulting to
+// an "uploads" directory in the working directory.
+func GetStorageDirectory() string {
+	directory := os.Getenv("STORAGE_DIR")
+	if directory == "" {
+		directory = "uploads"
+	}
+	return directory
+}
+
+func NewLocalBlobStorage(directory string, publicPath string) *LocalBlobStorage {
+	return &LocalBlobStorage{
+		Directory:  directory,
+		PublicPath: publicPath,
+	}
+}
+
+func (s *LocalBlobStorage) Put(key string, reader io.Reader) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	// write to a temporary file first so readers never see a partial blob
+	file, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := io.Copy(file, reader); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), path)
+}
+
+func (s *LocalBlobStorage) Open(key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return os.Open(path)
+}
+
+func (s *LocalBlobStorage) Delete(key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func (s *LocalBlobStorage) URL(key string) string {
+	return s.PublicPath + "/" + key
+}
+
+func (s *LocalBlobStorage) path(key string) (string, error) {
+	cleaned := filepath.Clean(filepath.FromSlash(key))
+	if cleaned == "." || filepath.IsAbs(cleaned) || strings.HasPrefix(cleaned, "..") {
+		return "", utils.ErrInvalidInput
+	}
+
+	return filepath.Join(s.Directory, cleaned), nil
+}
@@ -0,0 +1,77 @@
+package unfurl
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/badaccuracyid/softeng_backend/src/model"
+)
+
+// cache keeps the latest unfurl results, the least recently used one goes
+// first when it is full.
+type cache struct {
+	mutex   sync.Mutex
+	size    int
+	ttl     time.Duration
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type cacheEntry struct {
+	url       string
+	preview   *model.LinkPreview
+	err       error
+	expiresAt time.Time
+}
+
+func newCache(size int, ttl time.Duration) *cache {
+	return &cache{
+		size:    size,
+		ttl:     ttl,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+func (c *cache) get(url string) (*model.LinkPreview, error, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	element, found := c.entries[url]
+	if !found {
+		return nil, nil, false
+	}
+	entry := element.Value.(*cacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.order.Remove(element)
+		delete(c.entries, url)
+		return nil, nil, false
+	}
+
+	c.order.MoveToFront(element)
+	return entry.preview, entry.err, true
+}
+
+func (c *cache) put(url string, preview *model.LinkPreview, err error) {
+	if c.size <= 0 {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entry := &cacheEntry{url: url, preview: preview, err: err, expiresAt: time.Now().Add(c.ttl)}
+	if element, found := c.entries[url]; found {
+		element.Value = entry
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[url] = c.order.PushFront(entry)
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).url)
+	}
+}
@@ -0,0 +1,430 @@
+// Package unfurl fetches the OpenGraph and oEmbed metadata of links shared in
+// messages, to show a preview next to them.
+//
+// Links come from users, so the fetcher is kept on a short leash: it only
+// connects to public addresses, which is checked on the resolved address of
+// every connection, follows a few redirects, gives up after a timeout and
+// reads a bounded amount of every response.
+package unfurl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"github.com/badaccuracyid/softeng_backend/src/model"
+	"golang.org/x/net/html"
+)
+
+const (
+	maxTitleLength       = 300
+	maxDescriptionLength = 1000
+	maxSiteNameLength    = 100
+	maxURLLength         = 2048
+)
+
+var (
+	ErrUnsupportedURL     = errors.New("unfurl: only http and https links are previewed")
+	ErrBlockedAddress     = errors.New("unfurl: address is not public")
+	ErrTooManyRedirects   = errors.New("unfurl: too many redirects")
+	ErrUnsupportedContent = errors.New("unfurl: content is not a web page")
+	ErrNoPreview          = errors.New("unfurl: page has nothing to preview")
+)
+
+type Options struct {
+	// Timeout bounds a whole unfurl, oEmbed lookup and redirects included
+	Timeout      time.Duration
+	MaxRedirects int
+	// MaxBodySize is how much of a page is read, metadata lives in the head
+	// so a truncated page is still useful
+	MaxBodySize int64
+	CacheSize   int
+	CacheTTL    time.Duration
+	UserAgent   string
+	// AllowPrivateNetworks turns the address check off, for fetching from a
+	// server on the local machine in tests. Never set it in production.
+	AllowPrivateNetworks bool
+}
+
+var DefaultOptions = Options{
+	Timeout:      5 * time.Second,
+	MaxRedirects: 3,
+	MaxBodySize:  512 << 10,
+	CacheSize:    1000,
+	CacheTTL:     time.Hour,
+	UserAgent:    "softeng-backend-unfurler/1.0",
+}
+
+type Unfurler struct {
+	client  *http.Client
+	options Options
+	cache   *cache
+}
+
+func NewUnfurler(options Options) *Unfurler {
+	unfurler := &Unfurler{
+		options: options,
+		cache:   newCache(options.CacheSize, options.CacheTTL),
+	}
+
+	dialer := &net.Dialer{
+		Timeout: options.Timeout,
+		Control: unfurler.checkAddress,
+	}
+	unfurler.client = &http.Client{
+		Timeout: options.Timeout,
+		Transport: &http.Transport{
+			// a proxy would connect on our behalf, past the address check
+			Proxy:                 nil,
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   options.Timeout,
+			ResponseHeaderTimeout: options.Timeout,
+			MaxIdleConns:          10,
+			IdleConnTimeout:       30 * time.Second,
+		},
+		CheckRedirect: func(request *http.Request, via []*http.Request) error {
+			if len(via) > options.MaxRedirects {
+				return ErrTooManyRedirects
+			}
+			return checkURL(request.URL)
+		},
+	}
+	return unfurler
+}
+
+// Unfurl returns the preview of the page at rawURL. Results, failures
+// included, are cached so a link shared many times is fetched once.
+func (u *Unfurler) Unfurl(ctx context.Context, rawURL string) (*model.LinkPreview, error) {
+	if preview, err, found := u.cache.get(rawURL); found {
+		return copyPreview(preview), err
+	}
+
+	preview, err := u.fetch(ctx, rawURL)
+	// a caller giving up says nothing about the link
+	if ctx.Err() == nil {
+		u.cache.put(rawURL, preview, err)
+	}
+	return copyPreview(preview), err
+}
+
+func (u *Unfurler) fetch(ctx context.Context, rawURL string) (*model.LinkPreview, error) {
+	if len(rawURL) > maxURLLength {
+		return nil, ErrUnsupportedURL
+	}
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, ErrUnsupportedURL
+	}
+	if err := checkURL(target); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, u.options.Timeout)
+	defer cancel()
+
+	response, err := u.get(ctx, target.String(), "text/html,application/xhtml+xml")
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if !isHTML(response.Header.Get("Content-Type")) {
+		return nil, ErrUnsupportedContent
+	}
+
+	// relative addresses in the page are relative to where redirects led
+	page := response.Request.URL
+	metadata := parseHead(io.LimitReader(response.Body, u.options.MaxBodySize))
+
+	preview := &model.LinkPreview{
+		URL:         rawURL,
+		Title:       metadata.title,
+		Description: metadata.description,
+		SiteName:    metadata.siteName,
+		ImageURL:    resolveURL(page, metadata.image),
+	}
+
+	if oEmbedURL := resolveURL(page, metadata.oEmbed); oEmbedURL != "" && (preview.Title == "" || preview.ImageURL == "") {
+		// the page alone may be enough, a failing lookup is not fatal
+		if oEmbed, err := u.fetchOEmbed(ctx, oEmbedURL); err == nil {
+			if preview.Title == "" {
+				preview.Title = oEmbed.Title
+			}
+			if preview.SiteName == "" {
+				preview.SiteName = oEmbed.ProviderName
+			}
+			if preview.ImageURL == "" {
+				preview.ImageURL = resolveURL(page, oEmbed.ThumbnailURL)
+			}
+		}
+	}
+
+	preview.Title = truncate(preview.Title, maxTitleLength)
+	preview.Description = truncate(preview.Description, maxDescriptionLength)
+	preview.SiteName = truncate(preview.SiteName, maxSiteNameLength)
+	if preview.Title == "" && preview.Description == "" && preview.ImageURL == "" {
+		return nil, ErrNoPreview
+	}
+	return preview, nil
+}
+
+type oEmbedResponse struct {
+	Title        string `json:"title"`
+	ProviderName string `json:"provider_name"`
+	ThumbnailURL string `json:"thumbnail_url"`
+}
+
+func (u *Unfurler) fetchOEmbed(ctx context.Context, oEmbedURL string) (*oEmbedResponse, error) {
+	response, err := u.get(ctx, oEmbedURL, "application/json")
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(response.Body, u.options.MaxBodySize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > u.options.MaxBodySize {
+		return nil, ErrUnsupportedContent
+	}
+
+	oEmbed := &oEmbedResponse{}
+	if err := json.Unmarshal(body, oEmbed); err != nil {
+		return nil, ErrUnsupportedContent
+	}
+	return oEmbed, nil
+}
+
+func (u *Unfurler) get(ctx context.Context, address string, accept string) (*http.Response, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, address, nil)
+	if err != nil {
+		return nil, ErrUnsupportedURL
+	}
+	request.Header.Set("User-Agent", u.options.UserAgent)
+	request.Header.Set("Accept", accept)
+
+	response, err := u.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != http.StatusOK {
+		response.Body.Close()
+		return nil, fmt.Errorf("unfurl: %s answered %d", request.URL.Host, response.StatusCode)
+	}
+	return response, nil
+}
+
+// checkAddress runs on the resolved address right before connecting, so a
+// name that resolves to a private address is caught as well as a literal one.
+func (u *Unfurler) checkAddress(network string, address string, _ syscall.RawConn) error {
+	if u.options.AllowPrivateNetworks {
+		return nil
+	}
+
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return ErrBlockedAddress
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !IsPublicIP(ip) {
+		return ErrBlockedAddress
+	}
+	return nil
+}
+
+// reservedNetworks are not caught by the net.IP helpers, yet do not lead to
+// the public internet either.
+var reservedNetworks = parseNetworks(
+	"0.0.0.0/8",       // this network
+	"100.64.0.0/10",   // carrier-grade NAT
+	"192.0.0.0/24",    // IETF protocol assignments
+	"192.0.2.0/24",    // documentation
+	"198.18.0.0/15",   // benchmarking
+	"198.51.100.0/24", // documentation
+	"203.0.113.0/24",  // documentation
+	"240.0.0.0/4",     // reserved, broadcast included
+	"64:ff9b::/96",    // NAT64, could reach private IPv4 addresses
+	"64:ff9b:1::/48",  // local NAT64
+	"2001:db8::/32",   // documentation
+	"2002::/16",       // 6to4, embeds IPv4 addresses
+)
+
+// IsPublicIP tells whether ip is an address of the public internet.
+func IsPublicIP(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsMulticast() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() {
+		return false
+	}
+	for _, network := range reservedNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+func parseNetworks(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks[i] = network
+	}
+	return networks
+}
+
+func checkURL(target *url.URL) error {
+	if target.Scheme != "http" && target.Scheme != "https" {
+		return ErrUnsupportedURL
+	}
+	if target.Hostname() == "" || target.User != nil {
+		return ErrUnsupportedURL
+	}
+	return nil
+}
+
+func isHTML(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == "text/html" || mediaType == "application/xhtml+xml")
+}
+
+// resolveURL makes reference absolute against the page, and drops it unless
+// it is an http or https address.
+func resolveURL(page *url.URL, reference string) string {
+	reference = strings.TrimSpace(reference)
+	if reference == "" || len(reference) > maxURLLength {
+		return ""
+	}
+
+	parsed, err := url.Parse(reference)
+	if err != nil {
+		return ""
+	}
+	resolved := page.ResolveReference(parsed)
+	if checkURL(resolved) != nil {
+		return ""
+	}
+	return resolved.String()
+}
+
+func truncate(text string, maxLength int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if utf8.RuneCountInString(text) <= maxLength {
+		return text
+	}
+	runes := []rune(text)
+	return strings.TrimSpace(string(runes[:maxLength-1])) + "…"
+}
+
+func copyPreview(preview *model.LinkPreview) *model.LinkPreview {
+	if preview == nil {
+		return nil
+	}
+	copied := *preview
+	return &copied
+}
+
+type headMetadata struct {
+	title       string
+	description string
+	siteName    string
+	image       string
+	oEmbed      string
+}
+
+// parseHead reads the metadata of a page, OpenGraph first and the plain HTML
+// title and description otherwise. It stops at the body.
+func parseHead(body io.Reader) *headMetadata {
+	metadata := &headMetadata{}
+	var htmlTitle, htmlDescription string
+
+	tokenizer := html.NewTokenizer(body)
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == html.ErrorToken {
+			break
+		}
+		if tokenType != html.StartTagToken && tokenType != html.SelfClosingTagToken {
+			continue
+		}
+
+		token := tokenizer.Token()
+		switch token.Data {
+		case "body":
+			return metadata.withFallback(htmlTitle, htmlDescription)
+
+		case "title":
+			if tokenizer.Next() == html.TextToken && htmlTitle == "" {
+				htmlTitle = string(tokenizer.Text())
+			}
+
+		case "meta":
+			key := strings.ToLower(attribute(token, "property"))
+			if key == "" {
+				key = strings.ToLower(attribute(token, "name"))
+			}
+			content := attribute(token, "content")
+			switch key {
+			case "og:title":
+				setOnce(&metadata.title, content)
+			case "og:description":
+				setOnce(&metadata.description, content)
+			case "og:site_name":
+				setOnce(&metadata.siteName, content)
+			case "og:image", "og:image:url", "og:image:secure_url":
+				setOnce(&metadata.image, content)
+			case "twitter:title":
+				setOnce(&htmlTitle, content)
+			case "twitter:image":
+				setOnce(&metadata.image, content)
+			case "description", "twitter:description":
+				setOnce(&htmlDescription, content)
+			}
+
+		case "link":
+			rel := strings.ToLower(attribute(token, "rel"))
+			linkType := strings.ToLower(attribute(token, "type"))
+			if rel == "alternate" && linkType == "application/json+oembed" {
+				setOnce(&metadata.oEmbed, attribute(token, "href"))
+			}
+		}
+	}
+	return metadata.withFallback(htmlTitle, htmlDescription)
+}
+
+func (m *headMetadata) withFallback(title string, description string) *headMetadata {
+	setOnce(&m.title, title)
+	setOnce(&m.description, description)
+	return m
+}
+
+func attribute(token html.Token, name string) string {
+	for _, attr := range token.Attr {
+		if strings.EqualFold(attr.Key, name) {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+func setOnce(field *string, value string) {
+	if *field == "" {
+		*field = strings.TrimSpace(value)
+	}
+}
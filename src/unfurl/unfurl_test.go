@@ -0,0 +1,275 @@
+package unfurl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testOptions let the unfurler reach the httptest server on the loopback
+// address.
+func testOptions() Options {
+	options := DefaultOptions
+	options.Timeout = time.Second
+	options.AllowPrivateNetworks = true
+	return options
+}
+
+func servePage(page string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, page)
+	}
+}
+
+func TestUnfurlOpenGraph(t *testing.T) {
+	server := httptest.NewServer(servePage(`<html><head>
+		<title>Plain title</title>
+		<meta property="og:title" content="  OpenGraph   title ">
+		<meta property="og:description" content="What the page is about">
+		<meta property="og:site_name" content="Example">
+		<meta property="og:image" content="/images/cover.png">
+		</head><body><meta property="og:title" content="Not in the head"></body></html>`))
+	defer server.Close()
+
+	preview, err := NewUnfurler(testOptions()).Unfurl(context.Background(), server.URL+"/article")
+	if err != nil {
+		t.Fatalf("Unfurl: %v", err)
+	}
+	if preview.URL != server.URL+"/article" {
+		t.Errorf("URL = %q", preview.URL)
+	}
+	if preview.Title != "OpenGraph title" {
+		t.Errorf("Title = %q, want %q", preview.Title, "OpenGraph title")
+	}
+	if preview.Description != "What the page is about" {
+		t.Errorf("Description = %q", preview.Description)
+	}
+	if preview.SiteName != "Example" {
+		t.Errorf("SiteName = %q", preview.SiteName)
+	}
+	if preview.ImageURL != server.URL+"/images/cover.png" {
+		t.Errorf("ImageURL = %q, want it resolved against the page", preview.ImageURL)
+	}
+}
+
+func TestUnfurlHTMLFallback(t *testing.T) {
+	server := httptest.NewServer(servePage(`<html><head>
+		<title>Plain title</title>
+		<meta name="description" content="Plain description">
+		</head><body></body></html>`))
+	defer server.Close()
+
+	preview, err := NewUnfurler(testOptions()).Unfurl(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("Unfurl: %v", err)
+	}
+	if preview.Title != "Plain title" || preview.Description != "Plain description" {
+		t.Errorf("preview = %+v, want the HTML title and description", preview)
+	}
+}
+
+func TestUnfurlOEmbed(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/video", servePage(`<html><head>
+		<meta property="og:description" content="A video">
+		<link rel="alternate" type="application/json+oembed" href="/oembed?url=video">
+		</head><body></body></html>`))
+	mux.HandleFunc("/oembed", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"title":"Video title","provider_name":"Tube","thumbnail_url":"/thumbs/video.jpg"}`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	preview, err := NewUnfurler(testOptions()).Unfurl(context.Background(), server.URL+"/video")
+	if err != nil {
+		t.Fatalf("Unfurl: %v", err)
+	}
+	if preview.Title != "Video title" {
+		t.Errorf("Title = %q, want the oEmbed title", preview.Title)
+	}
+	if preview.SiteName != "Tube" {
+		t.Errorf("SiteName = %q, want the oEmbed provider", preview.SiteName)
+	}
+	if preview.Description != "A video" {
+		t.Errorf("Description = %q, want the page description", preview.Description)
+	}
+	if preview.ImageURL != server.URL+"/thumbs/video.jpg" {
+		t.Errorf("ImageURL = %q, want the oEmbed thumbnail", preview.ImageURL)
+	}
+}
+
+func TestUnfurlRedirectLimit(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/hop/", func(w http.ResponseWriter, r *http.Request) {
+		var hop int
+		fmt.Sscanf(strings.TrimPrefix(r.URL.Path, "/hop/"), "%d", &hop)
+		if hop == 0 {
+			servePage(`<html><head><title>Landed</title></head></html>`)(w, r)
+			return
+		}
+		http.Redirect(w, r, fmt.Sprintf("/hop/%d", hop-1), http.StatusFound)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	options := testOptions()
+	options.MaxRedirects = 3
+	options.CacheSize = 0
+	unfurler := NewUnfurler(options)
+
+	preview, err := unfurler.Unfurl(context.Background(), server.URL+"/hop/3")
+	if err != nil {
+		t.Fatalf("Unfurl with %d redirects: %v", options.MaxRedirects, err)
+	}
+	if preview.Title != "Landed" {
+		t.Errorf("Title = %q", preview.Title)
+	}
+
+	_, err = unfurler.Unfurl(context.Background(), server.URL+"/hop/4")
+	if !errors.Is(err, ErrTooManyRedirects) {
+		t.Errorf("Unfurl with %d redirects: err = %v, want ErrTooManyRedirects", options.MaxRedirects+1, err)
+	}
+}
+
+func TestUnfurlBodySizeCap(t *testing.T) {
+	padding := strings.Repeat(" ", 4096)
+	server := httptest.NewServer(servePage(`<html><head>` + padding +
+		`<meta property="og:title" content="Past the cap"></head></html>`))
+	defer server.Close()
+
+	options := testOptions()
+	options.MaxBodySize = 1024
+	_, err := NewUnfurler(options).Unfurl(context.Background(), server.URL)
+	if !errors.Is(err, ErrNoPreview) {
+		t.Errorf("err = %v, want ErrNoPreview as the title is past the cap", err)
+	}
+
+	options.MaxBodySize = 8192
+	preview, err := NewUnfurler(options).Unfurl(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("Unfurl under the cap: %v", err)
+	}
+	if preview.Title != "Past the cap" {
+		t.Errorf("Title = %q", preview.Title)
+	}
+}
+
+func TestUnfurlTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	options := testOptions()
+	options.Timeout = 100 * time.Millisecond
+	started := time.Now()
+	_, err := NewUnfurler(options).Unfurl(context.Background(), server.URL)
+	if err == nil {
+		t.Fatal("Unfurl of a page that never answers succeeded")
+	}
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Errorf("Unfurl gave up after %v, want about %v", elapsed, options.Timeout)
+	}
+}
+
+func TestUnfurlCache(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		servePage(`<html><head><title>Cached</title></head></html>`)(w, r)
+	}))
+	defer server.Close()
+
+	unfurler := NewUnfurler(testOptions())
+	for i := 0; i < 3; i++ {
+		preview, err := unfurler.Unfurl(context.Background(), server.URL+"/page")
+		if err != nil {
+			t.Fatalf("Unfurl %d: %v", i, err)
+		}
+		// callers get their own copy
+		preview.Title = "changed"
+	}
+	preview, _ := unfurler.Unfurl(context.Background(), server.URL+"/page")
+	if preview.Title != "Cached" {
+		t.Errorf("Title = %q, a caller changed the cached preview", preview.Title)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := unfurler.Unfurl(context.Background(), server.URL+"/missing"); err == nil {
+			t.Fatal("Unfurl of a missing page succeeded")
+		}
+	}
+
+	if got := requests.Load(); got != 2 {
+		t.Errorf("server got %d requests, want 2 as results and failures are cached", got)
+	}
+}
+
+func TestUnfurlBlocksPrivateAddresses(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		servePage(`<html><head><title>Internal</title></head></html>`)(w, r)
+	}))
+	defer server.Close()
+
+	options := testOptions()
+	options.AllowPrivateNetworks = false
+	unfurler := NewUnfurler(options)
+
+	_, port, _ := net.SplitHostPort(strings.TrimPrefix(server.URL, "http://"))
+	for _, address := range []string{server.URL, "http://localhost:" + port} {
+		if _, err := unfurler.Unfurl(context.Background(), address); !errors.Is(err, ErrBlockedAddress) {
+			t.Errorf("Unfurl(%q): err = %v, want ErrBlockedAddress", address, err)
+		}
+	}
+
+	if got := requests.Load(); got != 0 {
+		t.Errorf("server got %d requests, want none", got)
+	}
+}
+
+func TestIsPublicIP(t *testing.T) {
+	tests := []struct {
+		ip     string
+		public bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"::1", false},
+		{"fd00::1", false},
+		{"fe80::1", false},
+		{"::ffff:127.0.0.1", false},
+		{"64:ff9b::a00:1", false},
+	}
+	for _, test := range tests {
+		if got := IsPublicIP(net.ParseIP(test.ip)); got != test.public {
+			t.Errorf("IsPublicIP(%s) = %v, want %v", test.ip, got, test.public)
+		}
+	}
+}
@@ -1 +1,37 @@
 package utils
+
+import (
+	"errors"
+	"net/http"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrUnauthenticated = errors.New("user not authenticated")
+	ErrForbidden       = errors.New("user is not allowed to perform this action")
+	ErrNotFound        = errors.New("resource not found")
+	ErrRateLimited     = errors.New("too many requests")
+	ErrInvalidInput    = errors.New("invalid input")
+	ErrConflict        = errors.New("resource is in a conflicting state")
+)
+
+// GetErrorStatusCode maps an error returned by a controller to the HTTP
+// status code that should be sent back to the client.
+func GetErrorStatusCode(err error) int {
+	switch {
+	case errors.Is(err, ErrUnauthenticated):
+		return http.StatusUnauthorized
+	case errors.Is(err, ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, ErrNotFound), errors.Is(err, gorm.ErrRecordNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrRateLimited):
+		return http.StatusTooManyRequests
+	case errors.Is(err, ErrInvalidInput):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrConflict):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
@@ -1,6 +1,10 @@
 package utils
 
-import "github.com/gin-gonic/gin"
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
 
 func GetCurrentUserID(ctx *gin.Context) string {
 	if ctx == nil {
@@ -14,3 +18,19 @@ func GetCurrentUserID(ctx *gin.Context) string {
 
 	return userId.(string)
 }
+
+// GetLastEventID reads the resume position of a realtime client, either from
+// the Last-Event-ID header sent by EventSource or from the lastEventId query.
+func GetLastEventID(ctx *gin.Context) int64 {
+	lastEventId := ctx.GetHeader("Last-Event-ID")
+	if lastEventId == "" {
+		lastEventId = ctx.Query("lastEventId")
+	}
+
+	id, err := strconv.ParseInt(lastEventId, 10, 64)
+	if err != nil || id < 0 {
+		return 0
+	}
+
+	return id
+}